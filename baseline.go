@@ -0,0 +1,312 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Baseline is a frozen copy of all items and links in a project,
+// stored as a row in Projects with the items in ItemVersions
+type Baseline struct {
+	ID      int64
+	UID     int64
+	Name    string
+	Created string
+	// Number of items in the baseline
+	Items int
+}
+
+// baselineColumns are the columns in ItemVersions with the contents of an item
+const baselineColumns = "item, type, parent, description, rationale, fitCriterion, link, " +
+	"color, border, shape, x, y, width, height"
+
+// scanBaselineItem reads a row with baselineColumns as a snapshot
+func scanBaselineItem(row interface{ Scan(...interface{}) error }) (ItemSnapshot, error) {
+	var item ItemSnapshot
+	var itemType int8
+	var parent sql.NullInt64
+	var description, rationale, fitCriterion, link sql.NullString
+	if err := row.Scan(&item.UID, &itemType, &parent, &description, &rationale, &fitCriterion, &link,
+		&item.Color, &item.Border, &item.Shape, &item.X, &item.Y, &item.Width, &item.Height); err != nil {
+		return item, err
+	}
+	item.Type = ItemType(itemType)
+	item.HasParent = parent.Valid
+	item.ParentUID = parent.Int64
+	item.Description = description.String
+	item.Rationale = rationale.String
+	item.FitCriterion = fitCriterion.String
+	item.Link = link.String
+	return item, nil
+}
+
+// sameContents checks if two snapshots of the same item have the same contents and parent
+func sameContents(a, b ItemSnapshot) bool {
+	return a.Type == b.Type && a.HasParent == b.HasParent && a.ParentUID == b.ParentUID &&
+		a.Description == b.Description && a.Rationale == b.Rationale && a.FitCriterion == b.FitCriterion &&
+		a.Link == b.Link && a.Color == b.Color && a.Border == b.Border && a.Shape == b.Shape &&
+		a.X == b.X && a.Y == b.Y && a.Width == b.Width && a.Height == b.Height
+}
+
+// CurrentItems gets a snapshot of all items, with parents by uid
+func (data *DataContext) CurrentItems() ([]ItemSnapshot, error) {
+	items, err := data.LoadItems()
+	if err != nil {
+		return nil, err
+	}
+	uids := make(map[Item]int64)
+	for _, item := range items {
+		uids[item.Item] = item.UID
+	}
+	snapshots := make([]ItemSnapshot, 0, len(items))
+	for _, item := range items {
+		snapshot := ItemSnapshot{
+			ItemData: item,
+			Type:     GetItemType(item.Item),
+		}
+		snapshot.ParentUID, snapshot.HasParent = uids[item.Parent]
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// CreateBaseline freezes the current state of all items and links under a name.
+// Each item gets a new revision if it changed since it was last frozen.
+func (data *DataContext) CreateBaseline(name string) (Baseline, error) {
+	baseline := Baseline{
+		Name: strings.TrimSpace(name),
+	}
+	if baseline.Name == "" {
+		return baseline, fmt.Errorf("baseline name can't be empty")
+	}
+	if _, err := data.BaselineByName(baseline.Name); err == nil {
+		return baseline, fmt.Errorf("baseline with name \"%v\" already exists", baseline.Name)
+	}
+	items, err := data.CurrentItems()
+	if err != nil {
+		return baseline, err
+	}
+	err = data.Transaction(func() error {
+		baseline.UID = data.ItemUID()
+		result, err := data.conn().Exec("insert into Projects (uid, name) values (?, ?)", baseline.UID, baseline.Name)
+		if err != nil {
+			return err
+		}
+		if baseline.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+		for _, item := range items {
+			// Find the latest revision of the item, if any
+			revision := 0
+			latest, err := scanBaselineItem(data.conn().QueryRow(fmt.Sprintf(
+				"select %v from ItemVersions where item = ? order by itemV desc limit 1", baselineColumns), item.UID))
+			if err == nil {
+				if err := data.conn().QueryRow("select max(itemV) from ItemVersions where item = ?",
+					item.UID).Scan(&revision); err != nil {
+					return err
+				}
+				if !sameContents(item, latest) {
+					revision++
+				}
+			} else if err == sql.ErrNoRows {
+				revision = 1
+			} else {
+				return err
+			}
+			var parent interface{}
+			if item.HasParent {
+				parent = item.ParentUID
+			}
+			if _, err := data.conn().Exec(fmt.Sprintf("insert into ItemVersions (version, itemV, %v) "+
+				"values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", baselineColumns),
+				baseline.ID, revision, item.UID, item.Type, parent, item.Description, item.Rationale,
+				item.FitCriterion, item.Link, item.Color, item.Border, item.Shape,
+				item.X, item.Y, item.Width, item.Height); err != nil {
+				return err
+			}
+		}
+		return data.conn().QueryRow("select created from Projects where _rowid_ = ?",
+			baseline.ID).Scan(&baseline.Created)
+	})
+	baseline.Items = len(items)
+	return baseline, err
+}
+
+// Baselines gets all baselines, oldest first
+func (data *DataContext) Baselines() ([]Baseline, error) {
+	rows, err := data.conn().Query("select Projects._rowid_, uid, coalesce(name, ''), coalesce(created, ''), " +
+		"(select count(*) from ItemVersions where version = Projects._rowid_) from Projects order by Projects._rowid_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baselines: %v", err)
+	}
+	defer rows.Close()
+	baselines := make([]Baseline, 0)
+	for rows.Next() {
+		var baseline Baseline
+		if err := rows.Scan(&baseline.ID, &baseline.UID, &baseline.Name, &baseline.Created, &baseline.Items); err != nil {
+			return baselines, fmt.Errorf("failed to get baseline: %v", err)
+		}
+		baselines = append(baselines, baseline)
+	}
+	return baselines, rows.Err()
+}
+
+// BaselineByName finds the baseline with the specified name
+func (data *DataContext) BaselineByName(name string) (Baseline, error) {
+	baselines, err := data.Baselines()
+	if err != nil {
+		return Baseline{}, err
+	}
+	for _, baseline := range baselines {
+		if baseline.Name == name {
+			return baseline, nil
+		}
+	}
+	return Baseline{}, fmt.Errorf("no baseline with name \"%v\"", name)
+}
+
+// BaselineItems gets all items frozen in a baseline, with parents by uid
+func (data *DataContext) BaselineItems(baseline Baseline) ([]ItemSnapshot, error) {
+	rows, err := data.conn().Query(fmt.Sprintf(
+		"select %v from ItemVersions where version = ? order by _rowid_", baselineColumns), baseline.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items in baseline %v: %v", baseline.Name, err)
+	}
+	defer rows.Close()
+	items := make([]ItemSnapshot, 0)
+	for rows.Next() {
+		item, err := scanBaselineItem(rows)
+		if err != nil {
+			return items, fmt.Errorf("failed to get item in baseline %v: %v", baseline.Name, err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ReplaceItems removes all current items and adds the ones in the snapshots instead, keeping uids
+func (data *DataContext) ReplaceItems(items []ItemSnapshot) error {
+	for _, table := range []string{"Requirements", "Solutions"} {
+		if _, err := data.conn().Exec(fmt.Sprintf("delete from %v", table)); err != nil {
+			return err
+		}
+	}
+	// Add all items before linking them, as parents may come after children
+	for _, item := range items {
+		unlinked := item
+		unlinked.HasParent = false
+		unlinked.ChildUIDs = nil
+		if _, err := data.RestoreItem(unlinked); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		if !item.HasParent {
+			continue
+		}
+		child, err := data.ItemByUID(item.UID)
+		if err != nil {
+			return err
+		}
+		parent, err := data.ItemByUID(item.ParentUID)
+		if err != nil {
+			return err
+		}
+		if err := data.AddItemChild(parent, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportBaseline creates a new project in path with only the items in the baseline
+func (data *DataContext) ExportBaseline(baseline Baseline, path string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return fmt.Errorf("file with name \"%v\" already exists", path)
+	}
+	items, err := data.BaselineItems(baseline)
+	if err != nil {
+		return err
+	}
+	db, err := OpenDataContext(path)
+	if err != nil {
+		return err
+	}
+	db.SetProjectName(data.ProjectName())
+	err = db.Transaction(func() error {
+		return db.ReplaceItems(items)
+	})
+	db.Close()
+	// Don't leave half a baseline behind
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// OpenBaseline opens a read-only copy of a baseline as the current project,
+// the copy is removed when the project is closed
+func (proj *Project) OpenBaseline(baseline Baseline) (*Project, error) {
+	tempDir, err := ioutil.TempDir("", "orq")
+	if err != nil {
+		return nil, err
+	}
+	tempPath := filepath.Join(tempDir, "baseline.orq")
+	if err := proj.Data().ExportBaseline(baseline, tempPath); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+	source := proj.FilePath()
+	project, err := NewProject(tempPath)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+	// Refuse any changes to the baseline
+	if _, err := project.Data().conn().Exec("pragma query_only = 1"); err != nil {
+		project.Close()
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+	project.source = source
+	project.baseline = baseline.Name
+	project.tempDir = tempDir
+	return project, nil
+}
+
+// Branch creates a new project in path, with the same baselines,
+// but with the items from the baseline to continue working from
+func (proj *Project) Branch(baseline Baseline, path string) error {
+	items, err := proj.Data().BaselineItems(baseline)
+	if err != nil {
+		return err
+	}
+	return proj.CopyWithItems(path, items)
+}
+
+// CopyWithItems creates a copy of the project in path, with the items replaced
+func (proj *Project) CopyWithItems(path string, items []ItemSnapshot) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return fmt.Errorf("file with name \"%v\" already exists", path)
+	}
+	if err := proj.CopyTo(path); err != nil {
+		return err
+	}
+	db, err := OpenDataContext(path)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	err = db.Transaction(func() error {
+		return db.ReplaceItems(items)
+	})
+	db.Close()
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	db := project.Data()
+	// Add a requirement with a solution
+	reqID, _ := db.AddEmptyRequirement()
+	solID, _ := db.AddEmptySolution()
+	req := NewRequirement(reqID)
+	sol := NewSolution(solID)
+	req.SetDescription("approved")
+	_ = db.AddItemChild(req, sol)
+	reqUID, solUID := req.UID(), sol.UID()
+	// Freeze it, and then change it
+	if _, err := db.CreateBaseline("v1"); err != nil {
+		t.Error("failed to create baseline:", err)
+		return
+	}
+	if _, err := db.CreateBaseline("v1"); err == nil {
+		t.Error("expected baseline with the same name to fail")
+	}
+	req.SetDescription("changed")
+	if _, err := db.CreateBaseline("v2"); err != nil {
+		t.Error("failed to create second baseline:", err)
+	}
+	sol.SetParent(nil)
+	_ = db.RemoveItem(req)
+	baselines, err := db.Baselines()
+	if err != nil || len(baselines) != 2 || baselines[0].Name != "v1" || baselines[0].Items != 2 {
+		t.Error("unexpected baselines:", baselines, err)
+		return
+	}
+	// Only the changed item should have a new revision
+	for uid, expected := range map[int64]int{reqUID: 2, solUID: 1} {
+		var revision int
+		_ = db.conn().QueryRow("select max(itemV) from ItemVersions where item = ?", uid).Scan(&revision)
+		if revision != expected {
+			t.Errorf("unexpected revision of %v, expected %v, but got %v", FormatUID(uid), expected, revision)
+		}
+	}
+	// The first baseline should be exactly as it was frozen
+	baseline, err := project.OpenBaseline(baselines[0])
+	if err != nil {
+		t.Error("failed to open baseline:", err)
+		return
+	}
+	graph, err := LoadGraph(baseline.Data())
+	if err != nil || len(graph.Items()) != 2 || len(graph.Roots()) != 1 {
+		t.Error("unexpected items in baseline:", len(graph.Items()), err)
+	}
+	if item, err := baseline.Data().ItemByUID(reqUID); err != nil || item.Description() != "approved" {
+		t.Error("expected requirement to be restored as approved:", err)
+	} else if children := item.Children(); len(children) != 1 || children[0].UID() != solUID {
+		t.Error("expected solution to be linked to requirement in baseline")
+	}
+	// Baselines can't be changed
+	if _, err := baseline.Data().AddEmptySolution(); err == nil {
+		t.Error("expected adding items to a baseline to fail")
+	}
+	name, _ := baseline.Baseline()
+	if !baseline.ReadOnly() || name != "v1" {
+		t.Error("expected baseline to be read-only")
+	}
+	if err := baseline.Close(); err != nil {
+		t.Error("failed to close baseline:", err)
+	}
+	// Branch from the second baseline
+	if project, err = NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir)); err != nil {
+		t.Error("failed to open project again:", err)
+		return
+	}
+	branchPath := fmt.Sprintf("%v/branch.orq", tempDir)
+	if err := project.Branch(baselines[1], branchPath); err != nil {
+		t.Error("failed to branch:", err)
+		return
+	}
+	branch, err := NewProject(branchPath)
+	if err != nil {
+		t.Error("failed to open branch:", err)
+		return
+	}
+	defer branch.Close()
+	if item, err := branch.Data().ItemByUID(reqUID); err != nil || item.Description() != "changed" {
+		t.Error("expected branch to have the changed requirement:", err)
+	}
+	if branchBaselines, _ := branch.Data().Baselines(); len(branchBaselines) != 2 {
+		t.Error("expected branch to keep baselines, but got", len(branchBaselines))
+	}
+}
@@ -0,0 +1,1252 @@
+//go:build headless
+// +build headless
+
+// The orq command line tool, built without any Qt dependencies using:
+//   go build -tags headless -o orq
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// CliCommand is a sub command of the orq tool
+type CliCommand struct {
+	Name  string
+	Usage string
+	Info  string
+	Run   func(args []string) error
+}
+
+// CliCommands gets all available sub commands
+func CliCommands() []CliCommand {
+	return []CliCommand{
+		{"create", "<project.orq> [name]", "Create a new, empty project", CliCreate},
+		{"list", "[-baseline name] <project.orq>", "List all items as a tree", CliList},
+		{"show", "[-baseline name] <project.orq> <uid>", "Show all properties of an item", CliShow},
+		{"add-requirement", "[-parent uid] [-rationale text] [-fit text] <project.orq> <description>",
+			"Add a new problem", CliAddRequirement},
+		{"add-solution", "[-parent uid] <project.orq> <description>", "Add a new solution", CliAddSolution},
+		{"link", "<project.orq> <parent uid> <child uid>", "Link an item to a parent", CliLink},
+		{"unlink", "<project.orq> <child uid>", "Remove the link to the parent of an item", CliUnlink},
+		{"rename", "<project.orq> <name>", "Set the name of the project", CliRename},
+		{"convert", "[-baseline name] [-dry-run] <input> <output>", "Convert between .orq, .orqz, .json and .reqif", CliConvert},
+		{"import-csv", "[-tsv] [-no-header] [-map field=column,...] [-dry-run] <project.orq> <items.csv>",
+			"Add items from a spreadsheet, with parents by id or uid", CliImportCSV},
+		{"import-outline", "[-dry-run] <project.orq> <outline.md>",
+			"Add or update items from a markdown outline, writing new uids back to it", CliImportOutline},
+		{"import-tree", "[-parent uid | -pos x,y] [-on-conflict skip|overwrite|rekey] [-dry-run] <project.orq> <tree.json|orq>",
+			"Add all items from another project, under an item or at a position", CliImportTree},
+		{"export-outline", "<project.orq> <outline.md>", "Write all items as a markdown outline", CliExportOutline},
+		{"freeze", "<project.orq> <name>", "Freeze all items and links as a new baseline", CliFreeze},
+		{"baselines", "<project.orq>", "List all baselines", CliBaselines},
+		{"branch", "<project.orq> <baseline> <new.orq>",
+			"Create a new project to continue working from a baseline", CliBranch},
+		{"diff", "[-json] [-from baseline] [-to baseline] <old> <new>",
+			"Show items added, removed or changed between two projects or baselines", CliDiff},
+		{"merge", "[-json] [-prefer ours|theirs] <base> <ours> <theirs> <output.orq>",
+			"Merge changes from two copies of a project with a common ancestor", CliMerge},
+		{"trace", "[-baseline name] <project> <output.csv|html|xlsx>",
+			"Export a traceability matrix of problems and solutions, and a flat trace table", CliTrace},
+		{"diagram", "[-baseline name] [-root uid] [-format dot|mermaid|plantuml] <project> <output>",
+			"Export items and links as a Graphviz, Mermaid or PlantUML diagram", CliDiagram},
+		{"spec", "[-baseline name] <project> <output.md|html|pdf>",
+			"Generate a specification document with a numbered section for each problem", CliSpec},
+		{"rules", "<project.orq> [rule on|off|error|warning|info|param=value...]",
+			"Show or change what validation rules are used by a project", CliRules},
+		{"add-rule", "[-severity error|warning|info] [-replace name] <project.orq> <name> <expression>",
+			"Add a custom validation rule, that every item should match", CliAddRule},
+		{"remove-rule", "<project.orq> <name>", "Remove a custom validation rule", CliRemoveRule},
+		{"validate", "[-baseline name] [-format text|json|junit|sarif] [-o file] <project.orq>",
+			"Run all enabled validation rules, failing if any rule with error severity fails", CliValidate},
+		{"version", "", "Show version information", CliVersion},
+	}
+}
+
+// CliUsageError is returned when a command was called with invalid arguments
+type CliUsageError struct {
+	command CliCommand
+}
+
+func (err CliUsageError) Error() string {
+	return fmt.Sprintf("usage: orq %v %v", err.command.Name, err.command.Usage)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		CliPrintUsage()
+		os.Exit(2)
+	}
+	for _, command := range CliCommands() {
+		if command.Name != os.Args[1] {
+			continue
+		}
+		if err := command.Run(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			if _, ok := err.(CliUsageError); ok {
+				os.Exit(2)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "error: unknown command \"%v\"\n", os.Args[1])
+	CliPrintUsage()
+	os.Exit(2)
+}
+
+// CliPrintUsage prints all available commands
+func CliPrintUsage() {
+	fmt.Fprintln(os.Stderr, "usage: orq <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	writer := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	for _, command := range CliCommands() {
+		fmt.Fprintf(writer, "  %v\t%v\n", command.Name, command.Info)
+	}
+	writer.Flush()
+}
+
+// CliGetCommand gets a command by name
+func CliGetCommand(name string) CliCommand {
+	for _, command := range CliCommands() {
+		if command.Name == name {
+			return command
+		}
+	}
+	return CliCommand{Name: name}
+}
+
+// CliArgs parses flags for a command and makes sure the correct amount of arguments are left
+func CliArgs(name string, flags *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	command := CliGetCommand(name)
+	if flags == nil {
+		flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	}
+	flags.SetOutput(ioutil.Discard)
+	if err := flags.Parse(args); err != nil {
+		return nil, CliUsageError{command}
+	}
+	if flags.NArg() < min || (max >= 0 && flags.NArg() > max) {
+		return nil, CliUsageError{command}
+	}
+	return flags.Args(), nil
+}
+
+// CliOpenProject opens an already existing project
+func CliOpenProject(path string) (*Project, error) {
+	if filepath.Ext(path) != ".orq" {
+		return nil, fmt.Errorf("\"%v\" is not a project, use convert to create one", path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return NewProject(path)
+}
+
+// CliOpenBaseline opens a read-only copy of a baseline in the project,
+// or returns the project itself if name is empty
+func CliOpenBaseline(project *Project, name string) (*Project, error) {
+	if name == "" {
+		return project, nil
+	}
+	baseline, err := project.Data().BaselineByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return project.OpenBaseline(baseline)
+}
+
+// CliFindItem finds an item in the project from a formatted uid
+func CliFindItem(db *DataContext, uid string) (Item, error) {
+	itemUID, err := ParseUID(uid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid \"%v\"", uid)
+	}
+	return db.ItemByUID(itemUID)
+}
+
+func CliCreate(args []string) error {
+	args, err := CliArgs("create", nil, args, 1, 2)
+	if err != nil {
+		return err
+	}
+	path := args[0]
+	if !strings.HasSuffix(path, ".orq") {
+		path += ".orq"
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return fmt.Errorf("file with name \"%v\" already exists", path)
+	}
+	project, err := NewProject(path)
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	if len(args) > 1 {
+		db := project.Data()
+		db.SetProjectName(args[1])
+	}
+	return nil
+}
+
+// CliPrintTree prints an item and all its children, indented by depth
+func CliPrintTree(writer *tabwriter.Writer, graph *Graph, item Item, depth int) {
+	fmt.Fprintf(writer, "%v\t%v\t%v%v\n", FormatUID(item.UID()), GetItemName(item), strings.Repeat("  ", depth),
+		Truncate(strings.ReplaceAll(PlainText(graph.Description(item)), "\n", " "), 60))
+	for _, child := range graph.Children(item) {
+		CliPrintTree(writer, graph, child, depth+1)
+	}
+}
+
+func CliList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	baseline := flags.String("baseline", "", "name of baseline to list instead")
+	args, err := CliArgs("list", flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	if project, err = CliOpenBaseline(project, *baseline); err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	graph, err := LoadGraph(db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%v\n\n", db.ProjectName())
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "UID\tTYPE\tDESCRIPTION")
+	for _, root := range graph.Roots() {
+		CliPrintTree(writer, graph, root, 0)
+	}
+	return writer.Flush()
+}
+
+func CliShow(args []string) error {
+	flags := flag.NewFlagSet("show", flag.ContinueOnError)
+	baseline := flags.String("baseline", "", "name of baseline to show item from")
+	args, err := CliArgs("show", flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	if project, err = CliOpenBaseline(project, *baseline); err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	item, err := CliFindItem(db, args[1])
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "UID:\t%v\n", FormatUID(item.UID()))
+	fmt.Fprintf(writer, "Type:\t%v\n", GetItemName(item))
+	if parent := item.Parent(); parent != nil {
+		fmt.Fprintf(writer, "Parent:\t%v\n", FormatUID(parent.UID()))
+	}
+	children := make([]string, 0)
+	for _, child := range item.Children() {
+		children = append(children, FormatUID(child.UID()))
+	}
+	if len(children) > 0 {
+		fmt.Fprintf(writer, "Children:\t%v\n", strings.Join(children, ", "))
+	}
+	x, y := item.Pos()
+	w, h := item.Size()
+	fmt.Fprintf(writer, "Position:\t%v, %v\n", x, y)
+	fmt.Fprintf(writer, "Size:\t%v x %v\n", w, h)
+	media, err := db.ItemMedia(item.UID())
+	if err != nil {
+		return err
+	}
+	for _, attachment := range media {
+		fmt.Fprintf(writer, "Attachment:\t%v (%v)\n", attachment.Name, attachment.Format)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nDescription:\n%v\n", PlainText(item.Description()))
+	if req, isReq := item.(Requirement); isReq {
+		fmt.Printf("\nRationale:\n%v\n", PlainText(req.Rationale()))
+		fmt.Printf("\nFit Criterion:\n%v\n", PlainText(req.FitCriterion()))
+	}
+	return nil
+}
+
+// CliPlaceItem positions a newly added item below its parent, or below everything else if it's a root
+func CliPlaceItem(db *DataContext, item, parent Item) error {
+	if parent != nil {
+		children, err := db.ItemChildren(parent)
+		if err != nil {
+			return err
+		}
+		x, y := parent.Pos()
+		item.SetPos(x+(len(children)-1)*160, y+128)
+		return nil
+	}
+	var bottom int
+	if err := db.conn().QueryRow("select coalesce(max(y + height), 0) from " +
+		"(select y, height from Requirements union all select y, height from Solutions)").Scan(&bottom); err != nil {
+		return err
+	}
+	item.SetPos(0, bottom+64)
+	return nil
+}
+
+// CliAddItem adds a new item to the project, optionally with a parent
+func CliAddItem(name string, itemType ItemType, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	parentUID := flags.String("parent", "", "uid of parent item")
+	var rationale, fitCriterion string
+	if itemType == TypeRequirement {
+		flags.StringVar(&rationale, "rationale", "", "rationale of the problem")
+		flags.StringVar(&fitCriterion, "fit", "", "fit criterion of the problem")
+	}
+	args, err := CliArgs(name, flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	// Find parent before adding anything
+	var parent Item
+	if *parentUID != "" {
+		if parent, err = CliFindItem(db, *parentUID); err != nil {
+			return err
+		}
+	}
+	// Add item, with parent and position
+	uid := db.ItemUID()
+	err = db.Transaction(func() error {
+		var id int64
+		var err error
+		if itemType == TypeRequirement {
+			id, err = db.AddRequirement(TextToHTML(args[1]), TextToHTML(rationale), TextToHTML(fitCriterion), uid)
+		} else {
+			id, err = db.AddSolution(TextToHTML(args[1]), uid)
+		}
+		if err != nil {
+			return err
+		}
+		item := NewItem(id, itemType)
+		if parent != nil {
+			if err = db.AddItemChild(parent, item); err != nil {
+				return err
+			}
+		}
+		return CliPlaceItem(db, item, parent)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(FormatUID(uid))
+	return nil
+}
+
+func CliAddRequirement(args []string) error {
+	return CliAddItem("add-requirement", TypeRequirement, args)
+}
+
+func CliAddSolution(args []string) error {
+	return CliAddItem("add-solution", TypeSolution, args)
+}
+
+func CliLink(args []string) error {
+	args, err := CliArgs("link", nil, args, 3, 3)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	parent, err := CliFindItem(db, args[1])
+	if err != nil {
+		return err
+	}
+	child, err := CliFindItem(db, args[2])
+	if err != nil {
+		return err
+	}
+	if parent == child {
+		return fmt.Errorf("can't link an item to itself")
+	}
+	// Child can't be linked below one of its own children
+	for ancestor := parent.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		if ancestor == child {
+			return fmt.Errorf("can't link an item below its own child")
+		}
+	}
+	if !child.IsPropertyNull("parent") {
+		return fmt.Errorf("child already has a parent, unlink it first")
+	}
+	return db.AddItemChild(parent, child)
+}
+
+func CliUnlink(args []string) error {
+	args, err := CliArgs("unlink", nil, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	child, err := CliFindItem(db, args[1])
+	if err != nil {
+		return err
+	}
+	if child.IsPropertyNull("parent") {
+		return fmt.Errorf("item doesn't have a parent")
+	}
+	child.SetParent(nil)
+	return nil
+}
+
+func CliRename(args []string) error {
+	args, err := CliArgs("rename", nil, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	db.SetProjectName(args[1])
+	return nil
+}
+
+// CliLoadAnyProject loads a project from any supported format,
+// converting it to a temporary project if needed
+func CliLoadAnyProject(path, tempDir string) (*Project, error) {
+	switch filepath.Ext(path) {
+	case ".orq":
+		return CliOpenProject(path)
+	case ".orqz":
+		compressed, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		decompressed, err := Decompress(compressed)
+		if err != nil {
+			return nil, err
+		}
+		tempPath := filepath.Join(tempDir, "project.orq")
+		if err := ioutil.WriteFile(tempPath, decompressed, 0644); err != nil {
+			return nil, err
+		}
+		return NewProject(tempPath)
+	case ".json":
+		return ImportJSON(path, filepath.Join(tempDir, "project.orq"))
+	case ".reqif":
+		return ImportReqIF(path, filepath.Join(tempDir, "project.orq"))
+	}
+	return nil, fmt.Errorf("unknown project format \"%v\"", filepath.Ext(path))
+}
+
+func CliConvert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	baseline := flags.String("baseline", "", "name of baseline to convert instead")
+	dryRun := flags.Bool("dry-run", false, "only check the json input and show what it contains")
+	args, err := CliArgs("convert", flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	input, output := args[0], args[1]
+	if *dryRun {
+		if filepath.Ext(input) != ".json" {
+			return fmt.Errorf("-dry-run is only supported for .json input")
+		}
+		jsonImport, err := ReadJSONFile(input)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v: %v\n", jsonImport.ProjectName, jsonImport.Summary())
+		return nil
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		return fmt.Errorf("file with name \"%v\" already exists", output)
+	}
+	tempDir, err := ioutil.TempDir("", "orq")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := CliLoadAnyProject(input, tempDir)
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	if project, err = CliOpenBaseline(project, *baseline); err != nil {
+		return err
+	}
+	defer project.Close()
+	switch filepath.Ext(output) {
+	case ".orq", ".orqz":
+		return project.CopyTo(output)
+	case ".json":
+		db := project.Data()
+		graph, err := LoadGraph(db)
+		if err != nil {
+			return err
+		}
+		return project.SaveJSON(output, graph)
+	case ".reqif":
+		return project.ExportReqIF(output)
+	}
+	return fmt.Errorf("unknown project format \"%v\"", filepath.Ext(output))
+}
+
+func CliTrace(args []string) error {
+	flags := flag.NewFlagSet("trace", flag.ContinueOnError)
+	baseline := flags.String("baseline", "", "name of baseline to export instead")
+	args, err := CliArgs("trace", flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	input, output := args[0], args[1]
+	tempDir, err := ioutil.TempDir("", "orq")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := CliLoadAnyProject(input, tempDir)
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	if project, err = CliOpenBaseline(project, *baseline); err != nil {
+		return err
+	}
+	defer project.Close()
+	trace, err := project.Data().Traceability()
+	if err != nil {
+		return err
+	}
+	if err := trace.Export(output, project.Data().ProjectName()); err != nil {
+		return err
+	}
+	fmt.Printf("exported %v problems and %v solutions to %v\n", len(trace.Problems), len(trace.Solutions), output)
+	return nil
+}
+
+func CliSpec(args []string) error {
+	flags := flag.NewFlagSet("spec", flag.ContinueOnError)
+	baseline := flags.String("baseline", "", "name of baseline to generate from instead")
+	args, err := CliArgs("spec", flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	input, output := args[0], args[1]
+	tempDir, err := ioutil.TempDir("", "orq")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := CliLoadAnyProject(input, tempDir)
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	if project, err = CliOpenBaseline(project, *baseline); err != nil {
+		return err
+	}
+	defer project.Close()
+	spec, err := project.Data().Specification()
+	if err != nil {
+		return err
+	}
+	if err := spec.Export(output); err != nil {
+		return err
+	}
+	fmt.Printf("generated %v sections to %v\n", len(spec.Sections), output)
+	return nil
+}
+
+func CliDiagram(args []string) error {
+	flags := flag.NewFlagSet("diagram", flag.ContinueOnError)
+	baseline := flags.String("baseline", "", "name of baseline to export instead")
+	root := flags.String("root", "", "uid of item to only export the subtree of")
+	format := flags.String("format", "", "format of the diagram, instead of from the file extension")
+	args, err := CliArgs("diagram", flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	input, output := args[0], args[1]
+	if *format == "" {
+		if *format = DiagramFormat(output); *format == "" {
+			return fmt.Errorf("unknown diagram format \"%v\", use -format", filepath.Ext(output))
+		}
+	}
+	tempDir, err := ioutil.TempDir("", "orq")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := CliLoadAnyProject(input, tempDir)
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	if project, err = CliOpenBaseline(project, *baseline); err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	var rootItem Item
+	if *root != "" {
+		if rootItem, err = CliFindItem(db, *root); err != nil {
+			return err
+		}
+	}
+	diagram, err := db.Diagram(rootItem)
+	if err != nil {
+		return err
+	}
+	// Write to stdout if output is -
+	if output == "-" {
+		return diagram.Write(os.Stdout, *format)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := diagram.Write(file, *format); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("exported %v items and %v links to %v\n", len(diagram.Nodes), len(diagram.Links), output)
+	return nil
+}
+
+func CliImportCSV(args []string) error {
+	flags := flag.NewFlagSet("import-csv", flag.ContinueOnError)
+	tsv := flags.Bool("tsv", false, "columns are separated by tabs, default from file extension")
+	noHeader := flags.Bool("no-header", false, "first row is an item, not names of columns")
+	columns := flags.String("map", "", "fields of columns, like description=Text,parent=3")
+	dryRun := flags.Bool("dry-run", false, "only show what would be imported")
+	args, err := CliArgs("import-csv", flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	delimiter := CSVDelimiter(args[1])
+	if *tsv {
+		delimiter = '\t'
+	}
+	file, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	records, err := ReadCSV(file, delimiter)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("no rows found in \"%v\"", args[1])
+	}
+	// Columns are found by name in the header, unless mapped
+	header := records[0]
+	if *noHeader {
+		header = nil
+	}
+	mapping := GuessCSVMapping(header)
+	if *columns != "" {
+		overrides, err := ParseCSVMapping(*columns, header)
+		if err != nil {
+			return err
+		}
+		for field, column := range overrides {
+			mapping[field] = column
+		}
+	}
+	if _, found := mapping[CSVDescription]; !found {
+		return fmt.Errorf("no description column found, use -map description=column")
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	csvImport, err := db.PlanCSVImport(records, mapping, !*noHeader)
+	if err != nil {
+		return err
+	}
+	// Show all rows when only previewing, and otherwise only rows with errors
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "LINE\tTYPE\tDESCRIPTION\tPARENT\tERRORS")
+	for _, row := range csvImport.Rows {
+		if *dryRun || len(row.Errors) > 0 {
+			fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\n", row.Line, snapshotName(row.Snapshot),
+				Truncate(PlainText(row.Snapshot.Description), 40), row.Parent, strings.Join(row.Errors, "; "))
+		}
+	}
+	if *dryRun || csvImport.Errors() > 0 {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	if count := csvImport.Errors(); count > 0 {
+		return fmt.Errorf("%v rows have errors, nothing was imported", count)
+	}
+	if *dryRun {
+		return nil
+	}
+	command, err := csvImport.Command(db)
+	if err != nil {
+		return err
+	}
+	if err := db.Transaction(func() error {
+		return command.Redo(db)
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("imported %v items\n", len(csvImport.Rows))
+	return nil
+}
+
+func CliImportOutline(args []string) error {
+	flags := flag.NewFlagSet("import-outline", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only show what would be imported")
+	args, err := CliArgs("import-outline", flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	outline, err := ParseOutline(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	outlineImport, err := db.PlanOutlineImport(outline)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "LINE\tACTION\tTYPE\tUID\tDESCRIPTION")
+		for _, change := range outlineImport.Changes {
+			fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\n", change.Item.Line, change.Action,
+				snapshotName(change.Snapshot), FormatUID(change.Snapshot.UID), Truncate(change.Item.Description, 40))
+		}
+		return writer.Flush()
+	}
+	if command := outlineImport.Command(); command != nil {
+		if err := db.Transaction(func() error {
+			return command.Redo(db)
+		}); err != nil {
+			return err
+		}
+	}
+	// Uids are only written once the items exist
+	for _, item := range outline.Items {
+		if !item.HasUID {
+			if err := outline.WriteFile(args[1]); err != nil {
+				return fmt.Errorf("imported items, but failed to write uids to outline: %v", err)
+			}
+			break
+		}
+	}
+	fmt.Printf("added %v and updated %v items\n", outlineImport.Count(OutlineAdd), outlineImport.Count(OutlineUpdate))
+	return nil
+}
+
+func CliImportTree(args []string) error {
+	flags := flag.NewFlagSet("import-tree", flag.ContinueOnError)
+	parent := flags.String("parent", "", "uid of item to add the imported roots to")
+	pos := flags.String("pos", "0,0", "position to place the imported items at, as x,y")
+	onConflict := flags.String("on-conflict", "", "what to do with items that already exist")
+	dryRun := flags.Bool("dry-run", false, "only show what would be imported")
+	args, err := CliArgs("import-tree", flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	resolution, found := TreeResolutions[*onConflict]
+	if *onConflict != "" && !found {
+		return CliUsageError{CliGetCommand("import-tree")}
+	}
+	var placement TreePlacement
+	if _, err := fmt.Sscanf(*pos, "%d,%d", &placement.X, &placement.Y); err != nil {
+		return fmt.Errorf("invalid position \"%v\", expected x,y", *pos)
+	}
+	if *parent != "" {
+		if placement.ParentUID, err = ParseUID(*parent); err != nil {
+			return fmt.Errorf("invalid uid \"%v\"", *parent)
+		}
+		placement.HasParent = true
+	}
+	items, err := ReadProjectTree(args[1])
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	treeImport, err := db.PlanTreeImport(items, placement)
+	if err != nil {
+		return err
+	}
+	treeImport.ResolveAll(resolution)
+	if *dryRun || (len(treeImport.Conflicts) > 0 && *onConflict == "") {
+		conflicts := make(map[int64]bool)
+		for _, uid := range treeImport.Conflicts {
+			conflicts[uid] = true
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "ACTION\tTYPE\tUID\tDESCRIPTION")
+		for _, item := range treeImport.Items {
+			action := "add"
+			if conflicts[item.Snapshot.UID] {
+				action = treeImport.Resolution(item.Snapshot.UID).String()
+				if *onConflict == "" {
+					action = "exists"
+				}
+			}
+			fmt.Fprintf(writer, "%v\t%v\t%v\t%v\n", action, snapshotName(item.Snapshot),
+				FormatUID(item.Snapshot.UID), Truncate(PlainText(item.Snapshot.Description), 40))
+		}
+		if err := writer.Flush(); err != nil || *dryRun {
+			return err
+		}
+		return fmt.Errorf("%v items already exist, nothing was imported (use -on-conflict to resolve them)",
+			len(treeImport.Conflicts))
+	}
+	command, err := treeImport.Command(db)
+	if err != nil {
+		return err
+	}
+	if err := db.Transaction(func() error {
+		return command.Redo(db)
+	}); err != nil {
+		return err
+	}
+	added, overwritten := treeImport.Count()
+	fmt.Printf("added %v and overwrote %v items\n", added, overwritten)
+	return nil
+}
+
+func CliExportOutline(args []string) error {
+	args, err := CliArgs("export-outline", nil, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	// Write to stdout if output is -
+	if args[1] == "-" {
+		return project.Data().WriteOutline(os.Stdout)
+	}
+	file, err := os.Create(args[1])
+	if err != nil {
+		return err
+	}
+	if err := project.Data().WriteOutline(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func CliFreeze(args []string) error {
+	args, err := CliArgs("freeze", nil, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	baseline, err := project.Data().CreateBaseline(args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("froze %v items as %v\n", baseline.Items, baseline.Name)
+	return nil
+}
+
+func CliBaselines(args []string) error {
+	args, err := CliArgs("baselines", nil, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	baselines, err := project.Data().Baselines()
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tCREATED\tITEMS")
+	for _, baseline := range baselines {
+		fmt.Fprintf(writer, "%v\t%v\t%v\n", baseline.Name, baseline.Created, baseline.Items)
+	}
+	return writer.Flush()
+}
+
+func CliBranch(args []string) error {
+	args, err := CliArgs("branch", nil, args, 3, 3)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(args[2]) != ".orq" {
+		return fmt.Errorf("\"%v\" is not a project, branches can only be created as .orq", args[2])
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	baseline, err := project.Data().BaselineByName(args[1])
+	if err != nil {
+		return err
+	}
+	return project.Branch(baseline, args[2])
+}
+
+// CliPrintDiff prints all changes, with the old and new value of each changed field
+func CliPrintDiff(changes []ItemChange) {
+	symbols := map[ChangeKind]string{
+		ChangeAdded:   "+",
+		ChangeRemoved: "-",
+		ChangeChanged: "~",
+	}
+	for _, change := range changes {
+		snapshot := change.Snapshot()
+		fmt.Printf("%v %v %v %v\n", symbols[change.Kind], FormatUID(change.UID), snapshotName(snapshot),
+			Truncate(strings.ReplaceAll(PlainText(snapshot.Description), "\n", " "), 60))
+		for _, field := range change.Fields {
+			fmt.Printf("    %v: %q -> %q\n", field.Field, field.Old, field.New)
+		}
+	}
+	fmt.Println(DiffSummary(changes))
+}
+
+func CliDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "output changes as json")
+	from := flags.String("from", "", "baseline in the old project to compare")
+	to := flags.String("to", "", "baseline in the new project to compare")
+	args, err := CliArgs("diff", flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	before, err := LoadSnapshots(args[0], *from)
+	if err != nil {
+		return err
+	}
+	after, err := LoadSnapshots(args[1], *to)
+	if err != nil {
+		return err
+	}
+	changes := Diff(before, after)
+	if *asJSON {
+		data, err := json.MarshalIndent(changes, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	CliPrintDiff(changes)
+	return nil
+}
+
+// CliPrintConflicts prints all conflicts with the value in each version
+func CliPrintConflicts(conflicts []MergeConflict) {
+	for _, conflict := range conflicts {
+		fmt.Printf("conflict: %v %v\n", FormatUID(conflict.UID), conflict.Field)
+		fmt.Printf("    base:   %q\n", conflict.Base)
+		fmt.Printf("    ours:   %q\n", conflict.Ours)
+		fmt.Printf("    theirs: %q\n", conflict.Theirs)
+	}
+}
+
+func CliMerge(args []string) error {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "output conflicts as json")
+	prefer := flags.String("prefer", "", "resolve all conflicts using ours or theirs")
+	args, err := CliArgs("merge", flags, args, 4, 4)
+	if err != nil {
+		return err
+	}
+	if *prefer != "" && *prefer != "ours" && *prefer != "theirs" {
+		return CliUsageError{CliGetCommand("merge")}
+	}
+	output := args[3]
+	if filepath.Ext(output) != ".orq" {
+		return fmt.Errorf("\"%v\" is not a project, merges can only be saved as .orq", output)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		return fmt.Errorf("file with name \"%v\" already exists", output)
+	}
+	base, err := LoadSnapshots(args[0], "")
+	if err != nil {
+		return err
+	}
+	theirs, err := LoadSnapshots(args[2], "")
+	if err != nil {
+		return err
+	}
+	// Our project is kept open, as the merge is saved as a copy of it
+	tempDir, err := ioutil.TempDir("", "orq")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := CliLoadAnyProject(args[1], tempDir)
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	ours, err := project.Data().CurrentItems()
+	if err != nil {
+		return err
+	}
+	result := Merge(base, ours, theirs)
+	if len(result.Conflicts) > 0 {
+		if *prefer == "" {
+			if *asJSON {
+				data, err := json.MarshalIndent(result.Conflicts, "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			} else {
+				CliPrintConflicts(result.Conflicts)
+			}
+			return fmt.Errorf("%v conflicts, nothing was saved (use -prefer to resolve them)", len(result.Conflicts))
+		}
+		result.ResolveAll(*prefer == "theirs")
+	}
+	merged := result.Items()
+	if err := project.CopyWithItems(output, merged); err != nil {
+		return err
+	}
+	fmt.Printf("merged %v items (%v), %v conflicts\n",
+		len(merged), DiffSummary(Diff(ours, merged)), len(result.Conflicts))
+	return nil
+}
+
+// CliFormatParams formats all parameters of a rule as name=value
+func CliFormatParams(rule ValidationRule) string {
+	params := make([]string, 0, len(rule.Params))
+	for _, name := range rule.ParamNames() {
+		params = append(params, fmt.Sprintf("%v=%v", name, rule.Params[name]))
+	}
+	return strings.Join(params, " ")
+}
+
+func CliRules(args []string) error {
+	args, err := CliArgs("rules", nil, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	db := project.Data()
+	rules, err := db.ValidationRules()
+	if err != nil {
+		return err
+	}
+	// Change settings of a rule
+	if len(args) > 1 {
+		if len(args) < 3 {
+			return CliUsageError{CliGetCommand("rules")}
+		}
+		for _, rule := range rules {
+			if rule.Tag != args[1] {
+				continue
+			}
+			for _, setting := range args[2:] {
+				param := strings.SplitN(setting, "=", 2)
+				name := param[0]
+				switch {
+				case setting == "on" || setting == "off":
+					rule.Enabled = setting == "on"
+				case len(param) == 2:
+					if _, found := rule.Params[name]; !found {
+						return fmt.Errorf("rule %v has no parameter \"%v\"", rule.Tag, name)
+					}
+					if rule.Params[name], err = strconv.Atoi(param[1]); err != nil {
+						return fmt.Errorf("invalid value for %v: %v", name, param[1])
+					}
+				default:
+					if rule.Severity, err = ParseSeverity(setting); err != nil {
+						return err
+					}
+				}
+			}
+			return db.SaveValidationRule(rule)
+		}
+		return fmt.Errorf("no rule with tag \"%v\"", args[1])
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "RULE\tENABLED\tSEVERITY\tPARAMETERS\tDESCRIPTION")
+	for _, rule := range rules {
+		enabled := "off"
+		if rule.Enabled {
+			enabled = "on"
+		}
+		// Custom rules are described by their expression
+		description := rule.Name
+		if rule.IsCustom() {
+			description = rule.Info
+		}
+		fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\n", rule.Tag, enabled, rule.Severity, CliFormatParams(rule), description)
+	}
+	return writer.Flush()
+}
+
+func CliAddRule(args []string) error {
+	flags := flag.NewFlagSet("add-rule", flag.ContinueOnError)
+	severity := flags.String("severity", string(SeverityError), "how serious it is when the rule fails")
+	replace := flags.String("replace", "", "name of custom rule to replace")
+	args, err := CliArgs("add-rule", flags, args, 3, 3)
+	if err != nil {
+		return err
+	}
+	level, err := ParseSeverity(*severity)
+	if err != nil {
+		return err
+	}
+	rule, err := NewCustomRule(args[1], args[2], level)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	return project.Data().SaveCustomRule(*replace, rule)
+}
+
+func CliRemoveRule(args []string) error {
+	args, err := CliArgs("remove-rule", nil, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	return project.Data().RemoveCustomRule(args[1])
+}
+
+func CliValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	baseline := flags.String("baseline", "", "name of baseline to validate instead")
+	format := flags.String("format", "text", "format of the report")
+	output := flags.String("o", "", "file to write the report to")
+	args, err := CliArgs("validate", flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if !IsReportFormat(*format) {
+		return CliUsageError{CliGetCommand("validate")}
+	}
+	project, err := CliOpenProject(args[0])
+	if err != nil {
+		return err
+	}
+	defer project.Close()
+	// Baselines are validated with the current rules
+	rules, err := project.Data().ValidationRules()
+	if err != nil {
+		return err
+	}
+	if project, err = CliOpenBaseline(project, *baseline); err != nil {
+		return err
+	}
+	defer project.Close()
+	graph, err := LoadGraph(project.Data())
+	if err != nil {
+		return err
+	}
+	context, err := NewValidationContext(project.Data(), graph)
+	if err != nil {
+		return err
+	}
+	findings := Validate(context, rules)
+	report := NewValidationReport(filepath.Base(args[0]), context, rules, findings)
+	// Written to stdout, unless an output file is specified
+	writer := os.Stdout
+	if *output != "" {
+		if writer, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer writer.Close()
+	}
+	if err := report.Write(writer, *format); err != nil {
+		return err
+	}
+	// Failing rules with error severity fails the command, for use in pipelines
+	if count := report.Count(SeverityError); count > 0 {
+		return fmt.Errorf("%v findings with error severity", count)
+	}
+	return nil
+}
+
+func CliVersion(args []string) error {
+	if _, err := CliArgs("version", nil, args, 0, 0); err != nil {
+		return err
+	}
+	fmt.Println("orq", versionTagName)
+	return nil
+}
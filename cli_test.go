@@ -0,0 +1,158 @@
+//go:build headless
+// +build headless
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCliArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		min, max int
+		expected string
+		usage    bool
+	}{
+		{[]string{"a.orq"}, 1, 1, "a.orq", false},
+		{[]string{"-parent", "10", "a.orq", "Bow ramp"}, 2, 2, "a.orq,Bow ramp", false},
+		{[]string{"a.orq", "Ferry"}, 1, 2, "a.orq,Ferry", false},
+		{[]string{"a.orq", "on", "off", "error"}, 1, -1, "a.orq,on,off,error", false},
+		{[]string{}, 1, 1, "", true},
+		{[]string{"a.orq", "b.orq"}, 1, 1, "", true},
+		{[]string{"-unknown", "a.orq"}, 1, 1, "", true},
+		{[]string{"-parent"}, 0, 1, "", true},
+	}
+	for _, test := range tests {
+		flags := flag.NewFlagSet("add-solution", flag.ContinueOnError)
+		parent := flags.String("parent", "", "")
+		args, err := CliArgs("add-solution", flags, test.args, test.min, test.max)
+		if _, usage := err.(CliUsageError); usage != test.usage || (!usage && err != nil) {
+			t.Errorf("expected usage error to be %v for %v, but got %v", test.usage, test.args, err)
+			continue
+		}
+		if !test.usage && strings.Join(args, ",") != test.expected {
+			t.Errorf("expected arguments %v for %v, but got %v", test.expected, test.args, args)
+		}
+		if !test.usage && test.args[0] == "-parent" && *parent != "10" {
+			t.Errorf("expected parent flag for %v, but got \"%v\"", test.args, *parent)
+		}
+	}
+	// Usage errors show how the command is used
+	if _, err := CliArgs("link", nil, nil, 3, 3); err == nil || !strings.Contains(err.Error(), "orq link <project.orq>") {
+		t.Error("expected usage of link, but got", err)
+	}
+}
+
+// cliOutput runs a command, and gets what it printed
+func cliOutput(run func(args []string) error, args ...string) (string, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	err = run(args)
+	os.Stdout = stdout
+	writer.Close()
+	output, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	return strings.TrimSpace(string(output)), err
+}
+
+func TestCliRoundTrip(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	path := fmt.Sprintf("%v/openrq_test.orq", tempDir)
+	if err := CliCreate([]string{path, "Ferry"}); err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	if err := CliCreate([]string{path}); err == nil {
+		t.Error("expected creating existing project to fail")
+	}
+	ferry, err := cliOutput(CliAddRequirement, "-fit", "No water on deck", path, "Ferry must be safe")
+	if err != nil {
+		t.Error("failed to add requirement:", err)
+		return
+	}
+	ramp, err := cliOutput(CliAddSolution, path, "Bow ramp")
+	if err != nil {
+		t.Error("failed to add solution:", err)
+		return
+	}
+	if err := CliLink([]string{path, ferry, ramp}); err != nil {
+		t.Error("failed to link items:", err)
+	}
+	if err := CliLink([]string{path, ferry, "ZZ"}); err == nil {
+		t.Error("expected linking unknown item to fail")
+	}
+	// Items can't be linked below their own children
+	hull, err := cliOutput(CliAddSolution, "-parent", ramp, path, "Hull")
+	if err != nil {
+		t.Error("failed to add solution:", err)
+		return
+	}
+	if err := CliLink([]string{path, hull, ferry}); err == nil {
+		t.Error("expected linking item below its own child to fail")
+	}
+	if err := CliList([]string{"-baseline", "Unknown", path}); err == nil {
+		t.Error("expected listing unknown baseline to fail")
+	}
+	// Projects are closed when each command is done
+	if currentProject != nil && currentProject.Open {
+		t.Error("expected project to be closed after running command")
+	}
+	// Converting to json and back keeps all items and links
+	jsonPath := fmt.Sprintf("%v/openrq_test.json", tempDir)
+	if err := CliConvert([]string{path, jsonPath}); err != nil {
+		t.Error("failed to convert to json:", err)
+		return
+	}
+	jsonImport, err := ReadJSONFile(jsonPath)
+	if err != nil || jsonImport.ProjectName != "Ferry" || len(jsonImport.Items) != 3 {
+		t.Error("expected project in json, but got", jsonImport, err)
+		return
+	}
+	convertedPath := fmt.Sprintf("%v/openrq_converted.orq", tempDir)
+	if err := CliConvert([]string{jsonPath, convertedPath}); err != nil {
+		t.Error("failed to convert from json:", err)
+		return
+	}
+	items, err := LoadSnapshots(convertedPath, "")
+	if err != nil || len(items) != 3 {
+		t.Error("expected converted items, but got", items, err)
+		return
+	}
+	for _, item := range items {
+		switch FormatUID(item.UID) {
+		case ferry:
+			if PlainText(item.Description) != "Ferry must be safe" || PlainText(item.FitCriterion) != "No water on deck" ||
+				item.HasParent {
+				t.Error("unexpected requirement, got", item)
+			}
+		case ramp:
+			if PlainText(item.Description) != "Bow ramp" || formatParent(item) != ferry {
+				t.Error("unexpected solution, got", item)
+			}
+		case hull:
+			if formatParent(item) != ramp {
+				t.Error("unexpected solution, got", item)
+			}
+		default:
+			t.Error("unexpected item, got", item)
+		}
+	}
+	if currentProject != nil && currentProject.Open {
+		t.Error("expected project to be closed after converting")
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Fields that columns in a csv file can be mapped to
+const (
+	CSVDescription  = "description"
+	CSVRationale    = "rationale"
+	CSVFitCriterion = "fitCriterion"
+	CSVType         = "type"
+	CSVLabels       = "labels"
+	CSVID           = "id"
+	CSVParent       = "parent"
+)
+
+// CSVFields gets all fields columns can be mapped to, in the order they're shown
+func CSVFields() []string {
+	return []string{CSVDescription, CSVRationale, CSVFitCriterion, CSVType, CSVLabels, CSVID, CSVParent}
+}
+
+// csvHeaders are common names of columns for each field, in lower case without spaces
+var csvHeaders = map[string][]string{
+	CSVDescription:  {"description", "text", "requirement", "problem", "title", "summary"},
+	CSVRationale:    {"rationale", "reason", "why"},
+	CSVFitCriterion: {"fitcriterion", "fit", "acceptance", "acceptancecriteria", "acceptancecriterion"},
+	CSVType:         {"type", "itemtype", "kind"},
+	CSVLabels:       {"labels", "label", "tags", "tag"},
+	CSVID:           {"id", "rowid", "key", "ref", "reference"},
+	CSVParent:       {"parent", "parentid", "parentuid", "parentkey", "parentref"},
+}
+
+// CSVMapping is the column index of each field, where fields that aren't mapped are missing
+type CSVMapping map[string]int
+
+// GuessCSVMapping maps columns to fields from their names in the header,
+// returning an empty mapping if nothing matched, as the file probably has no header
+func GuessCSVMapping(header []string) CSVMapping {
+	mapping := make(CSVMapping)
+	for i, name := range header {
+		name = strings.ToLower(strings.Join(strings.Fields(strings.Replace(name, "_", " ", -1)), ""))
+		for _, field := range CSVFields() {
+			if _, mapped := mapping[field]; mapped {
+				continue
+			}
+			for _, header := range csvHeaders[field] {
+				if name == header {
+					mapping[field] = i
+				}
+			}
+		}
+	}
+	return mapping
+}
+
+// ParseCSVMapping parses a mapping written as field=column pairs separated by commas, like
+// "description=Text,parent=3", where columns are names in the header or numbers starting at 1
+func ParseCSVMapping(text string, header []string) (CSVMapping, error) {
+	mapping := make(CSVMapping)
+	for _, pair := range strings.Split(text, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid column mapping \"%v\", expected field=column", pair)
+		}
+		field, column := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if _, found := csvHeaders[field]; !found {
+			return nil, fmt.Errorf("unknown field \"%v\" (expected %v)", field, strings.Join(CSVFields(), ", "))
+		}
+		mapping[field] = -1
+		if number, err := strconv.Atoi(column); err == nil && number > 0 {
+			mapping[field] = number - 1
+			continue
+		}
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				mapping[field] = i
+				break
+			}
+		}
+		if mapping[field] < 0 {
+			return nil, fmt.Errorf("no column named \"%v\"", column)
+		}
+	}
+	return mapping, nil
+}
+
+// CSVDelimiter gets the delimiter of a file from its extension, tabs for .tsv and commas for everything else
+func CSVDelimiter(path string) rune {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsv", ".tab":
+		return '\t'
+	}
+	return ','
+}
+
+// ReadCSV reads all rows, where rows can have different number of columns
+func ReadCSV(reader io.Reader, delimiter rune) ([][]string, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = delimiter
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+	return csvReader.ReadAll()
+}
+
+// CSVRow is a row to import, with what item it will be added as
+type CSVRow struct {
+	// Line in the file, starting at 1
+	Line int
+	// ID other rows refer to it by, the line if there is no id column
+	ID string
+	// Reference to the parent as written in the file
+	Parent string
+	Labels []string
+	// Item to add, with its parent if it has one
+	Snapshot ItemSnapshot
+	// Problems found, the row can't be imported if there are any
+	Errors []string
+}
+
+// CSVImport is a preview of what will be added to the project, nothing is changed until it's applied
+type CSVImport struct {
+	// All rows, where parents always come before their children
+	Rows []CSVRow
+}
+
+// csvItemType gets the item type from a value in the type column
+func csvItemType(value string) (ItemType, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "p", "problem", "r", "req", "requirement":
+		return TypeRequirement, nil
+	case "s", "sol", "solution":
+		return TypeSolution, nil
+	}
+	return TypeRequirement, fmt.Errorf("unknown item type \"%v\"", value)
+}
+
+// PlanCSVImport creates a preview of importing the rows, skipping the first if it's a header
+func (data *DataContext) PlanCSVImport(records [][]string, mapping CSVMapping, header bool) (*CSVImport, error) {
+	items, err := data.LoadItems()
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[int64]bool)
+	// New items are placed below everything else
+	bottom := 0
+	for _, item := range items {
+		existing[item.UID] = true
+		if item.Y+item.Height+layoutRowHeight/2 > bottom {
+			bottom = item.Y + item.Height + layoutRowHeight/2
+		}
+	}
+	value := func(record []string, field string) string {
+		if column, mapped := mapping[field]; mapped && column >= 0 && column < len(record) {
+			return strings.TrimSpace(record[column])
+		}
+		return ""
+	}
+	rows := make([]CSVRow, 0, len(records))
+	byID := make(map[string]int)
+	uids := make(map[int64]bool)
+	for i, record := range records {
+		if i == 0 && header {
+			continue
+		}
+		row := CSVRow{Line: i + 1, ID: strconv.Itoa(i + 1), Parent: value(record, CSVParent)}
+		if _, mapped := mapping[CSVID]; mapped {
+			row.ID = value(record, CSVID)
+		}
+		// Empty rows are skipped, like in spreadsheets
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		itemType, err := csvItemType(value(record, CSVType))
+		if err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+		description := value(record, CSVDescription)
+		if description == "" {
+			row.Errors = append(row.Errors, "description is empty")
+		}
+		for _, label := range strings.FieldsFunc(value(record, CSVLabels), func(r rune) bool {
+			return r == ';' || r == ','
+		}) {
+			if label = strings.TrimSpace(label); label != "" {
+				row.Labels = append(row.Labels, label)
+			}
+		}
+		if row.ID != "" {
+			if other, found := byID[row.ID]; found {
+				row.Errors = append(row.Errors, fmt.Sprintf("same id as line %v", rows[other].Line))
+			} else {
+				byID[row.ID] = len(rows)
+			}
+		}
+		// New uids must not be the same as each other either
+		uid := data.ItemUID()
+		for uids[uid] {
+			uid = data.ItemUID()
+		}
+		uids[uid] = true
+		row.Snapshot = ItemSnapshot{
+			ItemData: ItemData{
+				UID:         uid,
+				Description: TextToHTML(description),
+				Width:       128,
+				Height:      64,
+			},
+			Type: itemType,
+		}
+		if itemType == TypeRequirement {
+			row.Snapshot.Rationale = TextToHTML(value(record, CSVRationale))
+			row.Snapshot.FitCriterion = TextToHTML(value(record, CSVFitCriterion))
+		}
+		rows = append(rows, row)
+	}
+	// Parents are rows in the file by id, or existing items by uid
+	parents := make(map[int]int)
+	for i := range rows {
+		row := &rows[i]
+		if row.Parent == "" {
+			continue
+		}
+		if parent, found := byID[row.Parent]; found {
+			if parent == i {
+				row.Errors = append(row.Errors, "item can't be its own parent")
+				continue
+			}
+			parents[i] = parent
+			row.Snapshot.HasParent, row.Snapshot.ParentUID = true, rows[parent].Snapshot.UID
+		} else if uid, err := ParseUID(row.Parent); err == nil && existing[uid] {
+			row.Snapshot.HasParent, row.Snapshot.ParentUID = true, uid
+		} else {
+			row.Errors = append(row.Errors, fmt.Sprintf("unknown parent \"%v\"", row.Parent))
+		}
+	}
+	// Sort parents before children, where rows in a loop are left in place
+	sorted := make([]CSVRow, 0, len(rows))
+	state := make(map[int]int)
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		switch state[i] {
+		case 1:
+			return false
+		case 2:
+			return true
+		}
+		state[i] = 1
+		if parent, found := parents[i]; found && !visit(parent) {
+			rows[i].Errors = append(rows[i].Errors, "parents link to each other in a loop")
+			delete(parents, i)
+		}
+		state[i] = 2
+		sorted = append(sorted, rows[i])
+		return true
+	}
+	for i := range rows {
+		visit(i)
+	}
+	// Place the new items as trees, where items with an existing parent are also roots
+	roots := make([]Item, 0)
+	children := make(map[Item][]Item)
+	keys := make(map[int64]Item)
+	for i, row := range sorted {
+		keys[row.Snapshot.UID] = NewItem(int64(i+1), TypeRequirement)
+	}
+	for _, row := range sorted {
+		key := keys[row.Snapshot.UID]
+		if parent, found := keys[row.Snapshot.ParentUID]; row.Snapshot.HasParent && found {
+			children[parent] = append(children[parent], key)
+		} else {
+			roots = append(roots, key)
+		}
+	}
+	positions := LayoutTree(roots, children)
+	for i := range sorted {
+		pos := positions[keys[sorted[i].Snapshot.UID]]
+		sorted[i].Snapshot.X, sorted[i].Snapshot.Y = pos[0], bottom+pos[1]
+	}
+	return &CSVImport{sorted}, nil
+}
+
+// Errors gets the number of rows with errors
+func (csvImport *CSVImport) Errors() int {
+	count := 0
+	for _, row := range csvImport.Rows {
+		if len(row.Errors) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// Command gets the command adding all items, with their labels, creating labels that don't exist yet.
+// Nothing is added if any row has errors.
+func (csvImport *CSVImport) Command(db *DataContext) (Command, error) {
+	if count := csvImport.Errors(); count > 0 {
+		return nil, fmt.Errorf("%v rows have errors", count)
+	}
+	if len(csvImport.Rows) == 0 {
+		return nil, fmt.Errorf("nothing to import")
+	}
+	commands := make([]Command, 0, len(csvImport.Rows)+1)
+	labelCommands := make([]Command, 0)
+	// Labels that don't exist yet are created as part of the command, once for all rows
+	created := make([]string, 0)
+	isCreated := make(map[string]bool)
+	for _, row := range csvImport.Rows {
+		commands = append(commands, NewAddItemCommand(row.Snapshot))
+		if len(row.Labels) == 0 {
+			continue
+		}
+		ids := make([]int64, 0, len(row.Labels))
+		names := make([]string, 0)
+		for _, name := range row.Labels {
+			if label, err := db.LabelByName(name); err == nil {
+				ids = append(ids, label.ID)
+				continue
+			}
+			if !isCreated[name] {
+				isCreated[name] = true
+				created = append(created, name)
+			}
+			names = append(names, name)
+		}
+		labelCommands = append(labelCommands,
+			NewLabelItemNamesCommand(row.Snapshot.UID, row.Snapshot.Type, nil, ids, names))
+	}
+	if len(created) > 0 {
+		commands = append([]Command{NewCreateLabelsCommand(created)}, commands...)
+	}
+	return NewCommandGroup(fmt.Sprintf("Import %v Items", len(csvImport.Rows)),
+		append(commands, labelCommands...)...), nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCSVImport(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	db := project.Data()
+	existingUID := db.ItemUID()
+	existing, _ := db.AddRequirement("Cars must board quickly", "", "", existingUID)
+	// Columns are guessed from common names, and can be overridden by name or number
+	records, err := ReadCSV(strings.NewReader("Key,Text,Type,Parent,Tags,Acceptance criteria\n"+
+		"A,Bow ramp,solution,"+FormatUID(existingUID)+",,\n"+
+		"B,Ramp must not leak,problem,A,safety; hull,No water on deck\n"+
+		",,,,,\n"+
+		"C,Pumps,s,B,safety,\n"), ',')
+	if err != nil {
+		t.Error("failed to read csv:", err)
+		return
+	}
+	mapping := GuessCSVMapping(records[0])
+	if fmt.Sprint(mapping) != "map[description:1 fitCriterion:5 id:0 labels:4 parent:3 type:2]" {
+		t.Error("unexpected guessed mapping, got", mapping)
+	}
+	if override, err := ParseCSVMapping("description=text, labels=6", records[0]); err != nil ||
+		override[CSVDescription] != 1 || override[CSVLabels] != 5 {
+		t.Error("unexpected mapping, got", override, err)
+	}
+	for _, invalid := range []string{"description", "size=1", "description=Missing"} {
+		if _, err := ParseCSVMapping(invalid, records[0]); err == nil {
+			t.Errorf("expected mapping \"%v\" to fail", invalid)
+		}
+	}
+	csvImport, err := db.PlanCSVImport(records, mapping, true)
+	if err != nil {
+		t.Error("failed to plan import:", err)
+		return
+	}
+	if len(csvImport.Rows) != 3 || csvImport.Errors() != 0 {
+		t.Error("expected 3 rows without errors, but got", csvImport.Rows)
+		return
+	}
+	solution, problem := csvImport.Rows[0].Snapshot, csvImport.Rows[1].Snapshot
+	if solution.Type != TypeSolution || solution.ParentUID != existingUID || problem.ParentUID != solution.UID {
+		t.Error("expected parents by id and uid, but got", csvImport.Rows)
+	}
+	if problem.FitCriterion != TextToHTML("No water on deck") || problem.Y <= solution.Y {
+		t.Error("unexpected problem, got", problem)
+	}
+	// Nothing is changed until the command is done
+	if items, _ := db.LoadItems(); len(items) != 1 {
+		t.Error("expected preview to not add items, but got", items)
+	}
+	command, err := csvImport.Command(db)
+	if err != nil {
+		t.Error("failed to get command:", err)
+		return
+	}
+	if labels, _ := db.Labels(); len(labels) != 0 {
+		t.Error("expected labels to only be created by the command, but got", labels)
+	}
+	history := NewHistory()
+	if err := history.Do(db, command); err != nil {
+		t.Error("failed to import:", err)
+		return
+	}
+	items, _ := db.LoadItems()
+	graph := NewGraphFromItems(items)
+	if len(items) != 4 || len(graph.Children(NewRequirement(existing))) != 1 {
+		t.Error("expected imported items below existing item, but got", items)
+	}
+	for _, item := range items {
+		if item.UID != existingUID && item.Y < 64 {
+			t.Error("expected imported items below existing items, but got", item)
+		}
+	}
+	if labels, _ := db.ItemLabels(problem.UID); len(labels) != 2 {
+		t.Error("expected new labels, but got", labels)
+	}
+	// Undoing removes everything at once
+	if err := history.Undo(db); err != nil {
+		t.Error("failed to undo:", err)
+	}
+	if items, _ := db.LoadItems(); len(items) != 1 {
+		t.Error("expected undo to remove imported items, but got", items)
+	}
+	if labels, _ := db.Labels(); len(labels) != 0 {
+		t.Error("expected undo to remove created labels, but got", labels)
+	}
+	_ = history.Redo(db)
+	if labels, _ := db.ItemLabels(problem.UID); len(labels) != 2 {
+		t.Error("expected labels to be created again, but got", labels)
+	}
+	_ = history.Undo(db)
+	// Each problem is shown on its row, and prevents importing
+	records, _ = ReadCSV(strings.NewReader("1\t\tproblem\t\n2\tWheels\tcar\t\n3\tLoop\t\t4\n"+
+		"4\tLoop\t\t3\n5\tOrphan\t\tZZ\n5\tSame\t\t\n"), '\t')
+	csvImport, err = db.PlanCSVImport(records, CSVMapping{CSVID: 0, CSVDescription: 1, CSVType: 2, CSVParent: 3}, false)
+	if err != nil {
+		t.Error("failed to plan import:", err)
+		return
+	}
+	results := make([]string, 0)
+	for _, row := range csvImport.Rows {
+		results = append(results, fmt.Sprintf("%v: %v", row.Line, strings.Join(row.Errors, ", ")))
+	}
+	if result := strings.Join(results, "; "); !strings.Contains(result, "1: description is empty") ||
+		!strings.Contains(result, "2: unknown item type \"car\"") || !strings.Contains(result, "in a loop") ||
+		!strings.Contains(result, "5: unknown parent \"ZZ\"") || !strings.Contains(result, "6: same id as line 5") {
+		t.Error("unexpected errors, got", result)
+	}
+	if command, err := csvImport.Command(db); err == nil {
+		t.Error("expected rows with errors to fail, but got", command)
+	}
+}
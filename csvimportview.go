@@ -0,0 +1,199 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// csvFieldNames are the names of fields as shown in the import wizard
+var csvFieldNames = map[string]string{
+	CSVDescription:  "Description",
+	CSVRationale:    "Rationale",
+	CSVFitCriterion: "Fit Criterion",
+	CSVType:         "Item Type",
+	CSVLabels:       "Labels",
+	CSVID:           "Row ID",
+	CSVParent:       "Parent (Row ID or UID)",
+}
+
+// csvDelimiters are the delimiters that can be picked, by name
+var csvDelimiters = []struct {
+	name      string
+	delimiter rune
+}{
+	{"Comma", ','},
+	{"Semicolon", ';'},
+	{"Tab", '\t'},
+}
+
+// ImportCSV asks for a csv or tsv file, and shows a preview of the items in it before adding them
+func ImportCSV(window *widgets.QMainWindow) {
+	if currentProject == nil || currentProject.ReadOnly() {
+		widgets.QMessageBox_Information(window, "No Project Loaded",
+			"No project is currently loaded to import into", widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	fileName := widgets.QFileDialog_GetOpenFileName(window, "Import CSV",
+		filepath.Dir(currentProject.FilePath()), "Spreadsheet(*.csv *.tsv *.txt)", "", 0)
+	if len(fileName) <= 0 {
+		return
+	}
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		widgets.QMessageBox_Critical(window, "Failed to Import CSV", err.Error(),
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	db := currentProject.Data()
+	dialog := widgets.NewQDialog(window, 0)
+	dialog.SetWindowTitle(fmt.Sprintf("Import %v", filepath.Base(fileName)))
+	dialog.Resize2(800, 560)
+	layout := widgets.NewQVBoxLayout()
+	// File options, and what field each column is
+	form := widgets.NewQFormLayout(nil)
+	delimiter := widgets.NewQComboBox(nil)
+	for i, option := range csvDelimiters {
+		delimiter.AddItem(option.name, core.NewQVariant1(i))
+		if option.delimiter == CSVDelimiter(fileName) {
+			delimiter.SetCurrentIndex(i)
+		}
+	}
+	form.AddRow3("Delimiter", delimiter)
+	header := widgets.NewQCheckBox2("First row has the names of columns", nil)
+	header.SetChecked(true)
+	form.AddRow5(header)
+	columns := make(map[string]*widgets.QComboBox)
+	for _, field := range CSVFields() {
+		columns[field] = widgets.NewQComboBox(nil)
+		form.AddRow3(csvFieldNames[field], columns[field])
+	}
+	layout.AddLayout(form, 0)
+	// Preview of each row, with errors
+	preview := widgets.NewQTreeWidget(nil)
+	preview.SetRootIsDecorated(false)
+	preview.SetHeaderLabels([]string{"Line", "Type", "Description", "Parent", "Errors"})
+	layout.AddWidget(preview, 1, 0)
+	summary := widgets.NewQLabel2("", nil, 0)
+	layout.AddWidget(summary, 0, 0)
+	buttons := widgets.NewQDialogButtonBox3(widgets.QDialogButtonBox__Ok|widgets.QDialogButtonBox__Cancel, nil)
+	importButton := buttons.Button(widgets.QDialogButtonBox__Ok)
+	importButton.SetText("Import")
+	buttons.ConnectAccepted(dialog.Accept)
+	buttons.ConnectRejected(dialog.Reject)
+	layout.AddWidget(buttons, 0, 0)
+	dialog.SetLayout(layout)
+
+	var records [][]string
+	var csvImport *CSVImport
+	// Columns are changed while updating, which shouldn't update the preview every time
+	updating := false
+	updatePreview := func() {
+		if updating {
+			return
+		}
+		mapping := make(CSVMapping)
+		for field, combo := range columns {
+			if column := combo.CurrentData(int(core.Qt__UserRole)).ToInt(nil); column >= 0 {
+				mapping[field] = column
+			}
+		}
+		preview.Clear()
+		importButton.SetEnabled(false)
+		if _, found := mapping[CSVDescription]; !found {
+			summary.SetText("Select what column has the description of each item")
+			return
+		}
+		if csvImport, err = db.PlanCSVImport(records, mapping, header.IsChecked()); err != nil {
+			summary.SetText(fmt.Sprintf("Failed to read items: %v", err))
+			return
+		}
+		errorBrush := gui.NewQBrush3(gui.NewQColor2(core.Qt__red), core.Qt__SolidPattern)
+		for _, row := range csvImport.Rows {
+			item := widgets.NewQTreeWidgetItem2([]string{
+				fmt.Sprint(row.Line), snapshotName(row.Snapshot),
+				Truncate(PlainText(row.Snapshot.Description), 60), row.Parent, strings.Join(row.Errors, "; "),
+			}, 0)
+			if len(row.Errors) > 0 {
+				for column := 0; column < 5; column++ {
+					item.SetForeground(column, errorBrush)
+				}
+				item.SetToolTip(4, strings.Join(row.Errors, "\n"))
+			}
+			preview.AddTopLevelItem(item)
+		}
+		errors := csvImport.Errors()
+		summary.SetText(fmt.Sprintf("%v items, %v rows with errors", len(csvImport.Rows), errors))
+		importButton.SetEnabled(errors == 0 && len(csvImport.Rows) > 0)
+	}
+	// Reading the file again also guesses the columns again
+	updateColumns := func() {
+		records, err = ReadCSV(bytes.NewReader(content), csvDelimiters[delimiter.CurrentIndex()].delimiter)
+		if err != nil {
+			records = nil
+		}
+		count := 0
+		for _, record := range records {
+			if len(record) > count {
+				count = len(record)
+			}
+		}
+		var names []string
+		if header.IsChecked() && len(records) > 0 {
+			names = records[0]
+		}
+		mapping := GuessCSVMapping(names)
+		updating = true
+		for field, combo := range columns {
+			combo.Clear()
+			combo.AddItem("(None)", core.NewQVariant1(-1))
+			for i := 0; i < count; i++ {
+				name := fmt.Sprintf("Column %v", i+1)
+				if i < len(names) && strings.TrimSpace(names[i]) != "" {
+					name = fmt.Sprintf("%v (%v)", names[i], i+1)
+				}
+				combo.AddItem(name, core.NewQVariant1(i))
+			}
+			if column, found := mapping[field]; found {
+				combo.SetCurrentIndex(column + 1)
+			}
+		}
+		updating = false
+		updatePreview()
+	}
+	delimiter.ConnectCurrentIndexChanged(func(index int) {
+		updateColumns()
+	})
+	header.ConnectToggled(func(checked bool) {
+		updateColumns()
+	})
+	for _, combo := range columns {
+		combo.ConnectCurrentIndexChanged(func(index int) {
+			updatePreview()
+		})
+	}
+	updateColumns()
+	if dialog.Exec() != int(widgets.QDialog__Accepted) || csvImport == nil {
+		return
+	}
+	// Importing can be undone like any other change
+	command, err := csvImport.Command(db)
+	if err == nil {
+		err = currentHistory.Do(db, command)
+	}
+	if err != nil {
+		widgets.QMessageBox_Critical(window, "Failed to Import CSV", err.Error(),
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	ReloadProject(window)
+}
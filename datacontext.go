@@ -3,9 +3,12 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -19,13 +22,68 @@ const (
 	TypeSolution    ItemType = 2
 )
 
-// DataContext holding the connection to the database
+// DataContext holding the connection to the database.
+// A DataContext must only be used from one goroutine, as everything using it while a transaction
+// is in progress becomes part of that transaction.
 type DataContext struct {
 	Database *sql.DB
+	// Transaction currently in progress, if any
+	tx *sql.Tx
+	// Directory of the copy opened instead of the original file, removed when closed
+	copyDir string
 }
 
-// NewDataContext creates a new database
+// sqlConn is either the database or the current transaction
+type sqlConn interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// conn gets the transaction in progress, or the database if there isn't one
+func (data *DataContext) conn() sqlConn {
+	if data.tx != nil {
+		return data.tx
+	}
+	return data.Database
+}
+
+// Transaction runs fn in a transaction, committing if fn returns nil.
+// Everything using the same DataContext until fn returns, including items changed through the current project,
+// is part of the transaction, and transactions started from fn are part of the same one.
+func (data *DataContext) Transaction(fn func() error) error {
+	if data.tx != nil {
+		return fn()
+	}
+	tx, err := data.Database.Begin()
+	if err != nil {
+		return err
+	}
+	data.tx = tx
+	// Always end transaction when done
+	defer func() {
+		data.tx = nil
+	}()
+	if err := fn(); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// NewDataContext creates a new database, or opens an existing one
 func NewDataContext(path string) *DataContext {
+	data, err := OpenDataContext(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return nil
+	}
+	return data
+}
+
+// OpenDataContext opens the database in path, creating it if it doesn't exist
+// or upgrading it if it was created by an older version
+func OpenDataContext(path string) (*DataContext, error) {
 	data := new(DataContext)
 
 	// Check beforehand if file exists
@@ -37,9 +95,10 @@ func NewDataContext(path string) *DataContext {
 
 	// Open file
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "error: failed to open database:", err)
-		return nil
+		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
+	// SQLite only allows one writer at the time
+	data.Database.SetMaxOpenConns(1)
 
 	// Create if it didn't exist
 	if !fileExists {
@@ -48,17 +107,58 @@ func NewDataContext(path string) *DataContext {
 			fileName = fileName[0:strings.LastIndex(fileName, ".")]
 		}
 		if err := data.Create(fileName); err != nil {
-			fmt.Fprintln(os.Stderr, "error: failed to create database:", err)
-			return nil
+			data.Close()
+			return nil, fmt.Errorf("failed to create database: %v", err)
 		}
+	} else if err := data.Migrate(path); err != nil {
+		// Upgrade if created by an older version
+		data.Close()
+		return nil, err
 	}
 
-	return data
+	return data, nil
+}
+
+// OpenDataContextCopy opens a temporary copy of the existing database in path,
+// so reading a project created by an older version never upgrades or otherwise changes it
+func OpenDataContextCopy(path string) (*DataContext, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return OpenTempDataContext(func(copyPath string) error {
+		return ioutil.WriteFile(copyPath, content, 0600)
+	})
 }
 
-// Close closes the connection to the database
+// OpenTempDataContext opens a database in a temporary directory, after create writes it,
+// where the directory is removed when the database is closed
+func OpenTempDataContext(create func(path string) error) (*DataContext, error) {
+	copyDir, err := ioutil.TempDir("", "openrq")
+	if err != nil {
+		return nil, err
+	}
+	copyPath := filepath.Join(copyDir, "project.orq")
+	if err := create(copyPath); err != nil {
+		_ = os.RemoveAll(copyDir)
+		return nil, err
+	}
+	data, err := OpenDataContext(copyPath)
+	if err != nil {
+		_ = os.RemoveAll(copyDir)
+		return nil, err
+	}
+	data.copyDir = copyDir
+	return data, nil
+}
+
+// Close closes the connection to the database, removing it if it was a copy
 func (data *DataContext) Close() error {
-	return data.Database.Close()
+	err := data.Database.Close()
+	if data.copyDir != "" {
+		_ = os.RemoveAll(data.copyDir)
+	}
+	return err
 }
 
 // Create creates a new, empty database
@@ -66,20 +166,20 @@ func (data *DataContext) Create(projectName string) error {
 	// Loop through table data
 	for key, value := range tableData {
 		// Execute query
-		_, err := data.Database.Exec(fmt.Sprintf("create table %s (%s)", key, strings.Join(value, ", ")))
+		_, err := data.conn().Exec(fmt.Sprintf("create table %s (%s)", key, strings.Join(value, ", ")))
 		// Check for errors
 		if err != nil {
 			return fmt.Errorf("error: failed to create %v table", key)
 		}
 	}
 	// Insert info table
-	_, err := data.Database.Exec("insert into Info (name) values (?)", projectName)
+	_, err := data.conn().Exec("insert into Info (name, version) values (?, ?)", projectName, SchemaVersion())
 	return err
 }
 
 func (data *DataContext) ProjectName() string {
 	var name string
-	row := data.Database.QueryRow("select name from Info")
+	row := data.conn().QueryRow("select name from Info")
 	if err := row.Scan(&name); err != nil {
 		fmt.Fprintln(os.Stderr, "warning: failed to get project name:", err)
 	}
@@ -87,7 +187,7 @@ func (data *DataContext) ProjectName() string {
 }
 
 func (data *DataContext) SetProjectName(name string) {
-	if _, err := data.Database.Exec("update Info set name = ?", name); err != nil {
+	if _, err := data.conn().Exec("update Info set name = ?", name); err != nil {
 		fmt.Fprintln(os.Stderr, "warning: failed to set project name:", err)
 	}
 }
@@ -99,62 +199,38 @@ func (data *DataContext) AddEmptyRequirement() (int64, error) {
 // AddRequirement adds a requirement to the current database
 func (data *DataContext) AddRequirement(description, rationale, fitCriterion string, reqUID int64) (int64, error) {
 	// Try to add the requirement to the database
-	if _, err := data.Database.Exec(
+	if _, err := data.conn().Exec(
 		"insert into Requirements (uid, description, rationale, fitCriterion) values (?, ?, ?, ?)",
 		reqUID, description, rationale, fitCriterion); err != nil {
 		return 0, err
 	}
 	// Get item ID
 	var id int64
-	if err := data.Database.QueryRow(
-		"select _rowid_ from Requirements where uid = ?", reqUID).Scan(&id); err != nil {
-		return 0, err
-	}
-	// Try to version it and return the result of it
-	return id, data.AddItemVersion(reqUID, TypeRequirement)
+	err := data.conn().QueryRow("select _rowid_ from Requirements where uid = ?", reqUID).Scan(&id)
+	return id, err
 }
 
 // AddSolution adds a solution to the database
 func (data *DataContext) AddSolution(description string, solUID int64) (int64, error) {
 	// Try to add the solution to the database
-	if _, err := data.Database.Exec(
+	if _, err := data.conn().Exec(
 		"insert into Solutions (uid, description) values (?, ?)",
 		solUID, description); err != nil {
 		return 0, err
 	}
 	var id int64
-	if err := data.Database.QueryRow(
-		"select _rowid_ from Solutions where uid = ?", solUID).Scan(&id); err != nil {
-		return 0, err
-	}
-	// Try to version it and return the result of it
-	return id, data.AddItemVersion(solUID, TypeSolution)
+	err := data.conn().QueryRow("select _rowid_ from Solutions where uid = ?", solUID).Scan(&id)
+	return id, err
 }
 
 func (data *DataContext) AddEmptySolution() (int64, error) {
 	return data.AddSolution("", data.ItemUID())
 }
 
-// AddItemVersion versions an item
-func (data *DataContext) AddItemVersion(itemUID int64, itemType ItemType) error {
-	// Find item ID
-	var itemID int
-	row := data.Database.QueryRow(
-		fmt.Sprintf("select _rowid_ from %v where uid = ?", GetItemTableName(itemType)), itemUID)
-	if err := row.Scan(&itemID); err != nil {
-		return err
-	}
-	// Insert into item versions
-	_, err := data.Database.Exec(
-		"insert into ItemVersions (version, item, type) values (1, ?, ?)",
-		itemID, itemType)
-	return err
-}
-
 // RemoveItem removes the item from the current version
 func (data *DataContext) RemoveItem(item Item) error {
 	// Execute SQL
-	_, err := data.Database.Exec(fmt.Sprintf("delete from %v where _rowid_ = ?",
+	_, err := data.conn().Exec(fmt.Sprintf("delete from %v where _rowid_ = ?",
 		GetItemTableName(GetItemType(item))), item.ID())
 	return err
 }
@@ -171,20 +247,20 @@ func (data *DataContext) UpdateItem(item Item, projectVersion int) error {
 	itemUID := data.ItemUID()
 	if isReq {
 		// Add requirement
-		_, err := data.Database.Exec("insert into Requirements (uid) values (?)", itemUID)
+		_, err := data.conn().Exec("insert into Requirements (uid) values (?)", itemUID)
 		if err != nil {
 			return fmt.Errorf("failed to insert requirement: %v", err)
 		}
 	} else {
 		// Add solution
-		_, err := data.Database.Exec("insert into Solutions (uid, description) values (?, ?)")
+		_, err := data.conn().Exec("insert into Solutions (uid, description) values (?, ?)")
 		if err != nil {
 			return fmt.Errorf("failed to insert solution: %v", err)
 		}
 	}
 
 	// Find item id
-	row := data.Database.QueryRow("select _rowid_ from Requirements where uid = ?", item.UID())
+	row := data.conn().QueryRow("select _rowid_ from Requirements where uid = ?", item.UID())
 	if err := row.Scan(&req.id); err != nil || req.id == 0 {
 		return fmt.Errorf("failed to get item id: %v", err)
 	}
@@ -201,7 +277,7 @@ func GetItemType(item Item) ItemType {
 	case Solution:
 		return TypeSolution
 	default:
-		fmt.Println("error: failed to get item type for id", item.ID())
+		fmt.Fprintln(os.Stderr, "error: failed to get item type for id", item.ID())
 		return 0
 	}
 }
@@ -218,13 +294,10 @@ func GetItemTableName(itemType ItemType) string {
 
 // GetAllItems gets all requirements and solutions stored in the database
 func (data *DataContext) Items() (items map[Item]string, err error) {
-	// Connect to database
-	db := currentProject.Data()
-	defer db.Close()
 	// Crate slice of items
 	items = make(map[Item]string)
 	// Get all requirements
-	rows, err := data.Database.Query("select _rowid_, description from Requirements")
+	rows, err := data.conn().Query("select _rowid_, description from Requirements")
 	if err != nil {
 		return items, fmt.Errorf("failed to get requirements: %v", err)
 	}
@@ -236,7 +309,7 @@ func (data *DataContext) Items() (items map[Item]string, err error) {
 		}
 	}
 	// Get all solutions
-	rows, err = data.Database.Query("select _rowid_, description from Solutions")
+	rows, err = data.conn().Query("select _rowid_, description from Solutions")
 	if err != nil {
 		return items, fmt.Errorf("failed to get solutions: %v", err)
 	}
@@ -249,13 +322,10 @@ func (data *DataContext) Items() (items map[Item]string, err error) {
 }
 
 func (data *DataContext) Links() (items map[Item]Item, err error) {
-	// Connect to database
-	db := currentProject.Data()
-	defer db.Close()
 	// Create map
 	items = make(map[Item]Item)
 	// Get parents for requirements
-	rows, err := db.Database.Query("select _rowid_, parent, parentType from Requirements where parent is not null")
+	rows, err := data.conn().Query("select _rowid_, parent, parentType from Requirements where parent is not null")
 	if err != nil {
 		return items, fmt.Errorf("failed to get requirement links: %v", err)
 	}
@@ -272,7 +342,7 @@ func (data *DataContext) Links() (items map[Item]Item, err error) {
 		}
 	}
 	// Get parents for solutions
-	rows, err = db.Database.Query("select _rowid_, parent, parentType from Solutions where parent is not null")
+	rows, err = data.conn().Query("select _rowid_, parent, parentType from Solutions where parent is not null")
 	if err != nil {
 		return items, fmt.Errorf("failed to get solution links: %v", err)
 	}
@@ -288,10 +358,49 @@ func (data *DataContext) Links() (items map[Item]Item, err error) {
 	return items, nil
 }
 
+// ItemChildren gets all items that have the specified item as parent
+func (data *DataContext) ItemChildren(parent Item) ([]Item, error) {
+	children := make([]Item, 0)
+	for _, itemType := range []ItemType{TypeRequirement, TypeSolution} {
+		rows, err := data.conn().Query(fmt.Sprintf(
+			"select _rowid_ from %v where parent = ? and parentType = ?", GetItemTableName(itemType)),
+			parent.ID(), GetItemType(parent))
+		if err != nil {
+			return children, fmt.Errorf("failed to get children of %v: %v", parent.ToString(), err)
+		}
+		var itemID int64
+		for rows.Next() {
+			if err = rows.Scan(&itemID); err != nil {
+				rows.Close()
+				return children, fmt.Errorf("failed to get child of %v: %v", parent.ToString(), err)
+			}
+			children = append(children, NewItem(itemID, itemType))
+		}
+		rows.Close()
+	}
+	return children, nil
+}
+
+// ItemByUID finds the requirement or solution with the specified uid
+func (data *DataContext) ItemByUID(uid int64) (Item, error) {
+	for _, itemType := range []ItemType{TypeRequirement, TypeSolution} {
+		var itemID int64
+		err := data.conn().QueryRow(fmt.Sprintf(
+			"select _rowid_ from %v where uid = ?", GetItemTableName(itemType)), uid).Scan(&itemID)
+		if err == nil {
+			return NewItem(itemID, itemType), nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to find item %x: %v", uid, err)
+		}
+	}
+	return nil, fmt.Errorf("no item with uid %x", uid)
+}
+
 // GetItemValue gets a value from the specified column in the database
 // (value is assumed to be a pointer)
 func (data *DataContext) GetItemValue(itemID int64, tableName, name string, value interface{}) error {
-	row := data.Database.QueryRow(fmt.Sprintf("select %v from %v where _rowid_ = ?", name, tableName), itemID)
+	row := data.conn().QueryRow(fmt.Sprintf("select %v from %v where _rowid_ = ?", name, tableName), itemID)
 	// Execute and return it
 	if err := row.Scan(value); err != nil {
 		return fmt.Errorf("failed to get property %v from item %v: %v", name, itemID, err)
@@ -301,10 +410,10 @@ func (data *DataContext) GetItemValue(itemID int64, tableName, name string, valu
 
 func (data *DataContext) IsItemPropertyNull(itemID int64, tableName, columnName string) bool {
 	var count int
-	row := data.Database.QueryRow(
+	row := data.conn().QueryRow(
 		fmt.Sprintf("select count(*) from %v where _rowid_ = ? and %v is null", tableName, columnName), itemID)
 	if err := row.Scan(&count); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, "error:", err)
 	}
 	return count > 0
 }
@@ -314,34 +423,158 @@ func (data *DataContext) AddItemChild(parent, child Item) error {
 	// Get what table name child has
 	childTable := GetItemTableName(GetItemType(child))
 	// Execute update
-	_, err := data.Database.Exec(
+	_, err := data.conn().Exec(
 		fmt.Sprintf("update %v set parent = ?, parentType = ? where _rowid_ = ?", childTable),
 		parent.ID(), GetItemType(parent), child.ID())
 	return err
 }
 
-// RemoveItemParent removes the link between parent and child
+// RemoveChildrenLinks removes the links from parent to all its children
 func (data *DataContext) RemoveChildrenLinks(parent Item) error {
-	// Execute update
-	_, err := data.Database.Exec(
-		fmt.Sprintf("update %v set parent = null, parentType = null where parent = ? and parentType = ?",
-			GetItemTableName(GetItemType(parent))), parent.ID(), GetItemType(parent))
-	return err
+	// Children can be of any type
+	for _, itemType := range []ItemType{TypeRequirement, TypeSolution} {
+		_, err := data.conn().Exec(fmt.Sprintf(
+			"update %v set parent = null, parentType = null where parent = ? and parentType = ?",
+			GetItemTableName(itemType)), parent.ID(), GetItemType(parent))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // SetItemValue updates a value in the database
 func (data *DataContext) SetItemValue(itemID int64, tableName, name string, value interface{}) {
-	_, err := data.Database.Exec(
+	_, err := data.conn().Exec(
 		fmt.Sprintf("update %v set %v = ? where _rowid_ = ?", tableName, name), value, itemID)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "warning: failed to set property", name, "in requirement:", err)
 	}
 }
 
+// sortedColumns gets the column names in a map in a consistent order
+func sortedColumns(nameValues map[string]interface{}) []string {
+	names := make([]string, 0, len(nameValues))
+	for name := range nameValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetItemValues gets several values from the same item in a single query
+// (values are assumed to be pointers)
+func (data *DataContext) GetItemValues(itemID int64, tableName string, nameValues map[string]interface{}) error {
+	names := sortedColumns(nameValues)
+	values := make([]interface{}, len(names))
+	for i, name := range names {
+		values[i] = nameValues[name]
+	}
+	row := data.conn().QueryRow(fmt.Sprintf("select %v from %v where _rowid_ = ?",
+		strings.Join(names, ", "), tableName), itemID)
+	if err := row.Scan(values...); err != nil {
+		return fmt.Errorf("failed to get properties %v from item %v: %v", strings.Join(names, ", "), itemID, err)
+	}
+	return nil
+}
+
+// SetItemValues updates several values of the same item in a single statement
+func (data *DataContext) SetItemValues(itemID int64, tableName string, nameValues map[string]interface{}) error {
+	names := sortedColumns(nameValues)
+	values := make([]interface{}, 0, len(names)+1)
+	for i, name := range names {
+		values = append(values, nameValues[name])
+		names[i] = name + " = ?"
+	}
+	_, err := data.conn().Exec(fmt.Sprintf("update %v set %v where _rowid_ = ?",
+		tableName, strings.Join(names, ", ")), append(values, itemID)...)
+	return err
+}
+
+// ItemData is all properties of an item stored in the database
+type ItemData struct {
+	Item   Item
+	UID    int64
+	Parent Item
+	// Description, rationale and fit criterion as html
+	Description, Rationale, FitCriterion string
+	Link                                 string
+	Color, Border, Shape                 int
+	X, Y, Width, Height                  int
+}
+
+// itemDataQuery gets the query for selecting all properties of items of the specified type
+func itemDataQuery(itemType ItemType) string {
+	if itemType == TypeRequirement {
+		return "select _rowid_, uid, parent, parentType, coalesce(description, ''), " +
+			"coalesce(rationale, ''), coalesce(fitCriterion, ''), '', coalesce(color, 0), coalesce(border, 0), " +
+			"coalesce(shape, 0), coalesce(x, 0), coalesce(y, 0), coalesce(width, 128), coalesce(height, 64) " +
+			"from Requirements"
+	}
+	return "select _rowid_, uid, parent, parentType, coalesce(description, ''), " +
+		"'', '', coalesce(link, ''), coalesce(color, 0), coalesce(border, 0), " +
+		"coalesce(shape, 0), coalesce(x, 0), coalesce(y, 0), coalesce(width, 128), coalesce(height, 64) " +
+		"from Solutions"
+}
+
+// scanItemData reads the current row of a query from itemDataQuery
+func scanItemData(rows *sql.Rows, itemType ItemType) (ItemData, error) {
+	var item ItemData
+	var itemID int64
+	var parent, parentType sql.NullInt64
+	if err := rows.Scan(&itemID, &item.UID, &parent, &parentType, &item.Description, &item.Rationale,
+		&item.FitCriterion, &item.Link, &item.Color, &item.Border, &item.Shape,
+		&item.X, &item.Y, &item.Width, &item.Height); err != nil {
+		return item, fmt.Errorf("failed to get item %v: %v", itemID, err)
+	}
+	item.Item = NewItem(itemID, itemType)
+	if parent.Valid && parentType.Valid {
+		item.Parent = NewItem(parent.Int64, ItemType(parentType.Int64))
+	}
+	return item, nil
+}
+
+// LoadItems gets all items, with all their properties and parents, using one query for each item type
+func (data *DataContext) LoadItems() ([]ItemData, error) {
+	items := make([]ItemData, 0)
+	for _, itemType := range []ItemType{TypeRequirement, TypeSolution} {
+		rows, err := data.conn().Query(itemDataQuery(itemType))
+		if err != nil {
+			return items, fmt.Errorf("failed to get %v: %v", strings.ToLower(GetItemTableName(itemType)), err)
+		}
+		for rows.Next() {
+			item, err := scanItemData(rows, itemType)
+			if err != nil {
+				rows.Close()
+				return items, err
+			}
+			items = append(items, item)
+		}
+		if err := rows.Close(); err != nil {
+			return items, err
+		}
+	}
+	return items, nil
+}
+
+// LoadItem gets all properties of a single item
+func (data *DataContext) LoadItem(item Item) (ItemData, error) {
+	itemType := GetItemType(item)
+	rows, err := data.conn().Query(itemDataQuery(itemType)+" where _rowid_ = ?", item.ID())
+	if err != nil {
+		return ItemData{}, fmt.Errorf("failed to get %v: %v", item.ToString(), err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return ItemData{}, fmt.Errorf("failed to get %v: item does not exist", item.ToString())
+	}
+	return scanItemData(rows, itemType)
+}
+
 // UidExists checking if the specified uid is already taken
 func (data *DataContext) UIDExists(uid int64) bool {
 	// Execute query
-	row := data.Database.QueryRow(
+	row := data.conn().QueryRow(
 		"select count(*) from (select uid from Requirements union select uid from Solutions) where uid = ?", uid)
 	// Get value from row
 	var count int
@@ -361,13 +594,23 @@ func (data *DataContext) ItemUID() int64 {
 	return id
 }
 
+// FormatUID formats a uid the same way as in exported projects
+func FormatUID(uid int64) string {
+	return strconv.FormatInt(uid, 16)
+}
+
+// ParseUID parses a uid formatted with FormatUID
+func ParseUID(uid string) (int64, error) {
+	return strconv.ParseInt(uid, 16, 64)
+}
+
 func (data *DataContext) UpdateItemChildren(oldParent, newParent Item) error {
 	tables := []string{
 		GetItemTableName(TypeRequirement),
 		GetItemTableName(TypeSolution),
 	}
 	for _, table := range tables {
-		_, err := data.Database.Exec(fmt.Sprintf(
+		_, err := data.conn().Exec(fmt.Sprintf(
 			"update %v set parent = ?, parentType = ? where parent = ? and parentType = ?", table),
 			newParent.ID(), GetItemType(newParent), oldParent.ID(), GetItemType(oldParent))
 		if err != nil {
@@ -375,4 +618,4 @@ func (data *DataContext) UpdateItemChildren(oldParent, newParent Item) error {
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
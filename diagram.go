@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// diagramLabelLength is the maximum length of labels, same as in the canvas
+const diagramLabelLength = 46
+
+// DiagramNode is an item in a diagram
+type DiagramNode struct {
+	// ID of the node in the diagram, from the type and uid of the item
+	ID    string
+	Item  Item
+	UID   int64
+	Label string
+}
+
+// Diagram is the items and links of a project, or of a part of it
+type Diagram struct {
+	Title string
+	// All nodes, depth first from each root
+	Nodes []DiagramNode
+	// Links from parent to child, by node id
+	Links [][2]string
+}
+
+// diagramID gets the id of an item in a diagram, which only contains letters and digits
+func diagramID(item Item, uid int64) string {
+	prefix := "P"
+	if GetItemType(item) == TypeSolution {
+		prefix = "S"
+	}
+	return prefix + strconv.FormatUint(uint64(uid), 16)
+}
+
+// diagramLabel gets the label of an item from its description as html
+func diagramLabel(item Item, description string) string {
+	text := strings.Replace(PlainText(description), "\n", " ", -1)
+	if text == "" {
+		return fmt.Sprintf("(%v)", item.ToString())
+	}
+	return Truncate(text, diagramLabelLength)
+}
+
+// Diagram gets all items and links below root, or in the whole project if root is nil
+func (data *DataContext) Diagram(root Item) (*Diagram, error) {
+	items, err := data.LoadItems()
+	if err != nil {
+		return nil, err
+	}
+	graph := NewGraphFromItems(items)
+	byItem := make(map[Item]ItemData)
+	for _, item := range items {
+		byItem[item.Item] = item
+	}
+	roots := append(graph.Roots(), graph.Items()...)
+	if root != nil {
+		if !graph.HasItem(root) {
+			return nil, fmt.Errorf("no item found for %v", root.ToString())
+		}
+		roots = []Item{root}
+	}
+	diagram := &Diagram{Title: data.ProjectName()}
+	// Items in loops are only added once
+	ids := make(map[Item]string)
+	var visit func(item Item)
+	visit = func(item Item) {
+		if _, found := ids[item]; found {
+			return
+		}
+		itemData := byItem[item]
+		ids[item] = diagramID(item, itemData.UID)
+		diagram.Nodes = append(diagram.Nodes, DiagramNode{
+			ids[item], item, itemData.UID, diagramLabel(item, itemData.Description),
+		})
+		for _, child := range graph.Children(item) {
+			visit(child)
+		}
+	}
+	for _, item := range roots {
+		visit(item)
+	}
+	for _, node := range diagram.Nodes {
+		for _, child := range graph.Children(node.Item) {
+			diagram.Links = append(diagram.Links, [2]string{node.ID, ids[child]})
+		}
+	}
+	return diagram, nil
+}
+
+// DiagramFormats are all formats diagrams can be exported as
+var DiagramFormats = []string{"dot", "mermaid", "plantuml"}
+
+// DiagramFormat gets the format of a diagram from a file extension, or an empty string if unknown
+func DiagramFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dot", ".gv":
+		return "dot"
+	case ".mmd", ".mermaid":
+		return "mermaid"
+	case ".puml", ".plantuml":
+		return "plantuml"
+	}
+	return ""
+}
+
+// diagramFormatError is returned for formats not in DiagramFormats
+func diagramFormatError(format string) error {
+	return fmt.Errorf("unknown diagram format \"%v\" (expected %v)", format, strings.Join(DiagramFormats, ", "))
+}
+
+// diagramColor gets a color as hex, like #9c27b0
+func diagramColor(color uint) string {
+	return fmt.Sprintf("#%06x", color)
+}
+
+// Write writes the diagram in the specified format, one of DiagramFormats
+func (diagram *Diagram) Write(writer io.Writer, format string) error {
+	var text string
+	switch format {
+	case "dot":
+		text = diagram.DOT()
+	case "mermaid":
+		text = diagram.Mermaid()
+	case "plantuml":
+		text = diagram.PlantUML()
+	default:
+		return diagramFormatError(format)
+	}
+	_, err := io.WriteString(writer, text)
+	return err
+}
+
+// Export writes the diagram to a file, in the format of its extension
+func (diagram *Diagram) Export(path string) error {
+	format := DiagramFormat(path)
+	if format == "" {
+		return fmt.Errorf("unknown diagram format \"%v\" (expected .dot, .mmd or .puml)", filepath.Ext(path))
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := diagram.Write(file, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// DOT gets the diagram as a Graphviz graph
+func (diagram *Diagram) DOT() string {
+	quote := func(text string) string {
+		return "\"" + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + "\""
+	}
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "digraph %v {\n", quote(diagram.Title))
+	builder.WriteString("\tnode [shape=box, fontname=\"sans-serif\"];\n")
+	for _, node := range diagram.Nodes {
+		fmt.Fprintf(&builder, "\t%v [label=%v, color=%v];\n", node.ID, quote(node.Label),
+			quote(diagramColor(GetItemColor(node.Item))))
+	}
+	for _, link := range diagram.Links {
+		fmt.Fprintf(&builder, "\t%v -> %v;\n", link[0], link[1])
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// Mermaid gets the diagram as a Mermaid flowchart
+func (diagram *Diagram) Mermaid() string {
+	// Characters that would end the label, or be read as html, are written as entity codes
+	escaper := strings.NewReplacer(`#`, "#35;", `"`, "#quot;", `<`, "#lt;", `>`, "#gt;")
+	var builder strings.Builder
+	builder.WriteString("flowchart TD\n")
+	classes := map[ItemType][]string{}
+	for _, node := range diagram.Nodes {
+		fmt.Fprintf(&builder, "\t%v[\"%v\"]\n", node.ID, escaper.Replace(node.Label))
+		classes[GetItemType(node.Item)] = append(classes[GetItemType(node.Item)], node.ID)
+	}
+	for _, link := range diagram.Links {
+		fmt.Fprintf(&builder, "\t%v --> %v\n", link[0], link[1])
+	}
+	fmt.Fprintf(&builder, "\tclassDef problem stroke:%v\n", diagramColor(ProblemColor))
+	fmt.Fprintf(&builder, "\tclassDef solution stroke:%v\n", diagramColor(SolutionColor))
+	if ids := classes[TypeRequirement]; len(ids) > 0 {
+		fmt.Fprintf(&builder, "\tclass %v problem\n", strings.Join(ids, ","))
+	}
+	if ids := classes[TypeSolution]; len(ids) > 0 {
+		fmt.Fprintf(&builder, "\tclass %v solution\n", strings.Join(ids, ","))
+	}
+	return builder.String()
+}
+
+// PlantUML gets the diagram as a PlantUML diagram of rectangles
+func (diagram *Diagram) PlantUML() string {
+	// Quotes can't be escaped in labels, but unicode characters can be written by code point
+	escaper := strings.NewReplacer(`"`, "<U+0022>", "\n", " ")
+	var builder strings.Builder
+	builder.WriteString("@startuml\n")
+	if diagram.Title != "" {
+		fmt.Fprintf(&builder, "title %v\n", escaper.Replace(diagram.Title))
+	}
+	// Colors after ## are used for the border
+	for _, node := range diagram.Nodes {
+		fmt.Fprintf(&builder, "rectangle \"%v\" as %v #%v\n", escaper.Replace(node.Label), node.ID,
+			diagramColor(GetItemColor(node.Item)))
+	}
+	for _, link := range diagram.Links {
+		fmt.Fprintf(&builder, "%v --> %v\n", link[0], link[1])
+	}
+	builder.WriteString("@enduml\n")
+	return builder.String()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDiagram(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	db := project.Data()
+	db.SetProjectName("Ferry")
+	rootUID, solutionUID := db.ItemUID(), db.ItemUID()
+	root, _ := db.AddRequirement("<p>Cars must board \"quickly\" &amp; safely, even in heavy weather</p>", "", "", rootUID)
+	solution, _ := db.AddSolution("", solutionUID)
+	other, _ := db.AddRequirement("Passengers need seats", "", "", db.ItemUID())
+	_ = db.AddItemChild(NewRequirement(root), NewSolution(solution))
+	diagram, err := db.Diagram(nil)
+	if err != nil {
+		t.Error("failed to get diagram:", err)
+		return
+	}
+	if len(diagram.Nodes) != 3 || len(diagram.Links) != 1 {
+		t.Error("expected 3 nodes and 1 link, but got", diagram.Nodes, diagram.Links)
+		return
+	}
+	problemID, solutionID := diagram.Nodes[0].ID, diagram.Nodes[1].ID
+	// Labels are truncated like in the canvas, and empty descriptions show the item
+	if label := diagram.Nodes[0].Label; label != "Cars must board \"quickly\" & safely, even in..." {
+		t.Error("unexpected label, got", label)
+	}
+	if label := diagram.Nodes[1].Label; label != fmt.Sprintf("(solution %v)", solution) {
+		t.Error("unexpected label of empty solution, got", label)
+	}
+	tests := map[string][]string{
+		"dot": {
+			"digraph \"Ferry\" {",
+			problemID + " [label=\"Cars must board \\\"quickly\\\" & safely, even in...\", color=\"#9c27b0\"];",
+			"color=\"#2196f3\"", problemID + " -> " + solutionID + ";",
+		},
+		"mermaid": {
+			"flowchart TD", problemID + "[\"Cars must board #quot;quickly#quot; & safely, even in...\"]",
+			problemID + " --> " + solutionID, "classDef problem stroke:#9c27b0",
+			"class " + solutionID + " solution",
+		},
+		"plantuml": {
+			"@startuml", "title Ferry", "as " + problemID + " ##9c27b0", "<U+0022>quickly<U+0022>",
+			problemID + " --> " + solutionID, "@enduml",
+		},
+	}
+	for _, format := range DiagramFormats {
+		var builder strings.Builder
+		if err := diagram.Write(&builder, format); err != nil {
+			t.Errorf("failed to write %v: %v", format, err)
+			continue
+		}
+		for _, expected := range tests[format] {
+			if !strings.Contains(builder.String(), expected) {
+				t.Errorf("expected \"%v\" in %v, but got %v", expected, format, builder.String())
+			}
+		}
+	}
+	// Subtrees only have the root and its children
+	subtree, err := db.Diagram(NewRequirement(root))
+	if err != nil || len(subtree.Nodes) != 2 || len(subtree.Links) != 1 {
+		t.Error("expected only subtree, but got", subtree, err)
+	}
+	if subtree, err := db.Diagram(NewRequirement(other + 1)); err == nil {
+		t.Error("expected unknown root to fail, but got", subtree)
+	}
+	if format := DiagramFormat("docs/ferry.PUML"); format != "plantuml" {
+		t.Error("expected format from extension, but got", format)
+	}
+	if err := diagram.Export(fmt.Sprintf("%v/ferry.png", tempDir)); err == nil {
+		t.Error("expected unknown format to fail")
+	}
+}
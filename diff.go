@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ChangeKind is how an item changed between two versions of a project
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// changeOrder is the order changes are listed in
+var changeOrder = map[ChangeKind]int{
+	ChangeAdded:   0,
+	ChangeRemoved: 1,
+	ChangeChanged: 2,
+}
+
+// FieldChange is a single property of an item that changed
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// ItemChange is an item that was added, removed or changed, matched by uid
+type ItemChange struct {
+	UID  int64
+	Kind ChangeKind
+	// Old is empty if added, and New is empty if removed
+	Old, New ItemSnapshot
+	Fields   []FieldChange
+}
+
+// Snapshot gets the newest version of the item
+func (change ItemChange) Snapshot() ItemSnapshot {
+	if change.Kind == ChangeRemoved {
+		return change.Old
+	}
+	return change.New
+}
+
+// Has checks if a field changed
+func (change ItemChange) Has(field string) bool {
+	for _, fieldChange := range change.Fields {
+		if fieldChange.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (change ItemChange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		UID         string
+		Type        string
+		Kind        ChangeKind
+		Description string
+		Fields      []FieldChange `json:",omitempty"`
+	}{
+		FormatUID(change.UID), snapshotName(change.Snapshot()), change.Kind,
+		PlainText(change.Snapshot().Description), change.Fields,
+	})
+}
+
+// ItemsAt gets all items in a baseline, or the current items if baseline is empty
+func (data *DataContext) ItemsAt(baseline string) ([]ItemSnapshot, error) {
+	if baseline == "" {
+		return data.CurrentItems()
+	}
+	found, err := data.BaselineByName(baseline)
+	if err != nil {
+		return nil, err
+	}
+	return data.BaselineItems(found)
+}
+
+// LoadSnapshots gets all items in a project of any format, or in one of its baselines,
+// without changing the file or the current project
+func LoadSnapshots(path, baseline string) ([]ItemSnapshot, error) {
+	db, err := OpenProjectCopy(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return db.ItemsAt(baseline)
+}
+
+// formatParent gets the uid of the parent of an item, or an empty string if it's a root
+func formatParent(snapshot ItemSnapshot) string {
+	if !snapshot.HasParent {
+		return ""
+	}
+	return FormatUID(snapshot.ParentUID)
+}
+
+// DiffItem compares two versions of the same item, returning all fields that changed.
+// Text is compared without formatting, as the editor may change the html without changing the text.
+func DiffItem(before, after ItemSnapshot) []FieldChange {
+	fields := make([]FieldChange, 0)
+	compare := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			fields = append(fields, FieldChange{field, oldValue, newValue})
+		}
+	}
+	compare("Type", snapshotName(before), snapshotName(after))
+	compare("Description", PlainText(before.Description), PlainText(after.Description))
+	compare("Rationale", PlainText(before.Rationale), PlainText(after.Rationale))
+	compare("FitCriterion", PlainText(before.FitCriterion), PlainText(after.FitCriterion))
+	compare("Parent", formatParent(before), formatParent(after))
+	compare("Position", fmt.Sprintf("%v, %v", before.X, before.Y), fmt.Sprintf("%v, %v", after.X, after.Y))
+	compare("Size", fmt.Sprintf("%v x %v", before.Width, before.Height),
+		fmt.Sprintf("%v x %v", after.Width, after.Height))
+	return fields
+}
+
+// Diff compares two versions of a project, with items matched by uid.
+// Changes are sorted by kind, and then by uid.
+func Diff(before, after []ItemSnapshot) []ItemChange {
+	oldItems := make(map[int64]ItemSnapshot)
+	for _, item := range before {
+		oldItems[item.UID] = item
+	}
+	newItems := make(map[int64]ItemSnapshot)
+	for _, item := range after {
+		newItems[item.UID] = item
+	}
+	changes := make([]ItemChange, 0)
+	for _, item := range after {
+		oldItem, found := oldItems[item.UID]
+		if !found {
+			changes = append(changes, ItemChange{UID: item.UID, Kind: ChangeAdded, New: item})
+		} else if fields := DiffItem(oldItem, item); len(fields) > 0 {
+			changes = append(changes, ItemChange{item.UID, ChangeChanged, oldItem, item, fields})
+		}
+	}
+	for _, item := range before {
+		if _, found := newItems[item.UID]; !found {
+			changes = append(changes, ItemChange{UID: item.UID, Kind: ChangeRemoved, Old: item})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changeOrder[changes[i].Kind] < changeOrder[changes[j].Kind]
+		}
+		return changes[i].UID < changes[j].UID
+	})
+	return changes
+}
+
+// DiffSummary counts the changes of each kind, like "1 added, 0 removed, 2 changed"
+func DiffSummary(changes []ItemChange) string {
+	counts := make(map[ChangeKind]int)
+	for _, change := range changes {
+		counts[change.Kind]++
+	}
+	return fmt.Sprintf("%v added, %v removed, %v changed",
+		counts[ChangeAdded], counts[ChangeRemoved], counts[ChangeChanged])
+}
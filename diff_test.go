@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	root := ItemSnapshot{Type: TypeRequirement}
+	root.UID = 1
+	root.Description = "<p>root</p>"
+	child := ItemSnapshot{Type: TypeSolution, HasParent: true, ParentUID: 1}
+	child.UID = 2
+	child.Description = "child"
+	removed := ItemSnapshot{Type: TypeSolution}
+	removed.UID = 3
+	before := []ItemSnapshot{root, child, removed}
+	// Same items should not have any changes, even if the html is different
+	sameRoot := root
+	sameRoot.Description = "root"
+	if changes := Diff(before, []ItemSnapshot{sameRoot, child, removed}); len(changes) != 0 {
+		t.Error("unexpected changes, expected 0, but got", len(changes))
+	}
+	// Move child to a new root, and resize the old one
+	newRoot := ItemSnapshot{Type: TypeRequirement}
+	newRoot.UID = 4
+	movedChild := child
+	movedChild.ParentUID = 4
+	movedChild.Description = "moved child"
+	resizedRoot := root
+	resizedRoot.Width = 256
+	changes := Diff(before, []ItemSnapshot{resizedRoot, movedChild, newRoot})
+	if len(changes) != 4 {
+		t.Error("unexpected changes, expected 4, but got", len(changes))
+		return
+	}
+	// Changes are sorted by kind and uid
+	expected := []struct {
+		uid    int64
+		kind   ChangeKind
+		fields []string
+	}{
+		{4, ChangeAdded, nil},
+		{3, ChangeRemoved, nil},
+		{1, ChangeChanged, []string{"Size"}},
+		{2, ChangeChanged, []string{"Description", "Parent"}},
+	}
+	for i, change := range changes {
+		if change.UID != expected[i].uid || change.Kind != expected[i].kind ||
+			len(change.Fields) != len(expected[i].fields) {
+			t.Errorf("unexpected change %v, expected %v %v, but got %v %v (%v)",
+				i, expected[i].uid, expected[i].kind, change.UID, change.Kind, change.Fields)
+			continue
+		}
+		for _, field := range expected[i].fields {
+			if !change.Has(field) {
+				t.Errorf("expected %v to be changed in %v", field, change.UID)
+			}
+		}
+	}
+	if summary := DiffSummary(changes); summary != "1 added, 1 removed, 2 changed" {
+		t.Error("unexpected summary:", summary)
+	}
+}
+
+func TestLoadSnapshots(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	_, _ = project.Data().AddRequirement("Ferry must be safe", "", "", 0x10)
+	// Projects can be compared with any format
+	jsonPath := fmt.Sprintf("%v/openrq_test.json", tempDir)
+	_ = ioutil.WriteFile(jsonPath, []byte(`{"ProjectName": "Ferry", "Tree": [
+		{"ID": "10", "Description": "Ferry must be safe", "Pos": [0, 0]},
+		{"ID": "20", "Description": "Bow ramp", "Pos": [0, 100]}
+	]}`), 0644)
+	if items, err := LoadSnapshots(jsonPath, ""); err != nil || len(items) != 2 {
+		t.Error("expected items from json, but got", items, err)
+	}
+	if currentProject != project {
+		t.Error("expected current project to be kept open")
+	}
+	// Projects from older versions are read without upgrading them
+	oldMigrations := migrations
+	defer func() {
+		migrations = oldMigrations
+	}()
+	migrations = append(append([]Migration{}, migrations...), Migration{
+		Info: "add test column",
+		Run: func(tx *sql.Tx) error {
+			_, err := tx.Exec("alter table Info add column test text")
+			return err
+		},
+	})
+	before, _ := ioutil.ReadFile(project.path)
+	if items, err := LoadSnapshots(project.path, ""); err != nil || len(items) != 1 {
+		t.Error("expected items from older project, but got", items, err)
+	}
+	if after, _ := ioutil.ReadFile(project.path); !bytes.Equal(before, after) {
+		t.Error("expected older project to be unchanged")
+	}
+	if _, err := LoadSnapshots(fmt.Sprintf("%v/missing.orq", tempDir), ""); err == nil {
+		t.Error("expected missing project to fail")
+	}
+	if _, err := os.Stat(fmt.Sprintf("%v/missing.orq", tempDir)); !os.IsNotExist(err) {
+		t.Error("expected no project to be created")
+	}
+}
@@ -0,0 +1,120 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// Boxes shown on top of the scene when comparing the project to another version
+var diffOverlay []*widgets.QGraphicsRectItem
+
+// anyProjectFilter is the file dialog filter for all formats projects can be compared or merged with
+var anyProjectFilter = "OpenRQ Project(*.orq *.orqz);;JavaScript Object Notation(*.json);;" +
+	"Requirements Interchange Format(*.reqif)"
+
+// DiffColor gets the color for each kind of change
+func DiffColor(kind ChangeKind) *gui.QColor {
+	switch kind {
+	case ChangeAdded:
+		return gui.NewQColor3(76, 175, 80, 255)
+	case ChangeRemoved:
+		return gui.NewQColor3(244, 67, 54, 255)
+	}
+	return gui.NewQColor3(255, 152, 0, 255)
+}
+
+// ClearDiff removes all boxes from the last comparison
+func ClearDiff() {
+	for _, box := range diffOverlay {
+		scene.RemoveItem(box)
+	}
+	diffOverlay = nil
+}
+
+// ShowDiff colors all boxes that changed, with removed items shown where they used to be
+func ShowDiff(changes []ItemChange) {
+	ClearDiff()
+	for _, change := range changes {
+		snapshot := change.Snapshot()
+		// Show details when hovering
+		lines := []string{
+			fmt.Sprintf("%v %v (%v)", snapshotName(snapshot), FormatUID(change.UID), change.Kind),
+		}
+		for _, field := range change.Fields {
+			lines = append(lines, fmt.Sprintf("%v: \"%v\" -> \"%v\"", field.Field,
+				Truncate(field.Old, 40), Truncate(field.New, 40)))
+		}
+		box := widgets.NewQGraphicsRectItem3(float64(snapshot.X)-4, float64(snapshot.Y)-4,
+			float64(snapshot.Width)+8, float64(snapshot.Height)+8, nil)
+		pen := gui.NewQPen3(DiffColor(change.Kind))
+		pen.SetWidth(3)
+		if change.Kind == ChangeRemoved {
+			// Removed items aren't in the scene, so show what they were
+			pen.SetStyle(core.Qt__DashLine)
+			text := widgets.NewQGraphicsTextItem2(Truncate(PlainText(snapshot.Description), 46), box)
+			text.SetPos2(float64(snapshot.X), float64(snapshot.Y))
+			text.SetTextWidth(float64(snapshot.Width))
+			text.SetDefaultTextColor(DiffColor(ChangeRemoved))
+		}
+		box.SetPen(pen)
+		box.SetToolTip(strings.Join(lines, "\n"))
+		// Below items, so they can still be moved and edited
+		box.SetZValue(5)
+		scene.AddItem(box)
+		diffOverlay = append(diffOverlay, box)
+	}
+}
+
+// CompareWith compares the current project to an older version of it and shows the changes
+func CompareWith(window *widgets.QMainWindow, before []ItemSnapshot, err error) {
+	if err == nil {
+		var after []ItemSnapshot
+		if after, err = currentProject.Data().CurrentItems(); err == nil {
+			changes := Diff(before, after)
+			ShowDiff(changes)
+			widgets.QMessageBox_Information(window, "Compare", DiffSummary(changes),
+				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+	}
+	widgets.QMessageBox_Critical(window, "Failed to Compare", err.Error(),
+		widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+}
+
+// CreateDiffMenu creates the menu for comparing the current project with a baseline or another project
+func CreateDiffMenu(window *widgets.QMainWindow) *widgets.QMenu {
+	menu := widgets.NewQMenu2("Compare", nil)
+	menu.AddAction("Compare with Baseline...").ConnectTriggered(func(checked bool) {
+		if currentProject == nil || currentProject.ReadOnly() {
+			return
+		}
+		if baseline, ok := ChooseBaseline(window, "Compare with Baseline"); ok {
+			before, err := currentProject.Data().BaselineItems(baseline)
+			CompareWith(window, before, err)
+		}
+	})
+	menu.AddAction("Compare with Project...").ConnectTriggered(func(checked bool) {
+		if currentProject == nil {
+			return
+		}
+		fileName := widgets.QFileDialog_GetOpenFileName(window, "Compare with Project",
+			core.QStandardPaths_Locate(core.QStandardPaths__DocumentsLocation, "", 1),
+			anyProjectFilter, "", 0)
+		if len(fileName) > 0 {
+			before, err := LoadSnapshots(fileName, "")
+			CompareWith(window, before, err)
+		}
+	})
+	menu.AddSeparator()
+	menu.AddAction("Clear Comparison").ConnectTriggered(func(checked bool) {
+		ClearDiff()
+	})
+	return menu
+}
@@ -1,3 +1,6 @@
+//go:build !headless
+// +build !headless
+
 package main
 
 import (
@@ -9,6 +12,7 @@ import (
 
 // TextFormat enum (bold, italic, underline, strikethrough)
 type TextFormat int8
+
 const (
 	FormatBold          TextFormat = 0
 	FormatItalic        TextFormat = 1
@@ -18,6 +22,7 @@ const (
 
 // EntryType enum (description, rationale, fit criterion)
 type EntryType int8
+
 const (
 	Description  EntryType = 0
 	Rationale    EntryType = 1
@@ -173,9 +178,12 @@ func CreateEditWidget(item Item, group *widgets.QGraphicsItemGroup, scene *widge
 		"Rationale",
 		"Fit Criterion",
 	}
+	qualityRefreshes := make([]func(), len(titles))
 	for i := 0; i < len(titles); i++ {
 		textEdits[i] = widgets.NewQTextEdit(nil)
 		textEdits[i].SetHtml(textValues[i])
+		// Underline vague or unmeasurable phrases, in problems only
+		qualityRefreshes[i] = HighlightQuality(textEdits[i], QualityFields()[i], reqRadio.IsChecked)
 		// Local copy of i
 		i2 := i
 		// Show/hide font options on selection
@@ -195,6 +203,23 @@ func CreateEditWidget(item Item, group *widgets.QGraphicsItemGroup, scene *widge
 		textGroups[i] = CreateGroupBox(titles[i], textOptions[i], textEdits[i])
 		layout.AddWidget(textGroups[i], 1, 0)
 	}
+	// Show or clear quality issues when changing item type
+	for _, radio := range []*widgets.QRadioButton{reqRadio, solRadio} {
+		radio.ConnectReleased(func() {
+			for _, refresh := range qualityRefreshes {
+				refresh()
+			}
+		})
+	}
+
+	// Labels of the item, checked if added
+	uid := item.UID()
+	labelList := CreateLabelList(itemLabels[uid])
+	labelList.SetMaximumHeight(96)
+	layout.AddWidget(CreateGroupBox("Labels", labelList), 0, 0)
+	// Attached files, saved with the rest of the changes
+	attachments, attachmentCommands := CreateAttachmentList(nil, uid)
+	layout.AddWidget(CreateGroupBox("Attachments", attachments), 0, 0)
 
 	// Hide stuff
 	if itemType == TypeSolution {
@@ -208,86 +233,75 @@ func CreateEditWidget(item Item, group *widgets.QGraphicsItemGroup, scene *widge
 	buttons := widgets.NewQHBoxLayout()
 	// Save button
 	save := widgets.NewQPushButton2("Save", nil)
-	// Temporary values
-	var itemX, itemY, itemW, itemH int
 	save.ConnectReleased(func() {
-		// Check if we are changing item type
-		changingType := itemTypeWarn.IsVisible()
-		if changingType {
-			// We are changing type, we need to delete and add item again
-			db := currentProject.Data()
-			defer db.Close()
-			// Save old properties
+		db := currentProject.Data()
+		// Get the item as it is now, to be able to undo the changes
+		before, err := db.Snapshot(item)
+		if err != nil {
+			fmt.Println("error: failed to save item:", err)
+			return
+		}
+		// Changing type deletes the item and adds it again with the same uid
+		after := before
+		after.Type = TypeSolution
+		if reqRadio.IsChecked() {
+			after.Type = TypeRequirement
+		}
+		after.Description = textEdits[Description].ToHtml()
+		// Requirements also need rationale and fit criterion updated
+		if after.Type == TypeRequirement {
+			after.Rationale = textEdits[Rationale].ToHtml()
+			after.FitCriterion = textEdits[FitCriterion].ToHtml()
+		}
+		// Changing labels and attachments is undone together with the rest of the changes
+		var command Command = NewEditItemCommand(before, after)
+		commands := []Command{command}
+		labelIDs := CheckedLabels(labelList)
+		if currentLabels := LabelIDs(itemLabels[uid]); !SameLabels(currentLabels, labelIDs) {
+			commands = append(commands, NewLabelItemsCommand(uid, after.Type, currentLabels, labelIDs))
+		}
+		commands = append(commands, attachmentCommands(after.Type)...)
+		if len(commands) > 1 {
+			command = NewCommandGroup(command.Text(), commands...)
+		}
+		if err := currentHistory.Do(db, command); err != nil {
+			fmt.Println("error: failed to save item:", err)
+			return
+		}
+		if after.Type != before.Type {
 			oldItem := item
-			itemUID := item.UID()
-			itemX, itemY = item.Pos()
-			itemW, itemH = item.Size()
-			itemParent := item.Parent()
-			// Get links and delete the old ones
-			itemLinks := links[item]
-			delete(links, item)
-			// Delete old item
-			if err := db.RemoveItem(item); err != nil {
-				fmt.Println("error: failed to delete old item:", err)
+			if item, err = db.ItemByUID(before.UID); err != nil {
+				fmt.Println("error: failed to find new item:", err)
 				return
 			}
-			// Add new as set item type
-			if reqRadio.IsChecked() {
-				// Add new requirement with set values
-				newID, err := db.AddEmptyRequirement()
-				if err != nil {
-					fmt.Println("error: failed to create new requirement:", err)
-					return
-				}
-				// Create new and set UID, rest is same as updating item
-				item = NewRequirement(newID)
-			} else {
-				// Add new requirement with set values
-				newID, err := db.AddEmptySolution()
-				if err != nil {
-					fmt.Println("error: failed to create new solution:", err)
-					return
-				}
-				// Create new and set UID, rest is same as updating item
-				item = NewSolution(newID)
-			}
-			// Set UID and update and update requirement cast
-			// (rest is the same as updating an item)
-			item.SetUID(itemUID)
-			// Also set position and size same as old
-			item.SetPos(itemX, itemY)
-			item.SetSize(itemW, itemH)
-			// Update any links
-			if err := db.UpdateItemChildren(oldItem, item); err != nil {
-				fmt.Println("warning: failed to update item children:", err)
-			}
-			// Update parent
-			if itemParent != nil {
-				item.SetParent(itemParent)
-			}
+			// Get links and move them to the new item
+			itemLinks := links[oldItem]
+			delete(links, oldItem)
 			// Update links
 			for _, link := range itemLinks {
 				if link.parent == oldItem {
 					link.parent = item
 				} else if link.child == oldItem {
 					link.child = item
+					link.SetChildItem(item)
 				}
 			}
 			links[item] = itemLinks
-			// Update requirement values
-			req, isReq = item.(Requirement)
-		}
-
-		// Properties both items need
-		item.SetDescription(textEdits[Description].ToHtml())
-		// Requirements also need rationale and fit criterion updated
-		if isReq {
-			req.SetRationale(textEdits[Rationale].ToHtml())
-			req.SetFitCriterion(textEdits[FitCriterion].ToHtml())
+			currentGraph.ReplaceItem(oldItem, item)
 		}
+		currentGraph.SetDescription(item, textEdits[Description].ToHtml())
 		// Recreate group with new item
-		scene.AddItem(NewGraphicsItem(textEdits[Description].ToHtml(),
-			int(group.X()), int(group.Y()), 128, 64, item))
+		newGroup := NewGraphicsItem(textEdits[Description].ToHtml(),
+			int(group.X()), int(group.Y()), 128, 64, item)
+		if itemLabels[uid], err = db.ItemLabels(uid); err != nil {
+			fmt.Println("error: failed to get labels:", err)
+		}
+		ShowLabels(newGroup, uid, itemLabels[uid])
+		if itemMedia[uid], err = db.ItemMedia(uid); err != nil {
+			fmt.Println("error: failed to get attachments:", err)
+		}
+		ShowMedia(newGroup, itemMedia[uid])
+		scene.AddItem(newGroup)
 		scene.RemoveItem(group)
 		// Close window
 		dock.Close()
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Custom validation rules are expressions that should be true for every item, like:
+//
+//	type == "Problem" implies children(type == "Solution") >= 1
+//	label("safety") implies rationale != ""
+//	depth <= 6
+//
+// Expressions are type checked when parsed, so evaluating them can't fail.
+
+// exprKind is the type of value an expression results in
+type exprKind int
+
+const (
+	kindBool exprKind = iota
+	kindNumber
+	kindString
+)
+
+func (kind exprKind) String() string {
+	switch kind {
+	case kindBool:
+		return "boolean"
+	case kindNumber:
+		return "number"
+	}
+	return "text"
+}
+
+// exprEnv is the item an expression is evaluated for
+type exprEnv struct {
+	context *ValidationContext
+	item    Item
+}
+
+// exprNode is a parsed part of an expression
+type exprNode interface {
+	kind() exprKind
+	eval(env exprEnv) interface{}
+}
+
+// exprProperties are all properties of items that can be used in expressions
+var exprProperties = map[string]struct {
+	kind  exprKind
+	value func(env exprEnv) interface{}
+}{
+	"type": {kindString, func(env exprEnv) interface{} {
+		return GetItemName(env.item)
+	}},
+	"uid": {kindString, func(env exprEnv) interface{} {
+		return FormatUID(env.context.Items[env.item].UID)
+	}},
+	"description": {kindString, func(env exprEnv) interface{} {
+		return strings.TrimSpace(PlainText(env.context.Graph.Description(env.item)))
+	}},
+	"rationale": {kindString, func(env exprEnv) interface{} {
+		return strings.TrimSpace(PlainText(env.context.Items[env.item].Rationale))
+	}},
+	"fitCriterion": {kindString, func(env exprEnv) interface{} {
+		return strings.TrimSpace(PlainText(env.context.Items[env.item].FitCriterion))
+	}},
+	"link": {kindString, func(env exprEnv) interface{} {
+		return strings.TrimSpace(env.context.Items[env.item].Link)
+	}},
+	"children": {kindNumber, func(env exprEnv) interface{} {
+		return float64(len(env.context.Graph.Children(env.item)))
+	}},
+	"parents": {kindNumber, func(env exprEnv) interface{} {
+		return float64(len(env.context.Graph.Parents(env.item)))
+	}},
+	"depth": {kindNumber, func(env exprEnv) interface{} {
+		return float64(env.context.Depth(env.item))
+	}},
+}
+
+// exprFunctions are all functions that can be used in expressions
+var exprFunctions = map[string]struct {
+	args []exprKind
+	kind exprKind
+	call func(env exprEnv, args []exprNode) interface{}
+}{
+	// children(condition) counts children where condition is true
+	"children": {[]exprKind{kindBool}, kindNumber, func(env exprEnv, args []exprNode) interface{} {
+		count := 0
+		for _, child := range env.context.Graph.Children(env.item) {
+			if args[0].eval(exprEnv{env.context, child}).(bool) {
+				count++
+			}
+		}
+		return float64(count)
+	}},
+	// label(name) checks if the item has a label, ignoring case
+	"label": {[]exprKind{kindString}, kindBool, func(env exprEnv, args []exprNode) interface{} {
+		name := args[0].eval(env).(string)
+		for _, label := range env.context.Labels[env.context.Items[env.item].UID] {
+			if strings.EqualFold(label.Name, name) {
+				return true
+			}
+		}
+		return false
+	}},
+	// contains(text, part) checks if text contains part, ignoring case
+	"contains": {[]exprKind{kindString, kindString}, kindBool, func(env exprEnv, args []exprNode) interface{} {
+		return strings.Contains(strings.ToLower(args[0].eval(env).(string)),
+			strings.ToLower(args[1].eval(env).(string)))
+	}},
+	// len(text) gets the number of characters in text
+	"len": {[]exprKind{kindString}, kindNumber, func(env exprEnv, args []exprNode) interface{} {
+		return float64(len([]rune(args[0].eval(env).(string))))
+	}},
+}
+
+type exprLiteral struct {
+	value interface{}
+	k     exprKind
+}
+
+func (node exprLiteral) kind() exprKind {
+	return node.k
+}
+
+func (node exprLiteral) eval(env exprEnv) interface{} {
+	return node.value
+}
+
+type exprProperty struct {
+	name string
+}
+
+func (node exprProperty) kind() exprKind {
+	return exprProperties[node.name].kind
+}
+
+func (node exprProperty) eval(env exprEnv) interface{} {
+	return exprProperties[node.name].value(env)
+}
+
+type exprCall struct {
+	name string
+	args []exprNode
+}
+
+func (node exprCall) kind() exprKind {
+	return exprFunctions[node.name].kind
+}
+
+func (node exprCall) eval(env exprEnv) interface{} {
+	return exprFunctions[node.name].call(env, node.args)
+}
+
+type exprNot struct {
+	node exprNode
+}
+
+func (node exprNot) kind() exprKind {
+	return kindBool
+}
+
+func (node exprNot) eval(env exprEnv) interface{} {
+	return !node.node.eval(env).(bool)
+}
+
+type exprBinary struct {
+	op          string
+	left, right exprNode
+}
+
+func (node exprBinary) kind() exprKind {
+	return kindBool
+}
+
+func (node exprBinary) eval(env exprEnv) interface{} {
+	// Only evaluate the right side when needed
+	switch node.op {
+	case "and":
+		return node.left.eval(env).(bool) && node.right.eval(env).(bool)
+	case "or":
+		return node.left.eval(env).(bool) || node.right.eval(env).(bool)
+	case "implies":
+		return !node.left.eval(env).(bool) || node.right.eval(env).(bool)
+	}
+	left, right := node.left.eval(env), node.right.eval(env)
+	switch node.op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	}
+	// Numbers and text can both be ordered
+	var compare int
+	if node.left.kind() == kindNumber {
+		l, r := left.(float64), right.(float64)
+		if l < r {
+			compare = -1
+		} else if l > r {
+			compare = 1
+		}
+	} else {
+		compare = strings.Compare(left.(string), right.(string))
+	}
+	switch node.op {
+	case "<":
+		return compare < 0
+	case "<=":
+		return compare <= 0
+	case ">":
+		return compare > 0
+	}
+	return compare >= 0
+}
+
+// exprToken is a word, number, text or operator in an expression
+type exprToken struct {
+	text string
+	// Text is quoted, the quotes are not part of text
+	quoted bool
+	pos    int
+}
+
+// exprOperators are all operators, with aliases for the same operator
+var exprOperators = map[string]string{
+	"==": "==", "=": "==", "!=": "!=", "≠": "!=",
+	"<": "<", "<=": "<=", "≤": "<=", ">": ">", ">=": ">=", "≥": ">=",
+	"&&": "and", "||": "or", "!": "not", "=>": "implies",
+	"(": "(", ")": ")", ",": ",",
+}
+
+// lexExpr splits an expression into tokens
+func lexExpr(source string) ([]exprToken, error) {
+	tokens := make([]exprToken, 0)
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			// Text, where \" is a quote
+			var text strings.Builder
+			start := i
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("text at %v is never closed", start+1)
+			}
+			tokens = append(tokens, exprToken{text.String(), true, start})
+			i++
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) ||
+				runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{string(runes[start:i]), false, start})
+		default:
+			// Longest operator first
+			if i+1 < len(runes) {
+				if op, found := exprOperators[string(runes[i:i+2])]; found {
+					tokens = append(tokens, exprToken{op, false, i})
+					i += 2
+					continue
+				}
+			}
+			op, found := exprOperators[string(r)]
+			if !found {
+				return nil, fmt.Errorf("unexpected \"%v\" at %v", string(r), i+1)
+			}
+			tokens = append(tokens, exprToken{op, false, i})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// exprParser parses tokens into nodes, one level of precedence for each method
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// peek gets the next token, if it's not quoted text
+func (parser *exprParser) peek() string {
+	if parser.pos >= len(parser.tokens) || parser.tokens[parser.pos].quoted {
+		return ""
+	}
+	return parser.tokens[parser.pos].text
+}
+
+// errorf creates an error at the current position
+func (parser *exprParser) errorf(format string, args ...interface{}) error {
+	if parser.pos >= len(parser.tokens) {
+		return fmt.Errorf(format+" at end", args...)
+	}
+	return fmt.Errorf(format+" at %v", append(args, parser.tokens[parser.pos].pos+1)...)
+}
+
+// expect skips the next token, if it's the expected one
+func (parser *exprParser) expect(text string) error {
+	if parser.peek() != text {
+		return parser.errorf("expected \"%v\"", text)
+	}
+	parser.pos++
+	return nil
+}
+
+// logical parses operators taking two booleans, where implies binds the weakest
+func (parser *exprParser) logical(op string, next func() (exprNode, error)) (exprNode, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for parser.peek() == op {
+		parser.pos++
+		var right exprNode
+		if op == "implies" {
+			// a implies b implies c is a implies (b implies c)
+			right, err = parser.logical(op, next)
+		} else {
+			right, err = next()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if left.kind() != kindBool || right.kind() != kindBool {
+			return nil, fmt.Errorf("\"%v\" needs booleans on both sides", op)
+		}
+		left = exprBinary{op, left, right}
+	}
+	return left, nil
+}
+
+func (parser *exprParser) implies() (exprNode, error) {
+	return parser.logical("implies", parser.or)
+}
+
+func (parser *exprParser) or() (exprNode, error) {
+	return parser.logical("or", parser.and)
+}
+
+func (parser *exprParser) and() (exprNode, error) {
+	return parser.logical("and", parser.not)
+}
+
+func (parser *exprParser) not() (exprNode, error) {
+	if parser.peek() != "not" {
+		return parser.compare()
+	}
+	parser.pos++
+	node, err := parser.not()
+	if err != nil {
+		return nil, err
+	}
+	if node.kind() != kindBool {
+		return nil, fmt.Errorf("\"not\" needs a boolean")
+	}
+	return exprNot{node}, nil
+}
+
+func (parser *exprParser) compare() (exprNode, error) {
+	left, err := parser.primary()
+	if err != nil {
+		return nil, err
+	}
+	op := parser.peek()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	parser.pos++
+	right, err := parser.primary()
+	if err != nil {
+		return nil, err
+	}
+	if left.kind() != right.kind() {
+		return nil, fmt.Errorf("can't compare %v with %v", left.kind(), right.kind())
+	}
+	if left.kind() == kindBool && op != "==" && op != "!=" {
+		return nil, fmt.Errorf("booleans can't be compared with \"%v\"", op)
+	}
+	return exprBinary{op, left, right}, nil
+}
+
+func (parser *exprParser) primary() (exprNode, error) {
+	if parser.pos >= len(parser.tokens) {
+		return nil, parser.errorf("expected a value")
+	}
+	token := parser.tokens[parser.pos]
+	parser.pos++
+	if token.quoted {
+		return exprLiteral{token.text, kindString}, nil
+	}
+	switch token.text {
+	case "(":
+		node, err := parser.implies()
+		if err != nil {
+			return nil, err
+		}
+		return node, parser.expect(")")
+	case "true", "false":
+		return exprLiteral{token.text == "true", kindBool}, nil
+	}
+	if number, err := strconv.ParseFloat(token.text, 64); err == nil {
+		return exprLiteral{number, kindNumber}, nil
+	}
+	// Function call
+	if function, found := exprFunctions[token.text]; found && parser.peek() == "(" {
+		parser.pos++
+		args := make([]exprNode, 0, len(function.args))
+		for i, kind := range function.args {
+			if i > 0 {
+				if err := parser.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := parser.implies()
+			if err != nil {
+				return nil, err
+			}
+			if arg.kind() != kind {
+				return nil, fmt.Errorf("argument %v of %v should be %v, not %v", i+1, token.text, kind, arg.kind())
+			}
+			args = append(args, arg)
+		}
+		return exprCall{token.text, args}, parser.expect(")")
+	}
+	if _, found := exprProperties[token.text]; found {
+		return exprProperty{token.text}, nil
+	}
+	parser.pos--
+	return nil, parser.errorf("unknown name \"%v\"", token.text)
+}
+
+// Expression is a parsed expression that can be evaluated for each item
+type Expression struct {
+	root exprNode
+}
+
+// ParseExpression parses an expression, which must result in a boolean
+func ParseExpression(source string) (*Expression, error) {
+	tokens, err := lexExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	parser := &exprParser{tokens: tokens}
+	root, err := parser.implies()
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, parser.errorf("unexpected \"%v\"", parser.tokens[parser.pos].text)
+	}
+	if root.kind() != kindBool {
+		return nil, fmt.Errorf("expression results in %v, but should be true or false", root.kind())
+	}
+	return &Expression{root}, nil
+}
+
+// Check evaluates the expression for an item
+func (expr *Expression) Check(context *ValidationContext, item Item) bool {
+	return expr.root.eval(exprEnv{context, item}).(bool)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExpressions(t *testing.T) {
+	invalid := []string{
+		"",
+		"depth",
+		"depth <= \"six\"",
+		"children(depth) > 1",
+		"unknown == 1",
+		"type == \"Problem\" implies",
+		"(depth < 6",
+		"description == \"never closed",
+		"depth < 6 $",
+	}
+	for _, source := range invalid {
+		if _, err := ParseExpression(source); err == nil {
+			t.Errorf("expected \"%v\" to be invalid", source)
+		}
+	}
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	db := project.Data()
+	// Problem with a solution, a problem without, and a chain of problems 8 deep
+	solved, _ := db.AddRequirement("<p>Solved</p>", "<p>Why</p>", "<p>99%</p>", db.ItemUID())
+	solution, _ := db.AddSolution("Solution", db.ItemUID())
+	_ = db.AddItemChild(NewRequirement(solved), NewSolution(solution))
+	unsolved, _ := db.AddRequirement("Unsolved", "", "", db.ItemUID())
+	_ = db.AddItemChild(NewRequirement(solved), NewRequirement(unsolved))
+	safety, _ := db.AddLabel("Safety", 0)
+	_ = db.SetItemLabels(NewRequirement(unsolved).UID(), TypeRequirement, []int64{safety.ID})
+	parent := NewRequirement(unsolved)
+	for i := 0; i < 6; i++ {
+		id, _ := db.AddRequirement(fmt.Sprintf("Deep %v", i), "Why", "1 s", db.ItemUID())
+		_ = db.AddItemChild(parent, NewRequirement(id))
+		parent = NewRequirement(id)
+	}
+	graph, err := LoadGraph(db)
+	if err != nil {
+		t.Error("failed to load graph:", err)
+		return
+	}
+	context, err := NewValidationContext(db, graph)
+	if err != nil {
+		t.Error("failed to load items:", err)
+		return
+	}
+	// Items failing each rule
+	tests := map[string][]Item{
+		"type == \"Problem\" implies children(type == \"Solution\") >= 1": {
+			NewRequirement(unsolved), NewRequirement(unsolved + 1), NewRequirement(unsolved + 2),
+			NewRequirement(unsolved + 3), NewRequirement(unsolved + 4), NewRequirement(unsolved + 5),
+			NewRequirement(unsolved + 6),
+		},
+		"type == \"Problem\" implies fitCriterion != \"\"": {
+			NewRequirement(unsolved),
+		},
+		"depth ≤ 6": {
+			NewRequirement(unsolved + 5), NewRequirement(unsolved + 6),
+		},
+		"label(\"safety\") implies rationale != \"\"": {
+			NewRequirement(unsolved),
+		},
+		"not (contains(description, \"deep\") or len(description) == 8) || uid == \"\"": {
+			NewRequirement(unsolved), NewRequirement(unsolved + 1), NewRequirement(unsolved + 2),
+			NewRequirement(unsolved + 3), NewRequirement(unsolved + 4), NewRequirement(unsolved + 5),
+			NewRequirement(unsolved + 6), NewSolution(solution),
+		},
+	}
+	for source, expected := range tests {
+		rule, err := NewCustomRule("Test", source, SeverityError)
+		if err != nil {
+			t.Errorf("failed to parse \"%v\": %v", source, err)
+			continue
+		}
+		failed := make([]Item, 0)
+		for _, finding := range Validate(context, []ValidationRule{rule}) {
+			failed = append(failed, finding.Items...)
+		}
+		if fmt.Sprint(failed) != fmt.Sprint(expected) {
+			t.Errorf("expected \"%v\" to fail for %v, but got %v", source, expected, failed)
+		}
+	}
+	// Custom rules are saved in the project, and can be renamed and removed
+	rule, _ := NewCustomRule("Fit criterion", "fitCriterion != \"\"", SeverityWarning)
+	if err := db.SaveCustomRule("", rule); err != nil {
+		t.Error("failed to add rule:", err)
+	}
+	if err := db.SaveCustomRule("", rule); err == nil {
+		t.Error("expected rule with same name to fail")
+	}
+	if rule, _ := NewCustomRule("link-loop", "true", SeverityError); db.SaveCustomRule("", rule) == nil {
+		t.Error("expected rule with built-in name to fail")
+	}
+	renamed, _ := NewCustomRule("Has fit criterion", rule.Expression, SeverityInfo)
+	if err := db.SaveCustomRule(rule.Tag, renamed); err != nil {
+		t.Error("failed to rename rule:", err)
+	}
+	rules, err := db.ValidationRules()
+	if err != nil || len(rules) != len(BuiltinRules())+1 {
+		t.Error("expected custom rule to be saved, but got", rules, err)
+		return
+	}
+	if custom := rules[len(rules)-1]; custom.Tag != "Has fit criterion" || custom.Severity != SeverityInfo ||
+		!custom.IsCustom() || len(Validate(context, []ValidationRule{custom})) != 2 {
+		t.Error("unexpected custom rule, got", custom)
+	}
+	if err := db.RemoveCustomRule("link-loop"); err == nil {
+		t.Error("expected removing built-in rule to fail")
+	}
+	if err := db.RemoveCustomRule(renamed.Tag); err != nil {
+		t.Error("failed to remove rule:", err)
+	}
+	if rules, _ := db.ValidationRules(); len(rules) != len(BuiltinRules()) {
+		t.Error("expected custom rule to be removed, but got", rules)
+	}
+	// Invalid rules are kept to be fixed, but fail as errors instead of passing
+	_ = db.AddRuleData(RuleData{Tag: "Broken", Enabled: true, Severity: "info", Data: "fitCriterion !="})
+	rules, _ = db.ValidationRules()
+	broken := rules[len(rules)-1]
+	if findings := Validate(context, []ValidationRule{broken}); broken.Severity != SeverityError ||
+		len(findings) != 1 || !strings.HasPrefix(broken.Info, "Invalid rule") {
+		t.Error("expected invalid rule to fail as error, but got", broken, findings)
+	}
+}
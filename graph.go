@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Graph is an in-memory model of all items in a project and the links between them.
+// Unlike the database, an item can have more than one parent in the graph,
+// which is what the validation engine looks for.
+type Graph struct {
+	// All items with their description
+	items map[Item]string
+	// Parents and children of each item
+	parents  map[Item][]Item
+	children map[Item][]Item
+}
+
+// NewGraph creates a new empty graph
+func NewGraph() *Graph {
+	graph := new(Graph)
+	graph.items = make(map[Item]string)
+	graph.parents = make(map[Item][]Item)
+	graph.children = make(map[Item][]Item)
+	return graph
+}
+
+// LoadGraph creates a graph from all items and links in the database
+func LoadGraph(db *DataContext) (*Graph, error) {
+	items, err := db.LoadItems()
+	if err != nil {
+		return NewGraph(), err
+	}
+	return NewGraphFromItems(items), nil
+}
+
+// NewGraphFromItems creates a graph from already loaded items
+func NewGraphFromItems(items []ItemData) *Graph {
+	graph := NewGraph()
+	for _, item := range items {
+		graph.AddItem(item.Item, item.Description)
+	}
+	for _, item := range items {
+		if item.Parent == nil {
+			continue
+		}
+		if !graph.HasItem(item.Parent) {
+			fmt.Fprintf(os.Stderr, "warning: could not find parent, ignoring link (%v -> %v)\n",
+				item.Parent.ToString(), item.Item.ToString())
+			continue
+		}
+		graph.AddLink(item.Parent, item.Item)
+	}
+	return graph
+}
+
+// itemLess checks if a should be sorted before b, by type and then by id
+func itemLess(a, b Item) bool {
+	if GetItemType(a) != GetItemType(b) {
+		return GetItemType(a) < GetItemType(b)
+	}
+	return a.ID() < b.ID()
+}
+
+// SortItems sorts items by type and then by id, to always get the same order
+func SortItems(items []Item) []Item {
+	sort.Slice(items, func(i, j int) bool {
+		return itemLess(items[i], items[j])
+	})
+	return items
+}
+
+// AddItem adds an item, or updates the description if it already exists
+func (graph *Graph) AddItem(item Item, description string) {
+	graph.items[item] = description
+}
+
+// RemoveItem removes an item and all links to and from it
+func (graph *Graph) RemoveItem(item Item) {
+	for _, parent := range graph.Parents(item) {
+		graph.RemoveLink(parent, item)
+	}
+	for _, child := range graph.Children(item) {
+		graph.RemoveLink(item, child)
+	}
+	delete(graph.items, item)
+}
+
+// ReplaceItem replaces an item with another one, keeping all links
+func (graph *Graph) ReplaceItem(oldItem, newItem Item) {
+	parents := graph.Parents(oldItem)
+	children := graph.Children(oldItem)
+	description := graph.items[oldItem]
+	graph.RemoveItem(oldItem)
+	graph.AddItem(newItem, description)
+	for _, parent := range parents {
+		graph.AddLink(parent, newItem)
+	}
+	for _, child := range children {
+		graph.AddLink(newItem, child)
+	}
+}
+
+// HasItem checks if the item is in the graph
+func (graph *Graph) HasItem(item Item) bool {
+	_, ok := graph.items[item]
+	return ok
+}
+
+// Items gets all items, sorted by type and id
+func (graph *Graph) Items() []Item {
+	items := make([]Item, 0, len(graph.items))
+	for item := range graph.items {
+		items = append(items, item)
+	}
+	return SortItems(items)
+}
+
+// Description gets the description of an item as html
+func (graph *Graph) Description(item Item) string {
+	return graph.items[item]
+}
+
+// SetDescription updates the description of an item
+func (graph *Graph) SetDescription(item Item, description string) {
+	if graph.HasItem(item) {
+		graph.items[item] = description
+	}
+}
+
+// HasLink checks if there is a link from parent to child
+func (graph *Graph) HasLink(parent, child Item) bool {
+	for _, c := range graph.children[parent] {
+		if c == child {
+			return true
+		}
+	}
+	return false
+}
+
+// AddLink adds a link from parent to child, if it doesn't already exist
+func (graph *Graph) AddLink(parent, child Item) {
+	if graph.HasLink(parent, child) {
+		return
+	}
+	graph.children[parent] = append(graph.children[parent], child)
+	graph.parents[child] = append(graph.parents[child], parent)
+}
+
+// RemoveLink removes the link from parent to child, if any
+func (graph *Graph) RemoveLink(parent, child Item) {
+	graph.children[parent] = removeGraphItem(graph.children[parent], child)
+	graph.parents[child] = removeGraphItem(graph.parents[child], parent)
+	if len(graph.children[parent]) == 0 {
+		delete(graph.children, parent)
+	}
+	if len(graph.parents[child]) == 0 {
+		delete(graph.parents, child)
+	}
+}
+
+func removeGraphItem(items []Item, item Item) []Item {
+	for i, current := range items {
+		if current == item {
+			return append(items[:i:i], items[i+1:]...)
+		}
+	}
+	return items
+}
+
+// Parents gets all parents of an item, usually only one
+func (graph *Graph) Parents(item Item) []Item {
+	return append([]Item{}, graph.parents[item]...)
+}
+
+// Parent gets the first parent of an item, or nil if it's a root
+func (graph *Graph) Parent(item Item) Item {
+	if parents := graph.parents[item]; len(parents) > 0 {
+		return parents[0]
+	}
+	return nil
+}
+
+// Children gets all children of an item, sorted by type and id
+func (graph *Graph) Children(item Item) []Item {
+	return SortItems(append([]Item{}, graph.children[item]...))
+}
+
+// Links calls fn for each link in the graph
+func (graph *Graph) Links(fn func(parent, child Item)) {
+	for _, parent := range graph.Items() {
+		for _, child := range graph.Children(parent) {
+			fn(parent, child)
+		}
+	}
+}
+
+// Roots gets all items without a parent
+func (graph *Graph) Roots() []Item {
+	roots := make([]Item, 0)
+	for _, item := range graph.Items() {
+		if len(graph.parents[item]) == 0 {
+			roots = append(roots, item)
+		}
+	}
+	return roots
+}
+
+// LoopComponents gets all groups of items that can reach each other through links,
+// strongly connected components, found with Tarjan's algorithm.
+// Only groups that form a loop are included, each sorted, and sorted by their first item.
+func (graph *Graph) LoopComponents() [][]Item {
+	index := make(map[Item]int)
+	lowLink := make(map[Item]int)
+	onStack := make(map[Item]bool)
+	stack := make([]Item, 0)
+	components := make([][]Item, 0)
+	var connect func(item Item)
+	connect = func(item Item) {
+		index[item] = len(index)
+		lowLink[item] = index[item]
+		stack = append(stack, item)
+		onStack[item] = true
+		for _, child := range graph.Children(item) {
+			if _, visited := index[child]; !visited {
+				connect(child)
+				if lowLink[child] < lowLink[item] {
+					lowLink[item] = lowLink[child]
+				}
+			} else if onStack[child] && index[child] < lowLink[item] {
+				lowLink[item] = index[child]
+			}
+		}
+		// Only the first item found in a component removes it from the stack
+		if lowLink[item] != index[item] {
+			return
+		}
+		component := make([]Item, 0)
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == item {
+				break
+			}
+		}
+		// A single item is only a loop if it links to itself
+		if len(component) > 1 || graph.HasLink(item, item) {
+			components = append(components, SortItems(component))
+		}
+	}
+	for _, item := range graph.Items() {
+		if _, visited := index[item]; !visited {
+			connect(item)
+		}
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return itemLess(components[i][0], components[j][0])
+	})
+	return components
+}
+
+// Cycles gets loops of links that together include all items in a loop.
+// Each loop is a path, where each item links to the next, and the last one to the first.
+func (graph *Graph) Cycles() [][]Item {
+	cycles := make([][]Item, 0)
+	for _, component := range graph.LoopComponents() {
+		inComponent := make(map[Item]bool)
+		for _, item := range component {
+			inComponent[item] = true
+		}
+		// Shortest loop through each item not already in one
+		found := make(map[Item]bool)
+		for _, start := range component {
+			if found[start] {
+				continue
+			}
+			cycle := graph.shortestCycle(start, inComponent)
+			for _, item := range cycle {
+				found[item] = true
+			}
+			cycles = append(cycles, cycle)
+		}
+	}
+	return cycles
+}
+
+// shortestCycle finds the shortest path from start back to itself, only going through items in component
+func (graph *Graph) shortestCycle(start Item, component map[Item]bool) []Item {
+	// Breadth first, remembering where each item was reached from
+	from := make(map[Item]Item)
+	queue := []Item{start}
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+		for _, child := range graph.Children(item) {
+			if child == start {
+				path := []Item{item}
+				for path[0] != start {
+					path = append([]Item{from[path[0]]}, path...)
+				}
+				return path
+			}
+			if _, visited := from[child]; visited || !component[child] {
+				continue
+			}
+			from[child] = item
+			queue = append(queue, child)
+		}
+	}
+	return []Item{start}
+}
+
+// graphJSONItem exports an item from a project with the children it has in a graph
+type graphJSONItem struct {
+	data     *DataContext
+	children map[Item][]Item
+	item     Item
+	// Parent of an item in a loop, which is exported as a root
+	parent Item
+}
+
+func (node graphJSONItem) MarshalJSON() ([]byte, error) {
+	children := make([]json.Marshaler, 0)
+	for _, child := range node.children[node.item] {
+		children = append(children, graphJSONItem{node.data, node.children, child, nil})
+	}
+	parent := ""
+	if node.parent != nil {
+		parent = FormatUID(node.parent.UID())
+	}
+	switch item := node.item.(type) {
+	case Requirement:
+		data, err := item.JSONData(node.data, children)
+		if err != nil {
+			return nil, err
+		}
+		data.Parent = parent
+		return json.Marshal(data)
+	case Solution:
+		data, err := item.JSONData(node.data, children)
+		if err != nil {
+			return nil, err
+		}
+		data.Parent = parent
+		return json.Marshal(data)
+	}
+	return nil, fmt.Errorf("unknown item type for %v", node.item.ID())
+}
+
+// JSONTree gets all roots, with their children, as they should be exported to json from a project.
+// Loops have no root, so the first item found in each loop is exported as a root, with its parent.
+// Items are loaded when marshaled, so errors loading them are returned when marshaling.
+func (graph *Graph) JSONTree(data *DataContext) []json.Marshaler {
+	// Every item is exported once, even if it's in a loop
+	children := make(map[Item][]Item)
+	visited := make(map[Item]bool)
+	var visit func(item Item)
+	visit = func(item Item) {
+		visited[item] = true
+		for _, child := range graph.Children(item) {
+			if !visited[child] {
+				children[item] = append(children[item], child)
+				visit(child)
+			}
+		}
+	}
+	roots := make([]json.Marshaler, 0)
+	for _, root := range graph.Roots() {
+		visit(root)
+		roots = append(roots, graphJSONItem{data, children, root, nil})
+	}
+	for _, item := range graph.Items() {
+		if visited[item] {
+			continue
+		}
+		visit(item)
+		var parent Item
+		if parents := graph.Parents(item); len(parents) > 0 {
+			parent = parents[0]
+		}
+		roots = append(roots, graphJSONItem{data, children, item, parent})
+	}
+	return roots
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Command is a change to a project that can be undone.
+// Items are referred to by uid, as ids change when items are removed and added again.
+type Command interface {
+	// Text describes the command, like "Delete Problem"
+	Text() string
+	// Redo performs the command
+	Redo(db *DataContext) error
+	// Undo reverts the command
+	Undo(db *DataContext) error
+}
+
+// History keeps track of commands that can be undone or redone
+type History struct {
+	undo []Command
+	redo []Command
+}
+
+// NewHistory creates a new empty history
+func NewHistory() *History {
+	return new(History)
+}
+
+// Do performs a command in a transaction and adds it to the history
+func (history *History) Do(db *DataContext, command Command) error {
+	if err := db.Transaction(func() error {
+		return command.Redo(db)
+	}); err != nil {
+		return fmt.Errorf("failed to %v: %v", command.Text(), err)
+	}
+	history.Record(command)
+	return nil
+}
+
+// Record adds a command that has already been performed to the history
+func (history *History) Record(command Command) {
+	history.undo = append(history.undo, command)
+	// Anything undone before can't be redone anymore
+	history.redo = nil
+}
+
+// Undo reverts the last command, nothing is changed if it fails
+func (history *History) Undo(db *DataContext) error {
+	if !history.CanUndo() {
+		return nil
+	}
+	command := history.undo[len(history.undo)-1]
+	if err := db.Transaction(func() error {
+		return command.Undo(db)
+	}); err != nil {
+		return fmt.Errorf("failed to undo %v: %v", command.Text(), err)
+	}
+	history.undo = history.undo[:len(history.undo)-1]
+	history.redo = append(history.redo, command)
+	return nil
+}
+
+// Redo performs the last undone command again, nothing is changed if it fails
+func (history *History) Redo(db *DataContext) error {
+	if !history.CanRedo() {
+		return nil
+	}
+	command := history.redo[len(history.redo)-1]
+	if err := db.Transaction(func() error {
+		return command.Redo(db)
+	}); err != nil {
+		return fmt.Errorf("failed to redo %v: %v", command.Text(), err)
+	}
+	history.redo = history.redo[:len(history.redo)-1]
+	history.undo = append(history.undo, command)
+	return nil
+}
+
+// CanUndo checks if there is anything to undo
+func (history *History) CanUndo() bool {
+	return len(history.undo) > 0
+}
+
+// CanRedo checks if there is anything to redo
+func (history *History) CanRedo() bool {
+	return len(history.redo) > 0
+}
+
+// UndoText gets the text of the command to undo, or an empty string
+func (history *History) UndoText() string {
+	if !history.CanUndo() {
+		return ""
+	}
+	return history.undo[len(history.undo)-1].Text()
+}
+
+// RedoText gets the text of the command to redo, or an empty string
+func (history *History) RedoText() string {
+	if !history.CanRedo() {
+		return ""
+	}
+	return history.redo[len(history.redo)-1].Text()
+}
+
+// Clear removes all commands, for example when opening another project
+func (history *History) Clear() {
+	history.undo = nil
+	history.redo = nil
+}
+
+// ItemSnapshot is a copy of an item and its links, used to restore it later.
+// Item and Parent in ItemData are only valid when the snapshot was taken.
+type ItemSnapshot struct {
+	ItemData
+	Type ItemType
+	// Parent and children by uid
+	HasParent bool
+	ParentUID int64
+	ChildUIDs []int64
+}
+
+// Snapshot gets a copy of an item and its links
+func (data *DataContext) Snapshot(item Item) (ItemSnapshot, error) {
+	itemData, err := data.LoadItem(item)
+	if err != nil {
+		return ItemSnapshot{}, err
+	}
+	snapshot := ItemSnapshot{
+		ItemData: itemData,
+		Type:     GetItemType(item),
+	}
+	if itemData.Parent != nil {
+		if err := data.GetItemValue(itemData.Parent.ID(), GetItemTableName(GetItemType(itemData.Parent)),
+			"uid", &snapshot.ParentUID); err != nil {
+			return snapshot, err
+		}
+		snapshot.HasParent = true
+	}
+	children, err := data.ItemChildren(item)
+	if err != nil {
+		return snapshot, err
+	}
+	for _, child := range children {
+		var uid int64
+		if err := data.GetItemValue(child.ID(), GetItemTableName(GetItemType(child)), "uid", &uid); err != nil {
+			return snapshot, err
+		}
+		snapshot.ChildUIDs = append(snapshot.ChildUIDs, uid)
+	}
+	return snapshot, nil
+}
+
+// RestoreItem adds an item from a snapshot, with the same uid and links to items that still exist
+func (data *DataContext) RestoreItem(snapshot ItemSnapshot) (Item, error) {
+	var err error
+	if snapshot.Type == TypeRequirement {
+		_, err = data.conn().Exec("insert into Requirements (uid, description, rationale, fitCriterion, "+
+			"color, border, shape, x, y, width, height) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			snapshot.UID, snapshot.Description, snapshot.Rationale, snapshot.FitCriterion,
+			snapshot.Color, snapshot.Border, snapshot.Shape, snapshot.X, snapshot.Y, snapshot.Width, snapshot.Height)
+	} else {
+		_, err = data.conn().Exec("insert into Solutions (uid, description, link, "+
+			"color, border, shape, x, y, width, height) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			snapshot.UID, snapshot.Description, snapshot.Link,
+			snapshot.Color, snapshot.Border, snapshot.Shape, snapshot.X, snapshot.Y, snapshot.Width, snapshot.Height)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore item %v: %v", FormatUID(snapshot.UID), err)
+	}
+	item, err := data.ItemByUID(snapshot.UID)
+	if err != nil {
+		return nil, err
+	}
+	// Restore links, ignoring items that are gone
+	if snapshot.HasParent {
+		if parent, err := data.ItemByUID(snapshot.ParentUID); err == nil {
+			if err := data.AddItemChild(parent, item); err != nil {
+				return item, err
+			}
+		}
+	}
+	for _, uid := range snapshot.ChildUIDs {
+		if child, err := data.ItemByUID(uid); err == nil {
+			if err := data.AddItemChild(item, child); err != nil {
+				return item, err
+			}
+		}
+	}
+	return item, nil
+}
+
+// DeleteItem removes an item by uid, including links to its children
+func (data *DataContext) DeleteItem(uid int64) error {
+	item, err := data.ItemByUID(uid)
+	if err != nil {
+		return err
+	}
+	if err := data.RemoveChildrenLinks(item); err != nil {
+		return err
+	}
+	return data.RemoveItem(item)
+}
+
+// ApplySnapshot updates the item with the same uid as the snapshot to match it,
+// recreating it if the type changed
+func (data *DataContext) ApplySnapshot(snapshot ItemSnapshot) (Item, error) {
+	item, err := data.ItemByUID(snapshot.UID)
+	if err != nil {
+		return nil, err
+	}
+	if GetItemType(item) != snapshot.Type {
+		if err := data.DeleteItem(snapshot.UID); err != nil {
+			return nil, err
+		}
+		// Labels and media are attached by uid, so only the type needs to be updated
+		for _, table := range []string{"LabelItems", "MediaItems"} {
+			if _, err := data.conn().Exec(fmt.Sprintf("update %v set type = ? where item = ?", table),
+				snapshot.Type, snapshot.UID); err != nil {
+				return nil, err
+			}
+		}
+		return data.RestoreItem(snapshot)
+	}
+	values := map[string]interface{}{
+		"description": snapshot.Description,
+		"color":       snapshot.Color,
+		"border":      snapshot.Border,
+		"shape":       snapshot.Shape,
+		"x":           snapshot.X,
+		"y":           snapshot.Y,
+		"width":       snapshot.Width,
+		"height":      snapshot.Height,
+	}
+	if snapshot.Type == TypeRequirement {
+		values["rationale"] = snapshot.Rationale
+		values["fitCriterion"] = snapshot.FitCriterion
+	} else {
+		values["link"] = snapshot.Link
+	}
+	return item, data.SetItemValues(item.ID(), GetItemTableName(snapshot.Type), values)
+}
+
+// snapshotName gets the name of the item type in a snapshot as shown to the user
+func snapshotName(snapshot ItemSnapshot) string {
+	if snapshot.Type == TypeRequirement {
+		return "Problem"
+	}
+	return "Solution"
+}
+
+// ItemCommand adds or removes an item
+type ItemCommand struct {
+	snapshot ItemSnapshot
+	remove   bool
+}
+
+// NewAddItemCommand creates a command for adding the item in the snapshot
+func NewAddItemCommand(snapshot ItemSnapshot) *ItemCommand {
+	return &ItemCommand{snapshot, false}
+}
+
+// NewRemoveItemCommand creates a command for removing the item in the snapshot
+func NewRemoveItemCommand(snapshot ItemSnapshot) *ItemCommand {
+	return &ItemCommand{snapshot, true}
+}
+
+func (command *ItemCommand) Text() string {
+	if command.remove {
+		return "Delete " + snapshotName(command.snapshot)
+	}
+	return "Add " + snapshotName(command.snapshot)
+}
+
+func (command *ItemCommand) Redo(db *DataContext) error {
+	if command.remove {
+		return db.DeleteItem(command.snapshot.UID)
+	}
+	_, err := db.RestoreItem(command.snapshot)
+	return err
+}
+
+func (command *ItemCommand) Undo(db *DataContext) error {
+	if command.remove {
+		_, err := db.RestoreItem(command.snapshot)
+		return err
+	}
+	return db.DeleteItem(command.snapshot.UID)
+}
+
+// EditItemCommand changes the properties, or type, of an item
+type EditItemCommand struct {
+	before, after ItemSnapshot
+}
+
+// NewEditItemCommand creates a command for changing an item from before to after
+func NewEditItemCommand(before, after ItemSnapshot) *EditItemCommand {
+	return &EditItemCommand{before, after}
+}
+
+func (command *EditItemCommand) Text() string {
+	return "Edit " + snapshotName(command.before)
+}
+
+func (command *EditItemCommand) Redo(db *DataContext) error {
+	_, err := db.ApplySnapshot(command.after)
+	return err
+}
+
+func (command *EditItemCommand) Undo(db *DataContext) error {
+	_, err := db.ApplySnapshot(command.before)
+	return err
+}
+
+// MoveItemCommand moves an item to a new position
+type MoveItemCommand struct {
+	uid          int64
+	fromX, fromY int
+	toX, toY     int
+}
+
+// NewMoveItemCommand creates a command for moving the item with the uid
+func NewMoveItemCommand(uid int64, fromX, fromY, toX, toY int) *MoveItemCommand {
+	return &MoveItemCommand{uid, fromX, fromY, toX, toY}
+}
+
+func (command *MoveItemCommand) Text() string {
+	return "Move Item"
+}
+
+func (command *MoveItemCommand) move(db *DataContext, x, y int) error {
+	item, err := db.ItemByUID(command.uid)
+	if err != nil {
+		return err
+	}
+	return db.SetItemValues(item.ID(), GetItemTableName(GetItemType(item)), map[string]interface{}{
+		"x": x,
+		"y": y,
+	})
+}
+
+func (command *MoveItemCommand) Redo(db *DataContext) error {
+	return command.move(db, command.toX, command.toY)
+}
+
+func (command *MoveItemCommand) Undo(db *DataContext) error {
+	return command.move(db, command.fromX, command.fromY)
+}
+
+// LinkCommand creates or removes a link between two items
+type LinkCommand struct {
+	parentUID, childUID int64
+	remove              bool
+}
+
+// NewAddLinkCommand creates a command for linking parent to child
+func NewAddLinkCommand(parentUID, childUID int64) *LinkCommand {
+	return &LinkCommand{parentUID, childUID, false}
+}
+
+// NewRemoveLinkCommand creates a command for removing the link from parent to child
+func NewRemoveLinkCommand(parentUID, childUID int64) *LinkCommand {
+	return &LinkCommand{parentUID, childUID, true}
+}
+
+func (command *LinkCommand) Text() string {
+	if command.remove {
+		return "Delete Link"
+	}
+	return "Add Link"
+}
+
+func (command *LinkCommand) link(db *DataContext, add bool) error {
+	child, err := db.ItemByUID(command.childUID)
+	if err != nil {
+		return err
+	}
+	if !add {
+		return db.SetItemValues(child.ID(), GetItemTableName(GetItemType(child)), map[string]interface{}{
+			"parent":     nil,
+			"parentType": nil,
+		})
+	}
+	parent, err := db.ItemByUID(command.parentUID)
+	if err != nil {
+		return err
+	}
+	return db.AddItemChild(parent, child)
+}
+
+func (command *LinkCommand) Redo(db *DataContext) error {
+	return command.link(db, !command.remove)
+}
+
+func (command *LinkCommand) Undo(db *DataContext) error {
+	return command.link(db, command.remove)
+}
+
+// ReplaceItemsCommand replaces all items in the project, for example after merging
+type ReplaceItemsCommand struct {
+	text          string
+	before, after []ItemSnapshot
+}
+
+// NewReplaceItemsCommand creates a command for replacing all items in before with the ones in after
+func NewReplaceItemsCommand(text string, before, after []ItemSnapshot) *ReplaceItemsCommand {
+	return &ReplaceItemsCommand{text, before, after}
+}
+
+func (command *ReplaceItemsCommand) Text() string {
+	return command.text
+}
+
+func (command *ReplaceItemsCommand) Redo(db *DataContext) error {
+	return db.ReplaceItems(command.after)
+}
+
+func (command *ReplaceItemsCommand) Undo(db *DataContext) error {
+	return db.ReplaceItems(command.before)
+}
+
+// CommandGroup performs several commands as one, for example editing an item and its labels
+type CommandGroup struct {
+	text     string
+	commands []Command
+}
+
+// NewCommandGroup creates a command performing all commands in order
+func NewCommandGroup(text string, commands ...Command) *CommandGroup {
+	return &CommandGroup{text, commands}
+}
+
+func (command *CommandGroup) Text() string {
+	return command.text
+}
+
+func (command *CommandGroup) Redo(db *DataContext) error {
+	for _, cmd := range command.commands {
+		if err := cmd.Redo(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (command *CommandGroup) Undo(db *DataContext) error {
+	// Undo in reverse, as later commands may depend on earlier ones
+	for i := len(command.commands) - 1; i >= 0; i-- {
+		if err := command.commands[i].Undo(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// itemCount gets how many items of each type there are in the project
+func itemCount(db *DataContext) (requirements, solutions int) {
+	items, _ := db.LoadItems()
+	for _, item := range items {
+		if GetItemType(item.Item) == TypeRequirement {
+			requirements++
+		} else {
+			solutions++
+		}
+	}
+	return
+}
+
+func TestHistory(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	db := project.Data()
+	history := NewHistory()
+	// Add a requirement with a solution as child
+	reqID, _ := db.AddEmptyRequirement()
+	solID, _ := db.AddEmptySolution()
+	req := NewRequirement(reqID)
+	sol := NewSolution(solID)
+	req.SetDescription("req")
+	reqUID, solUID := req.UID(), sol.UID()
+	snapshot, err := db.Snapshot(req)
+	if err != nil {
+		t.Error("failed to get snapshot:", err)
+		return
+	}
+	history.Record(NewAddItemCommand(snapshot))
+	if err := history.Do(db, NewAddLinkCommand(reqUID, solUID)); err != nil {
+		t.Error("failed to add link:", err)
+	}
+	// Delete requirement, which should also remove the link
+	if snapshot, err = db.Snapshot(req); err != nil || len(snapshot.ChildUIDs) != 1 {
+		t.Error("unexpected snapshot children, expected 1, but got", len(snapshot.ChildUIDs), err)
+	}
+	if err := history.Do(db, NewRemoveItemCommand(snapshot)); err != nil {
+		t.Error("failed to remove item:", err)
+	}
+	if sol.Parent() != nil {
+		t.Error("expected solution to not have a parent after removing requirement")
+	}
+	// Undo removal, the requirement should be back with the same uid and child
+	if err := history.Undo(db); err != nil {
+		t.Error("failed to undo:", err)
+	}
+	item, err := db.ItemByUID(reqUID)
+	if err != nil {
+		t.Error("failed to find restored requirement:", err)
+		return
+	}
+	if item.Description() != "req" {
+		t.Errorf("unexpected description, expected \"req\", but got \"%v\"", item.Description())
+	}
+	if parent := sol.Parent(); parent == nil || parent.UID() != reqUID {
+		t.Error("expected solution to be linked to restored requirement")
+	}
+	// Move the requirement and change it to a solution
+	x, y := item.Pos()
+	if err := history.Do(db, NewMoveItemCommand(reqUID, x, y, x+32, y+64)); err != nil {
+		t.Error("failed to move item:", err)
+	}
+	before, _ := db.Snapshot(item)
+	after := before
+	after.Type = TypeSolution
+	after.Description = "sol"
+	if err := history.Do(db, NewEditItemCommand(before, after)); err != nil {
+		t.Error("failed to edit item:", err)
+	}
+	if requirements, solutions := itemCount(db); requirements != 0 || solutions != 2 {
+		t.Error("unexpected item count after changing type, expected 0 and 2, but got",
+			requirements, "and", solutions)
+	}
+	if item, err = db.ItemByUID(reqUID); err != nil || GetItemType(item) != TypeSolution {
+		t.Error("expected item to have changed type:", err)
+		return
+	}
+	if parent := sol.Parent(); parent == nil || parent != item {
+		t.Error("expected solution to still be linked after changing type")
+	}
+	if itemX, itemY := item.Pos(); itemX != x+32 || itemY != y+64 {
+		t.Error("unexpected position after changing type:", itemX, itemY)
+	}
+	// Undo everything, leaving only the solution
+	for history.CanUndo() {
+		if err := history.Undo(db); err != nil {
+			t.Error("failed to undo:", err)
+			return
+		}
+	}
+	if requirements, solutions := itemCount(db); requirements != 0 || solutions != 1 {
+		t.Error("unexpected item count after undoing, expected 0 and 1, but got",
+			requirements, "and", solutions)
+	}
+	// Redo everything again
+	for history.CanRedo() {
+		if err := history.Redo(db); err != nil {
+			t.Error("failed to redo:", err)
+			return
+		}
+	}
+	if item, err = db.ItemByUID(reqUID); err != nil || item.Description() != "sol" {
+		t.Error("expected item to be edited after redoing:", err)
+	}
+	// Doing something new should clear what can be redone
+	_ = history.Undo(db)
+	if err := history.Do(db, NewRemoveLinkCommand(reqUID, solUID)); err != nil {
+		t.Error("failed to remove link:", err)
+	}
+	if history.CanRedo() {
+		t.Error("expected nothing to redo after a new command")
+	}
+	if sol.Parent() != nil {
+		t.Error("expected solution to not have a parent after removing link")
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 )
 
 // Item that is either a solutions or a requirement
@@ -48,7 +49,32 @@ func NewItem(id int64, itemType ItemType) Item {
 	} else if itemType == TypeSolution {
 		item = NewSolution(id)
 	} else {
-		fmt.Println("error: failed to create item from id", id, "type", itemType)
+		fmt.Fprintln(os.Stderr, "error: failed to create item from id", id, "type", itemType)
 	}
 	return item
-}
\ No newline at end of file
+}
+
+// GetItemName gets the name of the item type as shown to the user
+func GetItemName(item Item) string {
+	switch GetItemType(item) {
+	case TypeRequirement:
+		return "Problem"
+	case TypeSolution:
+		return "Solution"
+	}
+	return ""
+}
+
+// Border colors of items, purple for problems and blue for solutions
+const (
+	ProblemColor  uint = 10233776
+	SolutionColor uint = 2201331
+)
+
+// GetItemColor gets the border color of the item, as shown in the canvas
+func GetItemColor(item Item) uint {
+	if GetItemType(item) == TypeSolution {
+		return SolutionColor
+	}
+	return ProblemColor
+}
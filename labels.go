@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Label is a tag, with a color, that can be added to any number of items.
+// Items are assigned in LabelItems by uid, so labels are kept when an item
+// changes type, or is deleted and then restored by undoing.
+type Label struct {
+	ID    int64
+	Name  string
+	Color uint
+}
+
+// Labels gets all labels sorted by name
+func (data *DataContext) Labels() ([]Label, error) {
+	rows, err := data.conn().Query("select _rowid_, coalesce(tag, ''), coalesce(color, 0) from Labels")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels: %v", err)
+	}
+	defer rows.Close()
+	labels := make([]Label, 0)
+	for rows.Next() {
+		var label Label
+		if err := rows.Scan(&label.ID, &label.Name, &label.Color); err != nil {
+			return labels, fmt.Errorf("failed to get label: %v", err)
+		}
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return strings.ToLower(labels[i].Name) < strings.ToLower(labels[j].Name)
+	})
+	return labels, rows.Err()
+}
+
+// LabelByName finds the label with the specified name
+func (data *DataContext) LabelByName(name string) (Label, error) {
+	labels, err := data.Labels()
+	if err != nil {
+		return Label{}, err
+	}
+	for _, label := range labels {
+		if label.Name == name {
+			return label, nil
+		}
+	}
+	return Label{}, fmt.Errorf("no label with name \"%v\"", name)
+}
+
+// checkLabelName makes sure the name isn't empty or used by another label
+func (data *DataContext) checkLabelName(name string, id int64) error {
+	if name == "" {
+		return fmt.Errorf("label name can't be empty")
+	}
+	if label, err := data.LabelByName(name); err == nil && label.ID != id {
+		return fmt.Errorf("label with name \"%v\" already exists", name)
+	}
+	return nil
+}
+
+// AddLabel creates a new label
+func (data *DataContext) AddLabel(name string, color uint) (Label, error) {
+	label := Label{
+		Name:  strings.TrimSpace(name),
+		Color: color,
+	}
+	if err := data.checkLabelName(label.Name, 0); err != nil {
+		return label, err
+	}
+	result, err := data.conn().Exec("insert into Labels (tag, color) values (?, ?)", label.Name, label.Color)
+	if err != nil {
+		return label, fmt.Errorf("failed to add label: %v", err)
+	}
+	label.ID, err = result.LastInsertId()
+	return label, err
+}
+
+// UpdateLabel saves a new name or color of a label
+func (data *DataContext) UpdateLabel(label Label) error {
+	label.Name = strings.TrimSpace(label.Name)
+	if err := data.checkLabelName(label.Name, label.ID); err != nil {
+		return err
+	}
+	_, err := data.conn().Exec("update Labels set tag = ?, color = ? where _rowid_ = ?",
+		label.Name, label.Color, label.ID)
+	return err
+}
+
+// RemoveLabel removes a label from all items, and then the label itself
+func (data *DataContext) RemoveLabel(id int64) error {
+	if _, err := data.conn().Exec("delete from LabelItems where label = ?", id); err != nil {
+		return err
+	}
+	_, err := data.conn().Exec("delete from Labels where _rowid_ = ?", id)
+	return err
+}
+
+// labelItemsQuery gets the uid of labelled items with each label
+const labelItemsQuery = "select LabelItems.item, Labels._rowid_, coalesce(Labels.tag, ''), " +
+	"coalesce(Labels.color, 0) from LabelItems join Labels on Labels._rowid_ = LabelItems.label"
+
+// ItemLabels gets all labels assigned to the item with the specified uid
+func (data *DataContext) ItemLabels(uid int64) ([]Label, error) {
+	labels, err := data.queryItemLabels(labelItemsQuery+" where LabelItems.item = ?", uid)
+	return labels[uid], err
+}
+
+// AllItemLabels gets the labels of all items by uid, in a single query
+func (data *DataContext) AllItemLabels() (map[int64][]Label, error) {
+	return data.queryItemLabels(labelItemsQuery)
+}
+
+// queryItemLabels gets labels by item uid, sorted by name
+func (data *DataContext) queryItemLabels(query string, args ...interface{}) (map[int64][]Label, error) {
+	rows, err := data.conn().Query(query+" order by lower(Labels.tag)", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item labels: %v", err)
+	}
+	defer rows.Close()
+	labels := make(map[int64][]Label)
+	for rows.Next() {
+		var uid int64
+		var label Label
+		if err := rows.Scan(&uid, &label.ID, &label.Name, &label.Color); err != nil {
+			return labels, fmt.Errorf("failed to get item label: %v", err)
+		}
+		labels[uid] = append(labels[uid], label)
+	}
+	return labels, rows.Err()
+}
+
+// SetItemLabels replaces all labels of the item with the specified uid
+func (data *DataContext) SetItemLabels(uid int64, itemType ItemType, labelIDs []int64) error {
+	if _, err := data.conn().Exec("delete from LabelItems where item = ?", uid); err != nil {
+		return err
+	}
+	for _, id := range labelIDs {
+		if _, err := data.conn().Exec("insert into LabelItems (label, item, type) values (?, ?, ?)",
+			id, uid, itemType); err != nil {
+			return fmt.Errorf("failed to add label to item: %v", err)
+		}
+	}
+	return nil
+}
+
+// LabelIDs gets the ids of labels
+func LabelIDs(labels []Label) []int64 {
+	ids := make([]int64, len(labels))
+	for i, label := range labels {
+		ids[i] = label.ID
+	}
+	return ids
+}
+
+// SameLabels checks if both lists have the same label ids, in any order
+func SameLabels(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	found := make(map[int64]bool)
+	for _, id := range a {
+		found[id] = true
+	}
+	for _, id := range b {
+		if !found[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// LabelNames gets the names of labels, used when exporting
+func LabelNames(labels []Label) []string {
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = label.Name
+	}
+	return names
+}
+
+// LabelData is a label as exported to json
+type LabelData struct {
+	Name  string
+	Color uint
+}
+
+// LabelsJSON gets all labels as they should be exported to json
+func (data *DataContext) LabelsJSON() ([]LabelData, error) {
+	labels, err := data.Labels()
+	exported := make([]LabelData, len(labels))
+	for i, label := range labels {
+		exported[i] = LabelData{label.Name, label.Color}
+	}
+	return exported, err
+}
+
+// SetItemLabelNames replaces all labels of an item using their names,
+// creating labels that don't exist yet
+func (data *DataContext) SetItemLabelNames(uid int64, itemType ItemType, names []string) error {
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		label, err := data.LabelByName(name)
+		if err != nil {
+			if label, err = data.AddLabel(name, 0); err != nil {
+				return err
+			}
+		}
+		ids = append(ids, label.ID)
+	}
+	return data.SetItemLabels(uid, itemType, ids)
+}
+
+// LabelItemsCommand changes what labels an item has
+type LabelItemsCommand struct {
+	uid           int64
+	itemType      ItemType
+	before, after []int64
+	// Names of labels added after the labels in after, that are created by another command before this one
+	created []string
+}
+
+// NewLabelItemsCommand creates a command for changing the labels of an item from before to after
+func NewLabelItemsCommand(uid int64, itemType ItemType, before, after []int64) *LabelItemsCommand {
+	return &LabelItemsCommand{uid, itemType, before, after, nil}
+}
+
+// NewLabelItemNamesCommand creates a command for changing the labels of an item from before to after,
+// and to the labels in created, that don't exist until a CreateLabelsCommand before it is done
+func NewLabelItemNamesCommand(uid int64, itemType ItemType, before, after []int64,
+	created []string) *LabelItemsCommand {
+	return &LabelItemsCommand{uid, itemType, before, after, created}
+}
+
+func (command *LabelItemsCommand) Text() string {
+	return "Change Labels"
+}
+
+func (command *LabelItemsCommand) Redo(db *DataContext) error {
+	// Created labels get a new id each time they're created
+	after := append([]int64{}, command.after...)
+	for _, name := range command.created {
+		label, err := db.LabelByName(name)
+		if err != nil {
+			return err
+		}
+		after = append(after, label.ID)
+	}
+	return db.SetItemLabels(command.uid, command.itemType, after)
+}
+
+func (command *LabelItemsCommand) Undo(db *DataContext) error {
+	return db.SetItemLabels(command.uid, command.itemType, command.before)
+}
+
+// CreateLabelsCommand creates new labels, that are removed again when undone
+type CreateLabelsCommand struct {
+	names []string
+	// Labels as last created
+	ids []int64
+}
+
+// NewCreateLabelsCommand creates a command for creating labels with the specified names
+func NewCreateLabelsCommand(names []string) *CreateLabelsCommand {
+	return &CreateLabelsCommand{names, nil}
+}
+
+func (command *CreateLabelsCommand) Text() string {
+	return "Create Labels"
+}
+
+func (command *CreateLabelsCommand) Redo(db *DataContext) error {
+	command.ids = make([]int64, 0, len(command.names))
+	for _, name := range command.names {
+		label, err := db.AddLabel(name, 0)
+		if err != nil {
+			return err
+		}
+		command.ids = append(command.ids, label.ID)
+	}
+	return nil
+}
+
+func (command *CreateLabelsCommand) Undo(db *DataContext) error {
+	for _, id := range command.ids {
+		if err := db.RemoveLabel(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
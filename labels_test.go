@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestLabels(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	projectPath := fmt.Sprintf("%v/openrq_test.orq", tempDir)
+	project, err := NewProject(projectPath)
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	db := project.Data()
+	// Create labels, names should be unique
+	important, err := db.AddLabel("important", 0xf44336)
+	if err != nil {
+		t.Error("failed to add label:", err)
+		return
+	}
+	later, _ := db.AddLabel("later", 0x2196f3)
+	if _, err := db.AddLabel(" important ", 0); err == nil {
+		t.Error("expected duplicate label name to fail")
+	}
+	later.Name = "important"
+	if err := db.UpdateLabel(later); err == nil {
+		t.Error("expected renaming to duplicate label name to fail")
+	}
+	later.Name = "Later"
+	if err := db.UpdateLabel(later); err != nil {
+		t.Error("failed to rename label:", err)
+	}
+	// Assign both labels to a requirement
+	reqID, _ := db.AddEmptyRequirement()
+	req := NewRequirement(reqID)
+	uid := req.UID()
+	history := NewHistory()
+	if err := history.Do(db, NewLabelItemsCommand(uid, TypeRequirement, nil,
+		[]int64{important.ID, later.ID})); err != nil {
+		t.Error("failed to label item:", err)
+		return
+	}
+	if labels, _ := db.ItemLabels(uid); len(labels) != 2 || labels[0].Name != "important" || labels[1].Name != "Later" {
+		t.Error("expected item to have both labels, but got", labels)
+	}
+	// Changing the type of the item keeps its labels
+	before, _ := db.Snapshot(req)
+	after := before
+	after.Type = TypeSolution
+	if err := history.Do(db, NewEditItemCommand(before, after)); err != nil {
+		t.Error("failed to change item type:", err)
+		return
+	}
+	if labels, _ := db.AllItemLabels(); len(labels[uid]) != 2 {
+		t.Error("expected labels to be kept when changing type, but got", labels[uid])
+	}
+	// Export to json and import it again
+	items, _ := db.LoadItems()
+	jsonPath := fmt.Sprintf("%v/openrq_test.json", tempDir)
+	if err := project.SaveJSON(jsonPath, NewGraphFromItems(items)); err != nil {
+		t.Error("failed to export project:", err)
+		return
+	}
+	unused, _ := db.AddLabel("unused", 0)
+	imported, err := ImportJSON(jsonPath, fmt.Sprintf("%v/openrq_imported.orq", tempDir))
+	if err != nil {
+		t.Error("failed to import project:", err)
+		return
+	}
+	importedLabels, _ := imported.Data().Labels()
+	if len(importedLabels) != 2 || importedLabels[1].Name != "Later" || importedLabels[1].Color != 0x2196f3 {
+		t.Error("expected labels with colors to be imported, but got", importedLabels)
+	}
+	if labels, _ := imported.Data().ItemLabels(uid); len(labels) != 2 {
+		t.Error("expected imported item to have both labels, but got", labels)
+	}
+	imported.Close()
+	// Importing closed the project, so open it again
+	if project, err = NewProject(projectPath); err != nil {
+		t.Error("failed to open project again:", err)
+		return
+	}
+	defer project.Close()
+	db = project.Data()
+	// Undoing removes them from the item, and removing a label removes it from all items
+	if err := history.Undo(db); err != nil {
+		t.Error("failed to undo type change:", err)
+	}
+	if err := db.RemoveLabel(important.ID); err != nil {
+		t.Error("failed to remove label:", err)
+	}
+	if labels, _ := db.ItemLabels(uid); len(labels) != 1 || labels[0].ID != later.ID {
+		t.Error("expected item to only have one label left, but got", labels)
+	}
+	if err := history.Undo(db); err != nil {
+		t.Error("failed to undo labelling:", err)
+	}
+	if labels, _ := db.ItemLabels(uid); len(labels) != 0 {
+		t.Error("expected item to have no labels, but got", labels)
+	}
+	if labels, _ := db.Labels(); len(labels) != 2 || labels[1].ID != unused.ID {
+		t.Error("expected labels to be kept when unused, but got", labels)
+	}
+}
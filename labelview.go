@@ -0,0 +1,364 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// Roles for label data stored in list items
+const (
+	labelIDRole    = int(core.Qt__UserRole)
+	labelColorRole = int(core.Qt__UserRole) + 1
+)
+
+// Keys for data stored in graphics items
+const (
+	// Uid of the item shown by a group
+	dataItemUID = 2
+	// Set on label chips, to find them in a group
+	dataLabelChip = 3
+)
+
+// Labels of all items in the scene by uid
+var itemLabels map[int64][]Label
+
+// Labels to show items for, other items are dimmed
+var labelFilter = make(map[int64]bool)
+
+// List of labels in the label dock, if created
+var labelList *widgets.QListWidget
+
+// LabelIcon creates a square icon with the color of a label
+func LabelIcon(label Label) *gui.QIcon {
+	pixmap := gui.NewQPixmap3(16, 16)
+	pixmap.Fill(gui.NewQColor4(label.Color))
+	return gui.NewQIcon2(pixmap)
+}
+
+// labelTextColor gets black or white, whatever is most readable on the label color
+func labelTextColor(label Label) *gui.QColor {
+	color := gui.NewQColor4(label.Color)
+	if color.Red()*299+color.Green()*587+color.Blue()*114 > 128000 {
+		return gui.NewQColor2(core.Qt__black)
+	}
+	return gui.NewQColor2(core.Qt__white)
+}
+
+// ShowLabels adds a colored chip for each label along the bottom of a group,
+// replacing any chips already shown
+func ShowLabels(group *widgets.QGraphicsItemGroup, uid int64, labels []Label) {
+	group.SetData(dataItemUID, core.NewQVariant1(uid))
+	for _, child := range group.ChildItems() {
+		if child.Data(dataLabelChip).ToBool() {
+			group.RemoveFromGroup(child)
+			scene.RemoveItem(child)
+		}
+	}
+	width := group.ChildrenBoundingRect().Width()
+	height := group.ChildrenBoundingRect().Height()
+	x := 4.0
+	for i, label := range labels {
+		text := widgets.NewQGraphicsSimpleTextItem2(Truncate(label.Name, 12), nil)
+		font := text.Font()
+		font.SetPointSizeF(font.PointSizeF() * 0.75)
+		text.SetFont(font)
+		text.SetBrush(gui.NewQBrush3(labelTextColor(label), 1))
+		textRect := text.BoundingRect()
+		chipWidth := textRect.Width() + 8
+		// Show how many more labels there are when running out of space
+		if x+chipWidth > width-4 && i > 0 {
+			text.SetText(fmt.Sprintf("+%v", len(labels)-i))
+			text.SetBrush(gui.NewQBrush3(gui.NewQColor2(core.Qt__gray), 1))
+			text.SetPos2(x, height-textRect.Height()-4)
+			text.SetData(dataLabelChip, core.NewQVariant1(true))
+			text.SetZValue(16)
+			group.AddToGroup(text)
+			break
+		}
+		chip := widgets.NewQGraphicsRectItem3(0, 0, chipWidth, textRect.Height()+2, nil)
+		chip.SetBrush(gui.NewQBrush3(gui.NewQColor4(label.Color), 1))
+		chip.SetPen(gui.NewQPen2(core.Qt__NoPen))
+		chip.SetToolTip(label.Name)
+		chip.SetPos2(x, height-textRect.Height()-6)
+		chip.SetData(dataLabelChip, core.NewQVariant1(true))
+		chip.SetZValue(16)
+		text.SetParentItem(chip)
+		text.SetPos2(4, 1)
+		group.AddToGroup(chip)
+		x += chipWidth + 4
+	}
+	group.SetOpacity(LabelOpacity(uid))
+}
+
+// LabelOpacity gets the opacity of an item, dimmed if it doesn't match the label filter
+func LabelOpacity(uid int64) float64 {
+	if len(labelFilter) == 0 {
+		return 1.0
+	}
+	for _, label := range itemLabels[uid] {
+		if labelFilter[label.ID] {
+			return 1.0
+		}
+	}
+	return 0.25
+}
+
+// ApplyLabelFilter dims all items in the scene that don't have any of the filtered labels
+func ApplyLabelFilter() {
+	for _, item := range scene.Items(core.Qt__AscendingOrder) {
+		// Only groups have an uid set
+		if uid := item.Data(dataItemUID); item.Type() == 10 && uid.IsValid() {
+			item.SetOpacity(LabelOpacity(uid.ToLongLong(nil)))
+		}
+	}
+}
+
+// LoadLabels loads the labels of all items, and updates the label dock
+func LoadLabels() {
+	var err error
+	if itemLabels, err = currentProject.Data().AllItemLabels(); err != nil {
+		fmt.Println("error: failed to get labels:", err)
+	}
+	UpdateLabelList()
+}
+
+// AddLabelListItem adds a label that can be checked to a list
+func AddLabelListItem(list *widgets.QListWidget, label Label, checked bool) {
+	listItem := widgets.NewQListWidgetItem3(LabelIcon(label), label.Name, list, 0)
+	listItem.SetData(labelIDRole, core.NewQVariant1(label.ID))
+	listItem.SetData(labelColorRole, core.NewQVariant1(label.Color))
+	listItem.SetFlags(listItem.Flags() | core.Qt__ItemIsUserCheckable)
+	state := core.Qt__Unchecked
+	if checked {
+		state = core.Qt__Checked
+	}
+	listItem.SetCheckState(state)
+}
+
+// UpdateLabelList shows all labels in the project in the label dock
+func UpdateLabelList() {
+	if labelList == nil {
+		return
+	}
+	labels, err := currentProject.Data().Labels()
+	if err != nil {
+		fmt.Println("error: failed to get labels:", err)
+	}
+	labelList.BlockSignals(true)
+	labelList.Clear()
+	filter := make(map[int64]bool)
+	for _, label := range labels {
+		// Keep filtering by labels that still exist
+		if labelFilter[label.ID] {
+			filter[label.ID] = true
+		}
+		AddLabelListItem(labelList, label, labelFilter[label.ID])
+	}
+	labelList.BlockSignals(false)
+	labelFilter = filter
+}
+
+// CreateLabelList creates a list of labels that can be checked, with the specified ones checked
+func CreateLabelList(checked []Label) *widgets.QListWidget {
+	list := widgets.NewQListWidget(nil)
+	isChecked := make(map[int64]bool)
+	for _, label := range checked {
+		isChecked[label.ID] = true
+	}
+	labels, err := currentProject.Data().Labels()
+	if err != nil {
+		fmt.Println("error: failed to get labels:", err)
+	}
+	for _, label := range labels {
+		AddLabelListItem(list, label, isChecked[label.ID])
+	}
+	return list
+}
+
+// CheckedLabels gets the ids of all checked labels in a list
+func CheckedLabels(list *widgets.QListWidget) []int64 {
+	ids := make([]int64, 0)
+	for i := 0; i < list.Count(); i++ {
+		if listItem := list.Item(i); listItem.CheckState() == core.Qt__Checked {
+			ids = append(ids, listItem.Data(labelIDRole).ToLongLong(nil))
+		}
+	}
+	return ids
+}
+
+// SetItemLabels changes the labels of the item shown in a group, and updates its chips
+func SetItemLabels(group *widgets.QGraphicsItemGroup, item Item, labelIDs []int64) error {
+	db := currentProject.Data()
+	uid := item.UID()
+	if err := currentHistory.Do(db, NewLabelItemsCommand(uid, GetItemType(item),
+		LabelIDs(itemLabels[uid]), labelIDs)); err != nil {
+		return err
+	}
+	labels, err := db.ItemLabels(uid)
+	if err != nil {
+		return err
+	}
+	itemLabels[uid] = labels
+	ShowLabels(group, uid, labels)
+	return nil
+}
+
+// CreateLabelMenu creates a menu for adding or removing labels from the item shown in a group
+func CreateLabelMenu(window *widgets.QMainWindow, group *widgets.QGraphicsItemGroup) *widgets.QMenu {
+	menu := widgets.NewQMenu2("Labels", nil)
+	item := GetGroupItem(group)
+	labels, err := currentProject.Data().Labels()
+	if err != nil {
+		fmt.Println("error: failed to get labels:", err)
+	}
+	if len(labels) == 0 {
+		menu.AddAction("No Labels").SetEnabled(false)
+		return menu
+	}
+	current := LabelIDs(itemLabels[item.UID()])
+	for _, label := range labels {
+		labelID := label.ID
+		action := menu.AddAction2(LabelIcon(label), label.Name)
+		action.SetCheckable(true)
+		for _, id := range current {
+			if id == labelID {
+				action.SetChecked(true)
+			}
+		}
+		action.ConnectTriggered(func(checked bool) {
+			ids := make([]int64, 0, len(current)+1)
+			for _, id := range current {
+				if id != labelID {
+					ids = append(ids, id)
+				}
+			}
+			if checked {
+				ids = append(ids, labelID)
+			}
+			if err := SetItemLabels(group, item, ids); err != nil {
+				widgets.QMessageBox_Warning(window, "Failed to Change Labels", err.Error(),
+					widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			}
+		})
+	}
+	return menu
+}
+
+// chooseLabelColor asks for a new label color, returns false if cancelled
+func chooseLabelColor(window *widgets.QMainWindow, label *Label) bool {
+	color := widgets.QColorDialog_GetColor(gui.NewQColor4(label.Color), window,
+		fmt.Sprintf("Color of \"%v\"", label.Name), 0)
+	if !color.IsValid() {
+		return false
+	}
+	label.Color = color.Rgb() & 0xffffff
+	return true
+}
+
+// CreateLabelDock creates the widget for managing labels and filtering items by them
+func CreateLabelDock(window *widgets.QMainWindow) *widgets.QWidget {
+	layout := widgets.NewQVBoxLayout()
+	labelList = widgets.NewQListWidget(nil)
+	labelList.SetToolTip("Check labels to only highlight items with them")
+	// Filter when checking labels
+	labelList.ConnectItemChanged(func(listItem *widgets.QListWidgetItem) {
+		id := listItem.Data(labelIDRole).ToLongLong(nil)
+		if listItem.CheckState() == core.Qt__Checked {
+			labelFilter[id] = true
+		} else {
+			delete(labelFilter, id)
+		}
+		ApplyLabelFilter()
+	})
+	layout.AddWidget(labelList, 1, 0)
+	showError := func(err error) {
+		widgets.QMessageBox_Warning(window, "Failed to Change Label", err.Error(),
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+	}
+	// Gets the selected label, if any
+	selected := func() (Label, bool) {
+		listItem := labelList.CurrentItem()
+		if currentProject == nil || currentProject.ReadOnly() || listItem.Pointer() == nil {
+			return Label{}, false
+		}
+		return Label{
+			ID:    listItem.Data(labelIDRole).ToLongLong(nil),
+			Name:  listItem.Text(),
+			Color: listItem.Data(labelColorRole).ToUInt(nil),
+		}, true
+	}
+	// Changes are shown on all items after saving
+	update := func(err error) {
+		if err != nil {
+			showError(err)
+			return
+		}
+		ReloadProject(window)
+	}
+	buttons := widgets.NewQGridLayout2()
+	add := widgets.NewQPushButton2("Add", nil)
+	add.ConnectReleased(func() {
+		if currentProject == nil || currentProject.ReadOnly() {
+			return
+		}
+		ok := false
+		name := widgets.QInputDialog_GetText(window, "Add Label", "Label name",
+			widgets.QLineEdit__Normal, "", &ok, 0, 0)
+		label := Label{Name: name, Color: 10233776}
+		if ok && chooseLabelColor(window, &label) {
+			_, err := currentProject.Data().AddLabel(label.Name, label.Color)
+			update(err)
+		}
+	})
+	buttons.AddWidget2(add, 0, 0, 0)
+	rename := widgets.NewQPushButton2("Rename", nil)
+	rename.ConnectReleased(func() {
+		label, found := selected()
+		if !found {
+			return
+		}
+		ok := false
+		name := widgets.QInputDialog_GetText(window, "Rename Label", "Label name",
+			widgets.QLineEdit__Normal, label.Name, &ok, 0, 0)
+		if ok {
+			label.Name = name
+			update(currentProject.Data().UpdateLabel(label))
+		}
+	})
+	buttons.AddWidget2(rename, 0, 1, 0)
+	color := widgets.NewQPushButton2("Color", nil)
+	color.ConnectReleased(func() {
+		if label, found := selected(); found && chooseLabelColor(window, &label) {
+			update(currentProject.Data().UpdateLabel(label))
+		}
+	})
+	buttons.AddWidget2(color, 1, 0, 0)
+	remove := widgets.NewQPushButton2("Delete", nil)
+	remove.ConnectReleased(func() {
+		label, found := selected()
+		if !found {
+			return
+		}
+		if widgets.QMessageBox_Question(window, "Delete Label",
+			fmt.Sprintf("Delete \"%v\" and remove it from all items?", label.Name),
+			widgets.QMessageBox__Yes|widgets.QMessageBox__No, widgets.QMessageBox__No) != widgets.QMessageBox__Yes {
+			return
+		}
+		db := currentProject.Data()
+		update(db.Transaction(func() error {
+			return db.RemoveLabel(label.ID)
+		}))
+	})
+	buttons.AddWidget2(remove, 1, 1, 0)
+	layout.AddLayout(buttons, 0)
+	if currentProject != nil {
+		UpdateLabelList()
+	}
+	return LayoutToWidget(layout)
+}
@@ -0,0 +1,37 @@
+package main
+
+// Spacing between items placed by LayoutTree, leaving room for the default item size
+const (
+	layoutColumnWidth = 160
+	layoutRowHeight   = 128
+)
+
+// LayoutTree places items top-down in rows by depth, where each leaf gets its
+// own column and parents are centered above their children
+func LayoutTree(roots []Item, children map[Item][]Item) map[Item][2]int {
+	positions := make(map[Item][2]int)
+	column := 0
+	var place func(item Item, depth int)
+	place = func(item Item, depth int) {
+		// Items in loops are only placed once
+		if _, found := positions[item]; found {
+			return
+		}
+		positions[item] = [2]int{}
+		first := column
+		for _, child := range children[item] {
+			place(child, depth+1)
+		}
+		x := first * layoutColumnWidth
+		if column > first {
+			x = (first + column - 1) * layoutColumnWidth / 2
+		} else {
+			column++
+		}
+		positions[item] = [2]int{x, depth * layoutRowHeight}
+	}
+	for _, root := range roots {
+		place(root, 0)
+	}
+	return positions
+}
@@ -1,9 +1,14 @@
+//go:build !headless
+// +build !headless
+
 package main
 
-import "github.com/therecipe/qt/core"
+import (
+	"fmt"
+	"os"
 
-// Variables set from linker flags
-const versionTagName = "v1.0"
+	"github.com/therecipe/qt/core"
+)
 
 func main() {
 	// Setup some application variables
@@ -23,4 +28,11 @@ func main() {
 
 	// Main Qt event loop
 	app.Exec()
+
+	// Close project, removing any temporary files, and saving compressed projects
+	if currentProject != nil {
+		if err := currentProject.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "error: failed to close project:", err)
+		}
+	}
 }
@@ -1,3 +1,6 @@
+//go:build !headless
+// +build !headless
+
 package main
 
 import (
@@ -26,8 +29,12 @@ func (link Link) SetChildItem(child Item) {
 	link.dir.SetData(1, childItemType)
 }
 
+// Graphics for each link, both from parent and child
 var links map[Item][]*Link
 
+// Graph of all items and links shown in the scene
+var currentGraph *Graph
+
 var view *widgets.QGraphicsView
 var scene *widgets.QGraphicsScene
 
@@ -36,6 +43,10 @@ var backgroundColor *gui.QColor
 // Items opened in an edit window
 var openItems map[Item]*widgets.QDockWidget
 
+// Changes that can be undone, and what project they were made in
+var currentHistory = NewHistory()
+var historyProject *Project
+
 func IsItemOpen(item Item) bool {
 	_, ok := openItems[item]
 	return ok
@@ -46,10 +57,21 @@ func CloseItem(item Item) {
 }
 
 func ReloadProject(window *widgets.QMainWindow) {
+	// Changes made in another project can't be undone here
+	if historyProject != currentProject {
+		currentHistory.Clear()
+		historyProject = currentProject
+		// Labels are different in each project
+		labelFilter = make(map[int64]bool)
+		// So are validation rules
+		LoadValidationRules()
+	}
 	// Make sure view is enabled
 	view.SetEnabled(true)
-	// Delete all current items
+	// Delete all current items, including any comparison
 	scene.Clear()
+	diffOverlay = nil
+	validationOverlay = nil
 	// Clear links
 	links = make(map[Item][]*Link)
 	// Close all open items
@@ -57,69 +79,67 @@ func ReloadProject(window *widgets.QMainWindow) {
 		openItems[id].Close()
 		CloseItem(id)
 	}
-	// Connect to database to get new items
-	db := currentProject.Data()
-	defer db.Close()
-	// Load items
-	items, err := db.Items()
+	// Load labels and attachments of all items
+	LoadLabels()
+	LoadMedia()
+	// Load all items, with links, at once
+	items, err := currentProject.Data().LoadItems()
 	if err != nil {
 		fmt.Println("error: failed to get saved items:", err)
-	} else {
-		var x, y, w, h int
-		for item, description := range items {
-			x, y = item.Pos()
-			w, h = item.Size()
-			scene.AddItem(NewGraphicsItem(description, x, y, w, h, item))
-		}
 	}
-	// Load links
-	links, err := db.Links()
-	if err != nil {
-		fmt.Println("error: failed to get saved links:", err)
-	} else {
-		for child, parent := range links {
-			// Find parent and child
-			var parentItem, childItem *widgets.QGraphicsItemGroup
-			for _, item := range view.Items() {
-				group := item.Group()
-				if group == nil {
-					continue
-				}
-				groupID := group.Data(0).ToLongLong(nil)
-				groupType := ItemType(group.Data(1).ToInt(nil))
-				if groupID == 0 || groupType == 0 {
-					continue
-				}
-				if groupID == child.ID() && groupType == GetItemType(child) {
-					childItem = group
-				} else if groupID == parent.ID() && groupType == GetItemType(parent) {
-					parentItem = group
-				}
-				// Stop loop if we found everything
-				if parentItem != nil && childItem != nil {
-					break
-				}
-			}
-			// Create and add link
-			if parentItem == nil || childItem == nil {
-				fmt.Printf("warning: could not find parent or child, ignoring link (%3v(%v)%v -> %3v(%v)%v)\n",
-					parent.ID(), GetItemType(parent), parentItem != nil, child.ID(), GetItemType(child), childItem != nil)
-			} else {
-				link := CreateLink(parentItem, childItem)
-				scene.AddItem(link.line)
-				scene.AddItem(link.dir)
-			}
-		}
+	currentGraph = NewGraphFromItems(items)
+	// Add all items in the graph to the scene
+	groups := make(map[Item]*widgets.QGraphicsItemGroup)
+	for _, item := range items {
+		groups[item.Item] = NewGraphicsItem(item.Description, item.X, item.Y, item.Width, item.Height, item.Item)
+		ShowLabels(groups[item.Item], item.UID, itemLabels[item.UID])
+		ShowMedia(groups[item.Item], itemMedia[item.UID])
+		scene.AddItem(groups[item.Item])
 	}
+	// Add all links between them
+	currentGraph.Links(func(parent, child Item) {
+		link := CreateLink(groups[parent], groups[child])
+		scene.AddItem(link.line)
+		scene.AddItem(link.dir)
+	})
 	// Set window title
 	UpdateWindowTitle(window)
 }
 
+// UndoCommand undoes, or redoes, the last change and shows the result
+func UndoCommand(window *widgets.QMainWindow, redo bool) {
+	if currentProject == nil {
+		return
+	}
+	db := currentProject.Data()
+	title := "Failed to Undo"
+	var err error
+	if redo {
+		title = "Failed to Redo"
+		err = currentHistory.Redo(db)
+	} else {
+		err = currentHistory.Undo(db)
+	}
+	if err != nil {
+		widgets.QMessageBox_Warning(window, title, err.Error(),
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	// Show items and links as they are now
+	ReloadProject(window)
+}
+
 func UpdateWindowTitle(window *widgets.QMainWindow) {
-	abs, err := filepath.Abs(currentProject.path)
+	// Baselines are viewed from a temporary copy, which shouldn't be loaded next time
+	if name, source := currentProject.Baseline(); currentProject.ReadOnly() {
+		window.SetWindowTitle(fmt.Sprintf("%v [%v, baseline %v] (read-only) - OpenRQ",
+			currentProject.Data().ProjectName(), source, name))
+		return
+	}
+	abs, err := filepath.Abs(currentProject.FilePath())
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "warning: failed to get absolute path to project:", err)
-		abs = currentProject.path
+		abs = currentProject.FilePath()
 	}
 	window.SetWindowTitle(fmt.Sprintf("%v [%v] - OpenRQ", currentProject.Data().ProjectName(), abs))
 	// Update last used project
@@ -166,16 +186,22 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 	openItems = make(map[Item]*widgets.QDockWidget)
 
 	// Check if we have a last loaded project
-	if project := NewSettings().LastProject(); project != "" {
-		NewProject(project)
+	project := NewSettings().LastProject()
+	if project != "" {
+		if err := LoadProjectFile(window, project); err != nil {
+			fmt.Fprintln(os.Stderr, "warning: failed to load last project:", err)
+			project = ""
+		}
+	}
+	if project != "" {
 		ReloadProject(window)
 	} else {
 		// No recent project, show message
 		font := gui.NewQFont()
 		font.SetPointSize(18)
 		text := scene.AddText("No Project Loaded", font)
-		text.SetX(float64(view.Width() / 2) + (scene.Width() / 2.0))
-		text.SetY(float64(view.Height() / 2) + scene.Height())
+		text.SetX(float64(view.Width()/2) + (scene.Width() / 2.0))
+		text.SetY(float64(view.Height()/2) + scene.Height())
 		scene.SetSceneRect2(0, 0, float64(view.Width()), float64(view.Height()))
 		view.SetEnabled(false)
 	}
@@ -183,13 +209,24 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 	// Setup drag-and-drop
 	view.SetAcceptDrops(true)
 	view.SetAlignment(core.Qt__AlignTop | core.Qt__AlignLeft)
+	// Accepts new items from the shape list, and files to attach
+	canDrop := func(event *gui.QDropEvent) bool {
+		return ((event.Source() != nil && event.Source().IsWidgetType()) || event.MimeData().HasUrls()) &&
+			!currentProject.ReadOnly()
+	}
+	view.ConnectDragEnterEvent(func(event *gui.QDragEnterEvent) {
+		if canDrop(event.QDropEvent_PTR()) {
+			event.AcceptProposedAction()
+		}
+	})
 	view.ConnectDragMoveEvent(func(event *gui.QDragMoveEvent) {
-		if event.Source() != nil && event.Source().IsWidgetType() {
+		if canDrop(event.QDropEvent_PTR()) {
 			event.AcceptProposedAction()
 		}
 	})
-	// What item we're currently moving, if any
+	// What item we're currently moving, if any, and where it was before
 	var movingItem *widgets.QGraphicsItemGroup
+	var movingFromX, movingFromY int
 	// Start position of link
 	var linkStart *widgets.QGraphicsItemGroup
 	// Temporary line shown when creating a new link
@@ -197,28 +234,59 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 
 	itemSize := 64
 	view.ConnectDropEvent(func(event *gui.QDropEvent) {
+		// Files dropped on an item are attached to it
+		if event.MimeData().HasUrls() {
+			if group := view.ItemAt(event.Pos()).Group(); group != nil && group.Type() != 0 {
+				files, err := ReadFiles(event.MimeData().Urls())
+				if err == nil {
+					err = AttachFiles(group, files)
+				}
+				if err != nil {
+					widgets.QMessageBox_Warning(window, "Failed to Add Attachment", err.Error(),
+						widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+				}
+			}
+			return
+		}
 		pos := view.MapToScene(event.Pos())
 
-		// Add item to database
+		// Snap to grid
+		gridPos := SnapToGrid(pos.ToPoint())
+		// Add item to database, with size and position
 		// For now, we assume all items are requirements
 		db := currentProject.Data()
-		defer db.Close()
-		uid, err := db.AddEmptyRequirement()
+		var req Requirement
+		err := db.Transaction(func() error {
+			uid, err := db.AddEmptyRequirement()
+			if err != nil {
+				return err
+			}
+			req = NewRequirement(uid)
+			return db.SetItemValues(uid, "Requirements", map[string]interface{}{
+				"x":      gridPos.X(),
+				"y":      gridPos.Y(),
+				"width":  itemSize * 2,
+				"height": itemSize,
+			})
+		})
 		if err != nil {
 			widgets.QMessageBox_Warning(
 				window, "Failed to add item", err.Error(),
 				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
 			return
 		}
-		// Snap to grid
-		gridPos := SnapToGrid(pos.ToPoint())
-		// Set size and position
-		// All items are requirements by default
-		req := NewRequirement(uid)
-		req.SetPos(gridPos.Y(), gridPos.Y())
-		req.SetSize(itemSize*2, itemSize)
-		// Add item to view
-		scene.AddItem(NewGraphicsItem(req.Description(), gridPos.X(), gridPos.Y(), itemSize*2, itemSize, req))
+		// Allow undoing adding the item
+		snapshot, err := db.Snapshot(req)
+		if err == nil {
+			currentHistory.Record(NewAddItemCommand(snapshot))
+		} else {
+			fmt.Fprintln(os.Stderr, "warning: failed to add item to history:", err)
+		}
+		// Add item to graph and view
+		currentGraph.AddItem(req, req.Description())
+		group := NewGraphicsItem(req.Description(), gridPos.X(), gridPos.Y(), itemSize*2, itemSize, req)
+		ShowLabels(group, snapshot.UID, nil)
+		scene.AddItem(group)
 		if len(openItems) <= 0 {
 			openItems[req], _ = CreateEditWidgetFromPos(event.Pos(), scene)
 			window.AddDockWidget(core.Qt__RightDockWidgetArea, openItems[req])
@@ -226,7 +294,8 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 	})
 
 	view.ConnectMousePressEvent(func(event *gui.QMouseEvent) {
-		if event.Button() != core.Qt__LeftButton {
+		// Baselines can't be changed
+		if event.Button() != core.Qt__LeftButton || currentProject.ReadOnly() {
 			return
 		}
 		item := view.ItemAt(event.Pos())
@@ -244,6 +313,7 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 				// We're moving an item
 				movingItem = item.Group()
 				movingItem.SetOpacity(0.6)
+				movingFromX, movingFromY = int(movingItem.Pos().X()), int(movingItem.Pos().Y())
 			}
 		}
 	})
@@ -274,6 +344,9 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 		}
 	})
 	view.ConnectMouseReleaseEvent(func(event *gui.QMouseEvent) {
+		if currentProject.ReadOnly() {
+			return
+		}
 		if event.Button() == core.Qt__RightButton && view.ItemAt(event.Pos()).Group() != nil {
 			pos := event.Pos()
 			menu := widgets.NewQMenu(nil)
@@ -289,8 +362,15 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 					for _, itemLinks := range links {
 						for _, link := range itemLinks {
 							if link.child == childItem {
-								// Remove from database
-								childItem.SetParent(nil)
+								// Remove from database, if it was saved there
+								if childItem.Parent() == link.parent {
+									err := currentHistory.Do(currentProject.Data(),
+										NewRemoveLinkCommand(link.parent.UID(), childItem.UID()))
+									if err != nil {
+										fmt.Println("error:", err)
+										return
+									}
+								}
 								// Remove from graphics scene
 								scene.RemoveItem(link.line)
 								scene.RemoveItem(link.dir)
@@ -312,15 +392,40 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 						window.AddDockWidget(core.Qt__RightDockWidgetArea, editWidget)
 					}
 				})
+			// Labels, if any
+			menu.AddMenu(CreateLabelMenu(window, group))
+			// Attach files or an image from the clipboard
+			menu.AddAction("Paste Attachment").ConnectTriggered(func(checked bool) {
+				files, err := ClipboardFiles()
+				if err == nil {
+					err = AttachFiles(group, files)
+				}
+				if err != nil {
+					widgets.QMessageBox_Warning(window, "Failed to Add Attachment", err.Error(),
+						widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+				}
+			})
+			// Diagram of the item and everything below it
+			menu.AddAction("Export Diagram...").ConnectTriggered(func(checked bool) {
+				ExportDiagram(window, GetGroupItem(group))
+			})
 			// Delete option
 			menu.AddAction2(GetIcon("menu-delete"), "Delete").
 				ConnectTriggered(func(checked bool) {
 					// Connect to database
 					db := currentProject.Data()
-					defer db.Close()
 					// Get the clicked item
 					group := view.ItemAt(pos).Group()
 					item := GetGroupItem(group)
+					// Remove the item, and links to its children, from the database
+					snapshot, err := db.Snapshot(item)
+					if err == nil {
+						err = currentHistory.Do(db, NewRemoveItemCommand(snapshot))
+					}
+					if err != nil {
+						fmt.Println("error: failed to remove item:", err)
+						return
+					}
 					// Try to get all links
 					link, ok := links[item]
 					if ok {
@@ -336,15 +441,9 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 							}
 						}
 					}
-					// Remove the group from the scene
+					// Remove the group from the scene and graph
 					scene.RemoveItem(group)
-					// Remove the links and the item itself from the database
-					if err := db.RemoveChildrenLinks(item); err != nil {
-						fmt.Println("warning: failed to remove children links:", err)
-					}
-					if err := db.RemoveItem(item); err != nil {
-						fmt.Println("warning: failed to remove item:", err)
-					}
+					currentGraph.RemoveItem(item)
 					// Check if item is opened in editor
 					if openItem, ok := openItems[item]; ok {
 						openItem.Close()
@@ -362,10 +461,17 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 			// Update link if needed
 			// Error handling is already taken care of in UpdateLinkPos
 			UpdateLinkPos(movingItem, pos.X(), pos.Y())
-			// Update position in database
-			GetGroupItem(movingItem).SetPos(int(pos.X()), int(pos.Y()))
-			// Reset opacity and remove as moving
-			movingItem.SetOpacity(1.0)
+			// Update position in database, if it was moved
+			x, y := int(pos.X()), int(pos.Y())
+			if x != movingFromX || y != movingFromY {
+				err := currentHistory.Do(currentProject.Data(),
+					NewMoveItemCommand(GetGroupItem(movingItem).UID(), movingFromX, movingFromY, x, y))
+				if err != nil {
+					fmt.Println("error: failed to move item:", err)
+				}
+			}
+			// Reset opacity, or dim if filtered, and remove as moving
+			movingItem.SetOpacity(LabelOpacity(movingItem.Data(dataItemUID).ToLongLong(nil)))
 			movingItem = nil
 		}
 		// When releasing, we always want to destroy temp link
@@ -398,11 +504,10 @@ func CreateView(window *widgets.QMainWindow, linkBtn *widgets.QToolButton) *widg
 			// Check if child already have a parent and add to db if it doesn't have one
 			if !GetGroupItem(group).IsPropertyNull("parent") {
 				fmt.Println("warning: child already has a parent")
-			} else if err := db.AddItemChild(linkStartItem, groupItem); err != nil {
+			} else if err := currentHistory.Do(db,
+				NewAddLinkCommand(linkStartItem.UID(), groupItem.UID())); err != nil {
 				fmt.Println("error: failed to add link to database:", err)
 			}
-			// We're done
-			db.Close()
 		}
 	})
 	return view
@@ -455,6 +560,8 @@ func CreateLink(parent, child *widgets.QGraphicsItemGroup) Link {
 	// Save in links map
 	links[parentItem] = append(links[parentItem], &lineData)
 	links[childItem] = append(links[childItem], &lineData)
+	// Save in graph
+	currentGraph.AddLink(parentItem, childItem)
 	// Return the graphics line to add to scene
 	return lineData
 }
@@ -486,12 +593,14 @@ func UpdateLinkPos(item *widgets.QGraphicsItemGroup, x, y float64) {
 }
 
 func RemoveLink(link *Link) {
+	// Remove from graph
+	currentGraph.RemoveLink(link.parent, link.child)
 	// Remove from child
 	delete(links, link.child)
 	// Remove from parent
 	for i, childLink := range links[link.parent] {
 		if childLink.child == link.child {
-			last := len(links[link.parent])-1
+			last := len(links[link.parent]) - 1
 			// Replace entry to delete with last
 			links[link.parent][i] = links[link.parent][last]
 			// Cut away last element
@@ -539,14 +648,8 @@ func NewGraphicsItem(text string, x, y, width, height int, item Item) *widgets.Q
 	textItem.SetTextWidth(float64(width))
 	shapeItem := widgets.NewQGraphicsRectItem3(0, 0, float64(width), float64(height), nil)
 	shapeItem.SetBrush(gui.NewQBrush3(backgroundColor, 1))
-	// Default purple color for requirements
-	var penColor uint = 10233776
-	// Blue color for solutions
-	if GetItemType(item) == TypeSolution {
-		penColor = 2201331
-	}
-	// Set opacity of border color
-	color := gui.NewQColor4(penColor)
+	// Purple color for requirements, and blue for solutions, with some opacity
+	color := gui.NewQColor4(GetItemColor(item))
 	color.SetAlpha(200)
 	shapeItem.SetPen(gui.NewQPen3(color))
 	group.AddToGroup(textItem)
@@ -576,37 +679,3 @@ func CreateTriangle(pos *core.QPointF, angle float64) *widgets.QGraphicsPolygonI
 	poly.SetRotation((-angle) - 90)
 	return poly
 }
-
-func Roots() []Item {
-	// Final tree
-	roots := make([]Item, 0)
-	// Items we have already added
-	added := map[Item]int{}
-	// Loop through all items in view
-	for _, item := range view.Items() {
-		// Get group and make sure it's valid
-		group := item.Group()
-		if group == nil || group.Type() == 0 {
-			continue
-		}
-		// Get item
-		groupItem := GetGroupItem(group)
-		// Ignore if already added
-		if ContainsItem(added, groupItem) {
-			continue
-		}
-		added[groupItem] = 0
-
-		isRoot := true
-		for _, l2 := range links[groupItem] {
-			if l2.child == groupItem {
-				isRoot = false
-				break
-			}
-		}
-		if isRoot {
-			roots = append(roots, groupItem)
-		}
-	}
-	return roots
-}
\ No newline at end of file
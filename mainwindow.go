@@ -1,9 +1,10 @@
+//go:build !headless
+// +build !headless
+
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -31,13 +32,245 @@ func NewMainWindow() (*widgets.QApplication, *widgets.QMainWindow) {
 		gui.QGuiApplication_Screens()[0].AvailableGeometry()))
 	// Set a window title
 	window.SetWindowTitle("OpenRQ")
+	// Save compressed projects before closing, keeping the working copy if it fails
+	window.ConnectCloseEvent(func(event *gui.QCloseEvent) {
+		if currentProject != nil {
+			if err := currentProject.Save(); err != nil && widgets.QMessageBox_Question(window,
+				"Failed to Save Project", fmt.Sprintf("%v\n\nClose anyway? "+
+					"Changes can be recovered the next time the project is opened.", err),
+				widgets.QMessageBox__Yes|widgets.QMessageBox__No, widgets.QMessageBox__No) != widgets.QMessageBox__Yes {
+				event.Ignore()
+				return
+			}
+		}
+		event.Accept()
+	})
 	// Return app and window for use in the main function
 	return app, window
 }
 
+// ChooseBaseline asks the user to select one of the baselines in the current project
+func ChooseBaseline(window *widgets.QMainWindow, title string) (Baseline, bool) {
+	baselines, err := currentProject.Data().Baselines()
+	if err != nil {
+		widgets.QMessageBox_Critical(window, title, err.Error(),
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return Baseline{}, false
+	}
+	if len(baselines) == 0 {
+		widgets.QMessageBox_Information(window, title, "The project doesn't have any baselines yet",
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return Baseline{}, false
+	}
+	names := make([]string, len(baselines))
+	for i, baseline := range baselines {
+		names[i] = fmt.Sprintf("%v (%v)", baseline.Name, baseline.Created)
+	}
+	ok := false
+	name := widgets.QInputDialog_GetItem(window, title, "Baseline", names, len(names)-1, false, &ok, 0, 0)
+	for i := range names {
+		if ok && names[i] == name {
+			return baselines[i], true
+		}
+	}
+	return Baseline{}, false
+}
+
+// LoadProjectFile opens an .orq or .orqz project, asking if changes in compressed projects that weren't saved,
+// because OpenRQ didn't close properly, should be recovered
+func LoadProjectFile(window *widgets.QMainWindow, fileName string) error {
+	if !strings.HasSuffix(fileName, ".orqz") {
+		_, err := NewProject(fileName)
+		return err
+	}
+	recover, err := CompressedRecovery(fileName)
+	if err != nil {
+		return err
+	}
+	if recover {
+		result := widgets.QMessageBox_Question(window, "Recover Changes",
+			"The project was not closed properly the last time it was open, "+
+				"and has changes that were not saved to it.\n"+
+				"Do you want to recover them?",
+			widgets.QMessageBox__Yes|widgets.QMessageBox__No, widgets.QMessageBox__Yes)
+		recover = result == widgets.QMessageBox__Yes
+	}
+	// Load compressed project, through a working copy saved back to it
+	_, err = NewCompressedProject(fileName, recover)
+	return err
+}
+
+// CloseBaseline goes back to the project the current baseline is from, if viewing one
+func CloseBaseline(window *widgets.QMainWindow) bool {
+	if currentProject == nil || !currentProject.ReadOnly() {
+		return true
+	}
+	_, source := currentProject.Baseline()
+	if err := LoadProjectFile(window, source); err != nil {
+		widgets.QMessageBox_Critical(window, "Failed to Load Project", err.Error(),
+			widgets.QMessageBox__Ok, widgets.QMessageBox__Ok)
+		return false
+	}
+	ReloadProject(window)
+	return true
+}
+
+// CreateBaselineMenu creates the menu for freezing, viewing and branching from baselines
+func CreateBaselineMenu(window *widgets.QMainWindow) *widgets.QMenu {
+	menu := widgets.NewQMenu2("Baselines", nil)
+	// Freeze
+	menu.AddAction("Freeze Baseline...").ConnectTriggered(func(checked bool) {
+		if currentProject == nil || currentProject.ReadOnly() {
+			widgets.QMessageBox_Information(window, "Freeze Baseline",
+				"Open a project to freeze a baseline of it", widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+		name := widgets.QInputDialog_GetText(window, "Freeze Baseline", "Baseline name",
+			widgets.QLineEdit__Normal, "", nil, 0, 0)
+		if len(name) <= 0 {
+			return
+		}
+		baseline, err := currentProject.Data().CreateBaseline(name)
+		if err != nil {
+			widgets.QMessageBox_Critical(window, "Failed to Freeze Baseline", err.Error(),
+				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+		widgets.QMessageBox_Information(window, "Freeze Baseline",
+			fmt.Sprintf("Froze %v items as %v", baseline.Items, baseline.Name),
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+	})
+	// Open read-only
+	menu.AddAction("Open Baseline...").ConnectTriggered(func(checked bool) {
+		if currentProject == nil || !CloseBaseline(window) {
+			return
+		}
+		baseline, ok := ChooseBaseline(window, "Open Baseline")
+		if !ok {
+			return
+		}
+		if _, err := currentProject.OpenBaseline(baseline); err != nil {
+			widgets.QMessageBox_Critical(window, "Failed to Open Baseline", err.Error(),
+				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+		ReloadProject(window)
+	})
+	// Branch
+	menu.AddAction("Branch from Baseline...").ConnectTriggered(func(checked bool) {
+		if currentProject == nil || !CloseBaseline(window) {
+			return
+		}
+		baseline, ok := ChooseBaseline(window, "Branch from Baseline")
+		if !ok {
+			return
+		}
+		fileName := widgets.QFileDialog_GetSaveFileName(window, "Branch Project",
+			filepath.Dir(currentProject.FilePath()), "OpenRQ Project(*.orq)", "", 0)
+		if len(fileName) <= 0 {
+			return
+		}
+		if !strings.HasSuffix(fileName, ".orq") {
+			fileName += ".orq"
+		}
+		if err := currentProject.Branch(baseline, fileName); err != nil {
+			widgets.QMessageBox_Critical(window, "Failed to Branch Project", err.Error(),
+				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+		// Continue working in the new branch
+		if _, err := NewProject(fileName); err != nil {
+			widgets.QMessageBox_Critical(window, "Failed to Load Project", err.Error(),
+				widgets.QMessageBox__Ok, widgets.QMessageBox__Ok)
+			return
+		}
+		ReloadProject(window)
+	})
+	// Close
+	menu.AddAction("Close Baseline").ConnectTriggered(func(checked bool) {
+		CloseBaseline(window)
+	})
+	return menu
+}
+
 // Temporary global pointer to the validation engine window for the hide/show button
 var dockValidation *widgets.QDockWidget
 
+// ExportDiagram asks where to export a diagram of the items below root, or all items if root is nil
+func ExportDiagram(window *widgets.QMainWindow, root Item) {
+	if currentProject == nil {
+		widgets.QMessageBox_Information(window, "No Project Loaded",
+			"No project is current loaded to export", widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	fileName := widgets.QFileDialog_GetSaveFileName(window, "Export Diagram",
+		filepath.Dir(currentProject.FilePath()),
+		"Graphviz(*.dot);;Mermaid(*.mmd);;PlantUML(*.puml)", "", 0)
+	if len(fileName) <= 0 {
+		return
+	}
+	diagram, err := currentProject.Data().Diagram(root)
+	if err == nil {
+		err = diagram.Export(fileName)
+	}
+	if err != nil {
+		widgets.QMessageBox_Critical(window, "Failed to Export Diagram",
+			err.Error(), widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+	}
+}
+
+// ImportOutline adds or updates items from a markdown outline, writing uids of new items back to it
+func ImportOutline(window *widgets.QMainWindow) {
+	if currentProject == nil || currentProject.ReadOnly() {
+		widgets.QMessageBox_Information(window, "No Project Loaded",
+			"No project is currently loaded to import into", widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	fileName := widgets.QFileDialog_GetOpenFileName(window, "Import Outline",
+		filepath.Dir(currentProject.FilePath()), "Markdown(*.md *.markdown *.txt)", "", 0)
+	if len(fileName) <= 0 {
+		return
+	}
+	db := currentProject.Data()
+	var outlineImport *OutlineImport
+	file, err := os.Open(fileName)
+	if err == nil {
+		var outline *Outline
+		outline, err = ParseOutline(file)
+		file.Close()
+		if err == nil {
+			outlineImport, err = db.PlanOutlineImport(outline)
+		}
+	}
+	if err != nil {
+		widgets.QMessageBox_Critical(window, "Failed to Import Outline",
+			err.Error(), widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	added, updated := outlineImport.Count(OutlineAdd), outlineImport.Count(OutlineUpdate)
+	if widgets.QMessageBox_Question(window, "Import Outline",
+		fmt.Sprintf("Add %v and update %v items from \"%v\"? Uids of new items are written to the outline.",
+			added, updated, filepath.Base(fileName)),
+		widgets.QMessageBox__Yes|widgets.QMessageBox__No, widgets.QMessageBox__Yes) != widgets.QMessageBox__Yes {
+		return
+	}
+	if command := outlineImport.Command(); command != nil {
+		if err := currentHistory.Do(db, command); err != nil {
+			widgets.QMessageBox_Critical(window, "Failed to Import Outline",
+				err.Error(), widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+		ReloadProject(window)
+	}
+	if added > 0 {
+		if err := outlineImport.Outline.WriteFile(fileName); err != nil {
+			widgets.QMessageBox_Warning(window, "Failed to Write Outline",
+				fmt.Sprintf("Items were imported, but uids could not be written to the outline: %v", err),
+				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		}
+	}
+}
+
 func AddMenuBar(window *widgets.QMainWindow) {
 	// Main menu bar
 	menuBar := window.MenuBar()
@@ -52,7 +285,11 @@ func AddMenuBar(window *widgets.QMainWindow) {
 			core.QStandardPaths_Locate(core.QStandardPaths__DocumentsLocation, "", 1),
 			"OpenRQ Project(*.orq)", "", 0)
 		if len(fileName) > 0 {
-			NewProject(fileName)
+			if _, err := NewProject(fileName); err != nil {
+				widgets.QMessageBox_Critical(window, "Failed to Create Project", err.Error(),
+					widgets.QMessageBox__Ok, widgets.QMessageBox__Ok)
+				return
+			}
 			ReloadProject(window)
 		}
 	})
@@ -62,45 +299,63 @@ func AddMenuBar(window *widgets.QMainWindow) {
 	fileOpen.ConnectTriggered(func(checked bool) {
 		fileName := widgets.QFileDialog_GetOpenFileName(window, "Open Project",
 			core.QStandardPaths_Locate(core.QStandardPaths__DocumentsLocation, "", 1),
-			"OpenRQ Project(*.orq *.orqz);;JavaScript Object Notation(*.json)", "", 0)
+			"OpenRQ Project(*.orq *.orqz);;JavaScript Object Notation(*.json);;Requirements Interchange Format(*.reqif)",
+			"", 0)
 		if len(fileName) > 0 {
-			if strings.HasSuffix(fileName, ".orqz") {
-				result := widgets.QMessageBox_Question(window, "Compressed Project",
-					"The project you are trying to load is compressed. "+
-						"In order to load the project, it first needs to be decompressed. "+
-						"This will replace the compressed project with a decompressed project.\n"+
-						"Are you sure you want to continue?",
-					widgets.QMessageBox__Yes|widgets.QMessageBox__No, widgets.QMessageBox__Yes)
-				if result == widgets.QMessageBox__No {
-					return
-				}
-				// Load compressed project
-				if _, err := NewCompressedProject(fileName); err != nil {
-					widgets.QMessageBox_Critical(window, "Failed to Load Project", err.Error(),
-						widgets.QMessageBox__Ok, widgets.QMessageBox__Ok)
+			if strings.HasSuffix(fileName, ".json") || strings.HasSuffix(fileName, ".reqif") {
+				// JSON is checked before asking, so nothing is created from an invalid file
+				message := "The project you are trying to load needs to be converted before it can be opened. " +
+					"Changes made to the converted project will not affect the original document " +
+					"unless manually saved. Are you sure you want to continue?"
+				var jsonImport *JSONImport
+				if strings.HasSuffix(fileName, ".json") {
+					var err error
+					if jsonImport, err = ReadJSONFile(fileName); err != nil {
+						widgets.QMessageBox_Critical(window, "Failed to Load Project",
+							fmt.Sprintf("The project is not valid and can't be converted:\n%v", err),
+							widgets.QMessageBox__Ok, widgets.QMessageBox__Ok)
+						return
+					}
+					message = fmt.Sprintf("%v\n\nThe project contains %v.", message, jsonImport.Summary())
 				}
-			} else if strings.HasSuffix(fileName, ".json") {
 				// Ask to open
-				result := widgets.QMessageBox_Question(window, "Convert Project",
-					"The project you are trying to load needs to be converted before it can be opened. " +
-						"Changes made to the converted project will not affect the original document " +
-						"unless manually saved. Are you sure you want to continue?",
+				result := widgets.QMessageBox_Question(window, "Convert Project", message,
 					widgets.QMessageBox__Yes|widgets.QMessageBox__No, widgets.QMessageBox__Yes)
 				if result == widgets.QMessageBox__No {
 					return
 				}
-				// Parse JSON
-				if _, err := NewJSONProject(fileName); err != nil {
+				// Parse JSON or ReqIF, creating a project next to it
+				var err error
+				if strings.HasSuffix(fileName, ".reqif") {
+					_, err = ImportReqIF(fileName, strings.TrimSuffix(fileName, ".reqif")+".orq")
+				} else {
+					_, err = jsonImport.CreateProject(strings.TrimSuffix(fileName, ".json") + ".orq")
+				}
+				if err != nil {
 					widgets.QMessageBox_Critical(window, "Failed to Load Project", err.Error(),
 						widgets.QMessageBox__Ok, widgets.QMessageBox__Ok)
+					return
 				}
-			} else {
-				// If not compressed, just load normal project
-				NewProject(fileName)
+			} else if err := LoadProjectFile(window, fileName); err != nil {
+				widgets.QMessageBox_Critical(window, "Failed to Load Project", err.Error(),
+					widgets.QMessageBox__Ok, widgets.QMessageBox__Ok)
+				return
 			}
 			ReloadProject(window)
 		}
 	})
+	// Save, only needed for compressed projects, as changes are saved when made
+	fileSave := fileMenu.AddAction("Save")
+	fileSave.SetShortcut(gui.NewQKeySequence5(gui.QKeySequence__Save))
+	fileSave.ConnectTriggered(func(checked bool) {
+		if currentProject == nil {
+			return
+		}
+		if err := currentProject.Save(); err != nil {
+			widgets.QMessageBox_Critical(window, "Failed to Save Project",
+				err.Error(), widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		}
+	})
 	// Save as
 	fileSaveAs := fileMenu.AddAction2(GetIcon("file-save-as"), "Save As...")
 	fileSaveAs.SetShortcut(gui.NewQKeySequence5(gui.QKeySequence__SaveAs))
@@ -112,20 +367,20 @@ func AddMenuBar(window *widgets.QMainWindow) {
 			return
 		}
 		fileName := widgets.QFileDialog_GetSaveFileName(window, "Save Project",
-			filepath.Dir(currentProject.path),
-			"OpenRQ Project(*.orq);;OpenRQ Compressed Project(*.orqz);;JavaScript Object Notation(*.json)",
-			"", 0)
+			filepath.Dir(currentProject.FilePath()),
+			"OpenRQ Project(*.orq);;OpenRQ Compressed Project(*.orqz);;JavaScript Object Notation(*.json);;"+
+				"Requirements Interchange Format(*.reqif)", "", 0)
 		if len(fileName) > 0 {
 			if strings.HasSuffix(fileName, ".json") {
-				if data, err := json.MarshalIndent(map[string]interface{}{
-					"ProjectName": currentProject.Name(),
-					"Tree": Roots(),
-				}, "", "\t"); err != nil {
+				if err := currentProject.SaveJSON(fileName, currentGraph); err != nil {
 					fmt.Println(err)
-				} else {
-					if err := ioutil.WriteFile(fileName, data, 0644); err != nil {
-						fmt.Println(err)
-					}
+				}
+				return
+			}
+			if strings.HasSuffix(fileName, ".reqif") {
+				if err := currentProject.ExportReqIF(fileName); err != nil {
+					widgets.QMessageBox_Critical(window, "Failed to Export Project",
+						err.Error(), widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
 				}
 				return
 			}
@@ -135,6 +390,94 @@ func AddMenuBar(window *widgets.QMainWindow) {
 			}
 		}
 	})
+	// Traceability matrix
+	fileMenu.AddAction("Export Traceability...").ConnectTriggered(func(checked bool) {
+		if currentProject == nil {
+			widgets.QMessageBox_Information(window, "No Project Loaded",
+				"No project is current loaded to export", widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+		fileName := widgets.QFileDialog_GetSaveFileName(window, "Export Traceability",
+			filepath.Dir(currentProject.FilePath()),
+			"Comma-Separated Values(*.csv);;Web Page(*.html);;Excel Workbook(*.xlsx)", "", 0)
+		if len(fileName) <= 0 {
+			return
+		}
+		trace, err := currentProject.Data().Traceability()
+		if err == nil {
+			err = trace.Export(fileName, currentProject.Data().ProjectName())
+		}
+		if err != nil {
+			widgets.QMessageBox_Critical(window, "Failed to Export Traceability",
+				err.Error(), widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		}
+	})
+	// Specification document
+	fileMenu.AddAction("Export Specification...").ConnectTriggered(func(checked bool) {
+		if currentProject == nil {
+			widgets.QMessageBox_Information(window, "No Project Loaded",
+				"No project is current loaded to export", widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+		fileName := widgets.QFileDialog_GetSaveFileName(window, "Export Specification",
+			filepath.Dir(currentProject.FilePath()),
+			"Markdown(*.md);;Web Page(*.html);;Portable Document Format(*.pdf)", "", 0)
+		if len(fileName) <= 0 {
+			return
+		}
+		spec, err := currentProject.Data().Specification()
+		if err == nil {
+			err = spec.Export(fileName)
+		}
+		if err != nil {
+			widgets.QMessageBox_Critical(window, "Failed to Export Specification",
+				err.Error(), widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		}
+	})
+	// Diagram of the whole project
+	fileMenu.AddAction("Export Diagram...").ConnectTriggered(func(checked bool) {
+		ExportDiagram(window, nil)
+	})
+	// Importing items from other files
+	fileMenu.AddSeparator()
+	fileMenu.AddAction("Import into Project...").ConnectTriggered(func(checked bool) {
+		ImportTree(window)
+	})
+	fileMenu.AddAction("Import CSV...").ConnectTriggered(func(checked bool) {
+		ImportCSV(window)
+	})
+	fileMenu.AddAction("Import Outline...").ConnectTriggered(func(checked bool) {
+		ImportOutline(window)
+	})
+	fileMenu.AddAction("Export Outline...").ConnectTriggered(func(checked bool) {
+		if currentProject == nil {
+			widgets.QMessageBox_Information(window, "No Project Loaded",
+				"No project is current loaded to export", widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+		fileName := widgets.QFileDialog_GetSaveFileName(window, "Export Outline",
+			filepath.Dir(currentProject.FilePath()), "Markdown(*.md)", "", 0)
+		if len(fileName) <= 0 {
+			return
+		}
+		file, err := os.Create(fileName)
+		if err == nil {
+			err = currentProject.Data().WriteOutline(file)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			widgets.QMessageBox_Critical(window, "Failed to Export Outline",
+				err.Error(), widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		}
+	})
+	// Baselines and merging
+	fileMenu.AddSeparator()
+	fileMenu.AddMenu(CreateBaselineMenu(window))
+	fileMenu.AddAction("Merge...").ConnectTriggered(func(checked bool) {
+		MergeProject(window)
+	})
 	// Quit
 	fileMenu.AddSeparator()
 	fileQuit := fileMenu.AddAction2(GetIcon("file-quit"), "Quit")
@@ -146,6 +489,30 @@ func AddMenuBar(window *widgets.QMainWindow) {
 
 	// Edit menu
 	editMenu := widgets.NewQMenu2("Edit", nil)
+	// Undo and redo
+	editUndo := editMenu.AddAction("Undo")
+	editUndo.SetShortcut(gui.NewQKeySequence5(gui.QKeySequence__Undo))
+	editUndo.ConnectTriggered(func(checked bool) {
+		UndoCommand(window, false)
+	})
+	editRedo := editMenu.AddAction("Redo")
+	editRedo.SetShortcut(gui.NewQKeySequence2("Ctrl+Shift+Z", gui.QKeySequence__PortableText))
+	editRedo.ConnectTriggered(func(checked bool) {
+		UndoCommand(window, true)
+	})
+	// Show what will be undone or redone when opening the menu
+	editMenu.ConnectAboutToShow(func() {
+		editUndo.SetText(strings.TrimSpace("Undo " + currentHistory.UndoText()))
+		editUndo.SetEnabled(currentHistory.CanUndo())
+		editRedo.SetText(strings.TrimSpace("Redo " + currentHistory.RedoText()))
+		editRedo.SetEnabled(currentHistory.CanRedo())
+	})
+	// Shortcuts should always work, even if the menu was disabled last time
+	editMenu.ConnectAboutToHide(func() {
+		editUndo.SetEnabled(true)
+		editRedo.SetEnabled(true)
+	})
+	editMenu.AddSeparator()
 	editMenu.AddAction2(GetIcon("edit-rename"),
 		"Rename Project...").ConnectTriggered(func(checked bool) {
 		// Check if project is loaded to rename
@@ -154,8 +521,12 @@ func AddMenuBar(window *widgets.QMainWindow) {
 				"No project is current loaded to rename", widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
 			return
 		}
+		if currentProject.ReadOnly() {
+			widgets.QMessageBox_Information(window, "Read-Only Project",
+				"Baselines can't be renamed", widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
 		db := currentProject.Data()
-		defer db.Close()
 		name := widgets.QInputDialog_GetText(window, "Rename Project", "New project name",
 			widgets.QLineEdit__Normal, db.ProjectName(), nil, 0, 0)
 		if len(name) > 0 {
@@ -181,23 +552,24 @@ func AddMenuBar(window *widgets.QMainWindow) {
 			dockValidation.Hide()
 		}
 	})
+	viewMenu.AddMenu(CreateDiffMenu(window))
 	menuBar.AddMenu(viewMenu)
 
 	// About
 	aboutMenu := widgets.NewQMenu2("About", nil)
 	aboutMenu.AddAction2(
 		GetIcon("about-app"), "About OpenRQ").ConnectTriggered(func(checked bool) {
-			// Add app version information
-			aboutMessage := fmt.Sprintf("Version %v", versionTagName[1:])
-			// Add useless version and memory information
-			var mem runtime.MemStats
-			runtime.ReadMemStats(&mem)
-			aboutMessage += fmt.Sprintf("\n\nQt %v, Go %v, %v\nMemory usage: %.2f mb (%.2f mb allocated)",
-				core.QLibraryInfo_Version().ToString(), runtime.Version()[2:], runtime.GOARCH,
-				float64(mem.TotalAlloc)/1000000, float64(mem.Sys)/1000000)
-			// Show simple dialog for now
-			widgets.QMessageBox_About(window, "About OpenRQ", aboutMessage)
-		})
+		// Add app version information
+		aboutMessage := fmt.Sprintf("Version %v", versionTagName[1:])
+		// Add useless version and memory information
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		aboutMessage += fmt.Sprintf("\n\nQt %v, Go %v, %v\nMemory usage: %.2f mb (%.2f mb allocated)",
+			core.QLibraryInfo_Version().ToString(), runtime.Version()[2:], runtime.GOARCH,
+			float64(mem.TotalAlloc)/1000000, float64(mem.Sys)/1000000)
+		// Show simple dialog for now
+		widgets.QMessageBox_About(window, "About OpenRQ", aboutMessage)
+	})
 	aboutMenu.AddAction2(GetIcon("about-qt"), "About Qt").ConnectTriggered(func(checked bool) {
 		widgets.QMessageBox_AboutQt(window, "About Qt")
 	})
@@ -208,27 +580,27 @@ func AddMenuBar(window *widgets.QMainWindow) {
 	aboutMenu.AddSeparator()
 	aboutMenu.AddAction2(
 		GetIcon("about-update"), "Check for updates").ConnectTriggered(func(checked bool) {
-			// Actually check for updates
-			if isLatest, err := IsLatestVersion(); isLatest {
-				widgets.QMessageBox_Information(
-					window, "Updater", "You are running the latest version",
-					widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
-				return
-			} else if err != nil {
-				widgets.QMessageBox_Warning(
-					window, "Updater", fmt.Sprintf("Failed to check for updates: %v", err),
-					widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
-				return
-			}
-			// New update was found
-			if widgets.QMessageBox_Question(
-				window, "Updater",
-				"New update found, do you want to update now?",
-				widgets.QMessageBox__Yes|widgets.QMessageBox__No, widgets.QMessageBox__Yes) == widgets.QMessageBox__Yes {
-					gui.QDesktopServices_OpenUrl(
-						core.NewQUrl3("https://github.com/kraxarn/OpenRQ/releases", 0))
-			}
-		})
+		// Actually check for updates
+		if isLatest, err := IsLatestVersion(); isLatest {
+			widgets.QMessageBox_Information(
+				window, "Updater", "You are running the latest version",
+				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		} else if err != nil {
+			widgets.QMessageBox_Warning(
+				window, "Updater", fmt.Sprintf("Failed to check for updates: %v", err),
+				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+		// New update was found
+		if widgets.QMessageBox_Question(
+			window, "Updater",
+			"New update found, do you want to update now?",
+			widgets.QMessageBox__Yes|widgets.QMessageBox__No, widgets.QMessageBox__Yes) == widgets.QMessageBox__Yes {
+			gui.QDesktopServices_OpenUrl(
+				core.NewQUrl3("https://github.com/kraxarn/OpenRQ/releases", 0))
+		}
+	})
 	aboutMenu.AddAction2(GetIcon("about-gc"), "Run GC").ConnectTriggered(func(checked bool) {
 		// Get memory information
 		var mem runtime.MemStats
@@ -277,6 +649,12 @@ func CreateLayout(window *widgets.QMainWindow) {
 	// Hide close button as there's no reason to close it
 	dockItemShape.SetFeatures(widgets.QDockWidget__DockWidgetMovable | widgets.QDockWidget__DockWidgetFloatable)
 	window.AddDockWidget(core.Qt__LeftDockWidgetArea, dockItemShape)
+
+	// Create label dock widget, for managing and filtering by labels
+	dockLabels := widgets.NewQDockWidget("Labels", window, 0)
+	dockLabels.SetWidget(CreateLabelDock(window))
+	dockLabels.SetFeatures(widgets.QDockWidget__DockWidgetMovable | widgets.QDockWidget__DockWidgetFloatable)
+	window.AddDockWidget(core.Qt__LeftDockWidgetArea, dockLabels)
 }
 
 func CreateVBoxWidget(children ...widgets.QWidget_ITF) *widgets.QWidget {
@@ -338,4 +716,4 @@ func CreateItemShapeCreator() *widgets.QWidget {
 	shapeList.AddItem("Square")
 	layout.AddWidget(CreateVBoxWidget(shapeList), 0, 0)
 	return LayoutToWidget(layout)
-}
\ No newline at end of file
+}
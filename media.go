@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Attachment is a file attached to an item.
+// Files are stored once in Media, even if attached to several items,
+// and attached in MediaItems by item uid, like labels.
+type Attachment struct {
+	// Id of the file in Media
+	Media  int64
+	Name   string
+	Format string
+	Hash   string
+}
+
+// IsImage checks if the attachment can be shown as an image
+func (attachment Attachment) IsImage() bool {
+	return strings.HasPrefix(attachment.Format, "image/")
+}
+
+// MediaHash gets the hash used for finding files that are already stored
+func MediaHash(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// MediaFormat gets the mime type of a file, from its name or contents
+func MediaFormat(name string, content []byte) string {
+	if format := mime.TypeByExtension(filepath.Ext(name)); format != "" {
+		return format
+	}
+	return http.DetectContentType(content)
+}
+
+// AddMedia stores a file, or finds it if it's already stored, and returns its id
+func (data *DataContext) AddMedia(format string, content []byte) (int64, error) {
+	hash := MediaHash(content)
+	var id int64
+	err := data.conn().QueryRow("select _rowid_ from Media where hash = ?", hash).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+	result, err := data.conn().Exec("insert into Media (hash, format, data) values (?, ?, ?)",
+		hash, format, content)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add media: %v", err)
+	}
+	return result.LastInsertId()
+}
+
+// MediaContent gets the contents of a stored file
+func (data *DataContext) MediaContent(id int64) ([]byte, error) {
+	var content []byte
+	if err := data.conn().QueryRow("select data from Media where _rowid_ = ?", id).Scan(&content); err != nil {
+		return nil, fmt.Errorf("failed to get media: %v", err)
+	}
+	return content, nil
+}
+
+// AttachMedia stores a file and attaches it to the item with the specified uid,
+// where added is false if the item already had the file
+func (data *DataContext) AttachMedia(uid int64, itemType ItemType, name string,
+	content []byte) (Attachment, bool, error) {
+	attachment := Attachment{
+		Name:   filepath.Base(name),
+		Format: MediaFormat(name, content),
+		Hash:   MediaHash(content),
+	}
+	var err error
+	if attachment.Media, err = data.AddMedia(attachment.Format, content); err != nil {
+		return attachment, false, err
+	}
+	// Each file is only attached once to the same item
+	var count int
+	if err := data.conn().QueryRow("select count(*) from MediaItems where item = ? and media = ?",
+		uid, attachment.Media).Scan(&count); err != nil || count > 0 {
+		return attachment, false, err
+	}
+	_, err = data.conn().Exec("insert into MediaItems (media, item, type, name) values (?, ?, ?, ?)",
+		attachment.Media, uid, itemType, attachment.Name)
+	return attachment, err == nil, err
+}
+
+// DetachMedia removes a file from an item, and removes the file if no other item has it
+func (data *DataContext) DetachMedia(uid, media int64) error {
+	if _, err := data.conn().Exec("delete from MediaItems where item = ? and media = ?", uid, media); err != nil {
+		return err
+	}
+	_, err := data.conn().Exec("delete from Media where _rowid_ = ? and "+
+		"not exists (select 1 from MediaItems where media = ?)", media, media)
+	return err
+}
+
+// mediaItemsQuery gets the uid of items with each attached file
+const mediaItemsQuery = "select MediaItems.item, Media._rowid_, coalesce(MediaItems.name, ''), " +
+	"coalesce(Media.format, ''), coalesce(Media.hash, '') " +
+	"from MediaItems join Media on Media._rowid_ = MediaItems.media"
+
+// ItemMedia gets all files attached to the item with the specified uid
+func (data *DataContext) ItemMedia(uid int64) ([]Attachment, error) {
+	media, err := data.queryItemMedia(mediaItemsQuery+" where MediaItems.item = ?", uid)
+	return media[uid], err
+}
+
+// AllItemMedia gets the files attached to all items by uid, in a single query
+func (data *DataContext) AllItemMedia() (map[int64][]Attachment, error) {
+	return data.queryItemMedia(mediaItemsQuery)
+}
+
+// queryItemMedia gets attachments by item uid, in the order they were attached
+func (data *DataContext) queryItemMedia(query string, args ...interface{}) (map[int64][]Attachment, error) {
+	rows, err := data.conn().Query(query+" order by MediaItems._rowid_", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %v", err)
+	}
+	defer rows.Close()
+	media := make(map[int64][]Attachment)
+	for rows.Next() {
+		var uid int64
+		var attachment Attachment
+		if err := rows.Scan(&uid, &attachment.Media, &attachment.Name,
+			&attachment.Format, &attachment.Hash); err != nil {
+			return media, fmt.Errorf("failed to get attachment: %v", err)
+		}
+		media[uid] = append(media[uid], attachment)
+	}
+	return media, rows.Err()
+}
+
+// MediaData is an attached file as exported to json, where the contents are base64 encoded
+type MediaData struct {
+	Name   string
+	Format string
+	Data   []byte
+}
+
+// MediaJSON gets all files attached to an item as they should be exported to json
+func (data *DataContext) MediaJSON(uid int64) ([]MediaData, error) {
+	attachments, err := data.ItemMedia(uid)
+	if err != nil {
+		return nil, err
+	}
+	exported := make([]MediaData, 0, len(attachments))
+	for _, attachment := range attachments {
+		content, err := data.MediaContent(attachment.Media)
+		if err != nil {
+			return exported, err
+		}
+		exported = append(exported, MediaData{attachment.Name, attachment.Format, content})
+	}
+	return exported, nil
+}
+
+// MediaCommand attaches a file to an item, or removes it.
+// The contents are kept, as the file is removed when no item has it.
+type MediaCommand struct {
+	uid      int64
+	itemType ItemType
+	name     string
+	content  []byte
+	remove   bool
+	// If the file was attached by the command, and not already attached to the item
+	attached bool
+}
+
+// NewAttachMediaCommand creates a command for attaching a file to an item
+func NewAttachMediaCommand(uid int64, itemType ItemType, name string, content []byte) *MediaCommand {
+	return &MediaCommand{uid, itemType, name, content, false, false}
+}
+
+// NewDetachMediaCommand creates a command for removing an attached file from an item
+func NewDetachMediaCommand(uid int64, itemType ItemType, name string, content []byte) *MediaCommand {
+	return &MediaCommand{uid, itemType, name, content, true, false}
+}
+
+func (command *MediaCommand) Text() string {
+	if command.remove {
+		return "Remove Attachment"
+	}
+	return "Add Attachment"
+}
+
+func (command *MediaCommand) attach(db *DataContext, attach bool) error {
+	if attach {
+		var err error
+		_, command.attached, err = db.AttachMedia(command.uid, command.itemType, command.name, command.content)
+		return err
+	}
+	// Files already attached before the command are kept when it's undone
+	if !command.remove && !command.attached {
+		return nil
+	}
+	var media int64
+	if err := db.conn().QueryRow("select _rowid_ from Media where hash = ?",
+		MediaHash(command.content)).Scan(&media); err != nil {
+		return fmt.Errorf("failed to find attachment: %v", err)
+	}
+	return db.DetachMedia(command.uid, media)
+}
+
+func (command *MediaCommand) Redo(db *DataContext) error {
+	return command.attach(db, !command.remove)
+}
+
+func (command *MediaCommand) Undo(db *DataContext) error {
+	return command.attach(db, command.remove)
+}
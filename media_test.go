@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// mediaCount gets how many files are stored in the project
+func mediaCount(db *DataContext) int {
+	var count int
+	_ = db.conn().QueryRow("select count(*) from Media").Scan(&count)
+	return count
+}
+
+func TestMedia(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	projectPath := fmt.Sprintf("%v/openrq_test.orq", tempDir)
+	project, err := NewProject(projectPath)
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	db := project.Data()
+	reqID, _ := db.AddEmptyRequirement()
+	solID, _ := db.AddEmptySolution()
+	reqUID, solUID := NewRequirement(reqID).UID(), NewSolution(solID).UID()
+	// The same file attached to both items is only stored once
+	image := []byte("\x89PNG\r\n\x1a\nnot really an image")
+	history := NewHistory()
+	if err := history.Do(db, NewAttachMediaCommand(reqUID, TypeRequirement, "/tmp/drawing.png", image)); err != nil {
+		t.Error("failed to attach file:", err)
+		return
+	}
+	if _, _, err := db.AttachMedia(solUID, TypeSolution, "copy.png", image); err != nil {
+		t.Error("failed to attach file:", err)
+	}
+	if _, _, err := db.AttachMedia(solUID, TypeSolution, "datasheet", []byte("%PDF-1.4")); err != nil {
+		t.Error("failed to attach file:", err)
+	}
+	if count := mediaCount(db); count != 2 {
+		t.Error("expected files to be stored once, but got", count)
+	}
+	media, _ := db.ItemMedia(reqUID)
+	if len(media) != 1 || media[0].Name != "drawing.png" || !media[0].IsImage() {
+		t.Error("unexpected attachment, got", media)
+		return
+	}
+	if content, _ := db.MediaContent(media[0].Media); !bytes.Equal(content, image) {
+		t.Error("expected attachment to have the same contents, but got", content)
+	}
+	if media, _ := db.ItemMedia(solUID); len(media) != 2 || media[1].Format != "application/pdf" {
+		t.Error("expected format to be found from contents, but got", media)
+	}
+	// Attaching a file the item already has, and undoing it, keeps the attachment
+	if _, added, err := db.AttachMedia(reqUID, TypeRequirement, "again.png", image); err != nil || added {
+		t.Error("expected file to already be attached, but got", added, err)
+	}
+	again := NewHistory()
+	_ = again.Do(db, NewAttachMediaCommand(reqUID, TypeRequirement, "again.png", image))
+	if err := again.Undo(db); err != nil {
+		t.Error("failed to undo attaching file again:", err)
+	}
+	if media, _ := db.ItemMedia(reqUID); len(media) != 1 {
+		t.Error("expected attachment to be kept, but got", media)
+	}
+	// Export to json and import it again
+	items, _ := db.LoadItems()
+	jsonPath := fmt.Sprintf("%v/openrq_test.json", tempDir)
+	if err := project.SaveJSON(jsonPath, NewGraphFromItems(items)); err != nil {
+		t.Error("failed to export project:", err)
+		return
+	}
+	imported, err := ImportJSON(jsonPath, fmt.Sprintf("%v/openrq_imported.orq", tempDir))
+	if err != nil {
+		t.Error("failed to import project:", err)
+		return
+	}
+	if count := mediaCount(imported.Data()); count != 2 {
+		t.Error("expected imported files to be stored once, but got", count)
+	}
+	if media, _ := imported.Data().MediaJSON(solUID); len(media) != 2 || !bytes.Equal(media[0].Data, image) {
+		t.Error("expected imported item to have both files, but got", media)
+	}
+	imported.Close()
+	if project, err = NewProject(projectPath); err != nil {
+		t.Error("failed to open project again:", err)
+		return
+	}
+	defer project.Close()
+	db = project.Data()
+	// Files are only removed when no item has them
+	if err := db.DetachMedia(solUID, media[0].Media); err != nil {
+		t.Error("failed to remove attachment:", err)
+	}
+	if count := mediaCount(db); count != 2 {
+		t.Error("expected file to be kept for other item, but got", count)
+	}
+	if err := history.Undo(db); err != nil {
+		t.Error("failed to undo attaching file:", err)
+	}
+	if count := mediaCount(db); count != 1 {
+		t.Error("expected file to be removed, but got", count)
+	}
+	if err := history.Redo(db); err != nil {
+		t.Error("failed to redo attaching file:", err)
+	}
+	if media, _ := db.ItemMedia(reqUID); len(media) != 1 || media[0].Name != "drawing.png" {
+		t.Error("expected file to be attached again, but got", media)
+	}
+}
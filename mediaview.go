@@ -0,0 +1,275 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// Set on media thumbnails, to find them in a group
+const dataMediaThumbnail = 4
+
+// Files attached to all items in the scene by uid
+var itemMedia map[int64][]Attachment
+
+// LoadMedia loads what files are attached to all items
+func LoadMedia() {
+	var err error
+	if itemMedia, err = currentProject.Data().AllItemMedia(); err != nil {
+		fmt.Println("error: failed to get attachments:", err)
+	}
+}
+
+// MediaPixmap loads an attached image, scaled to fit in size
+func MediaPixmap(content []byte, size int) *gui.QPixmap {
+	pixmap := gui.NewQPixmap()
+	if !pixmap.LoadFromData2(core.NewQByteArray2(string(content), len(content)), "", core.Qt__AutoColor) {
+		return nil
+	}
+	return pixmap.Scaled2(size, size, core.Qt__KeepAspectRatio, core.Qt__SmoothTransformation)
+}
+
+// ShowMedia shows a thumbnail of the first attached image in the corner of a group,
+// or how many files are attached if there are no images
+func ShowMedia(group *widgets.QGraphicsItemGroup, attachments []Attachment) {
+	for _, child := range group.ChildItems() {
+		if child.Data(dataMediaThumbnail).ToBool() {
+			group.RemoveFromGroup(child)
+			scene.RemoveItem(child)
+		}
+	}
+	if len(attachments) == 0 {
+		return
+	}
+	names := make([]string, len(attachments))
+	for i, attachment := range attachments {
+		names[i] = attachment.Name
+	}
+	var thumbnail *widgets.QGraphicsItem
+	for _, attachment := range attachments {
+		if !attachment.IsImage() {
+			continue
+		}
+		content, err := currentProject.Data().MediaContent(attachment.Media)
+		if err != nil {
+			fmt.Println("error: failed to get attachment:", err)
+			continue
+		}
+		if pixmap := MediaPixmap(content, 32); pixmap != nil {
+			thumbnail = widgets.NewQGraphicsPixmapItem2(pixmap, nil).QGraphicsItem_PTR()
+			break
+		}
+	}
+	if thumbnail == nil {
+		count := widgets.NewQGraphicsSimpleTextItem2(fmt.Sprintf("[%v]", len(attachments)), nil)
+		count.SetBrush(gui.NewQBrush3(gui.NewQColor2(core.Qt__gray), 1))
+		thumbnail = count.QGraphicsItem_PTR()
+	}
+	width := group.ChildrenBoundingRect().Width()
+	thumbnail.SetPos2(width-thumbnail.BoundingRect().Width()-4, 4)
+	thumbnail.SetToolTip(strings.Join(names, "\n"))
+	thumbnail.SetData(dataMediaThumbnail, core.NewQVariant1(true))
+	thumbnail.SetZValue(16)
+	group.AddToGroup(thumbnail)
+}
+
+// ClipboardFiles gets files, or an image, from the clipboard as names and contents
+func ClipboardFiles() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	clipboard := gui.QGuiApplication_Clipboard()
+	mimeData := clipboard.MimeData(gui.QClipboard__Clipboard)
+	if mimeData.HasUrls() {
+		return ReadFiles(mimeData.Urls())
+	}
+	if mimeData.HasImage() {
+		// Images are saved as png, as the original format isn't known
+		buffer := core.NewQBuffer(nil)
+		buffer.Open(core.QIODevice__WriteOnly)
+		clipboard.Image(gui.QClipboard__Clipboard).Save2(buffer, "PNG", -1)
+		files["pasted.png"] = []byte(buffer.Data().ConstData())
+	}
+	return files, nil
+}
+
+// ReadFiles reads all local files in urls, as names and contents
+func ReadFiles(urls []*core.QUrl) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for _, url := range urls {
+		if !url.IsLocalFile() {
+			continue
+		}
+		content, err := ioutil.ReadFile(url.ToLocalFile())
+		if err != nil {
+			return files, err
+		}
+		files[url.ToLocalFile()] = content
+	}
+	return files, nil
+}
+
+// AttachFiles attaches files to the item shown in a group, and updates its thumbnail
+func AttachFiles(group *widgets.QGraphicsItemGroup, files map[string][]byte) error {
+	if len(files) == 0 {
+		return nil
+	}
+	db := currentProject.Data()
+	item := GetGroupItem(group)
+	uid := item.UID()
+	commands := make([]Command, 0, len(files))
+	for name, content := range files {
+		commands = append(commands, NewAttachMediaCommand(uid, GetItemType(item), name, content))
+	}
+	if err := currentHistory.Do(db, NewCommandGroup("Add Attachment", commands...)); err != nil {
+		return err
+	}
+	media, err := db.ItemMedia(uid)
+	if err != nil {
+		return err
+	}
+	itemMedia[uid] = media
+	ShowMedia(group, media)
+	return nil
+}
+
+// attachmentChange is a file added or removed in the edit dock, but not yet saved
+type attachmentChange struct {
+	name    string
+	content []byte
+	remove  bool
+}
+
+// CreateAttachmentList creates a list for adding and removing attached files in the edit dock,
+// and a function getting the commands for saving the changes made
+func CreateAttachmentList(window widgets.QWidget_ITF, uid int64) (*widgets.QWidget, func(itemType ItemType) []Command) {
+	list := widgets.NewQListWidget(nil)
+	list.SetMaximumHeight(96)
+	// Contents of files added, by name, and all changes in order
+	added := make(map[string][]byte)
+	changes := make([]attachmentChange, 0)
+	// Gets the contents of a file in the list, added or attached
+	content := func(listItem *widgets.QListWidgetItem) ([]byte, error) {
+		if data, ok := added[listItem.Text()]; ok {
+			return data, nil
+		}
+		return currentProject.Data().MediaContent(listItem.Data(int(core.Qt__UserRole)).ToLongLong(nil))
+	}
+	addItem := func(name string, media int64, image []byte) {
+		listItem := widgets.NewQListWidgetItem2(name, list, 0)
+		listItem.SetData(int(core.Qt__UserRole), core.NewQVariant1(media))
+		if pixmap := MediaPixmap(image, 16); pixmap != nil {
+			listItem.SetIcon(gui.NewQIcon2(pixmap))
+		}
+	}
+	for _, attachment := range itemMedia[uid] {
+		var image []byte
+		if attachment.IsImage() {
+			image, _ = currentProject.Data().MediaContent(attachment.Media)
+		}
+		addItem(attachment.Name, attachment.Media, image)
+	}
+	addFiles := func(files map[string][]byte, err error) {
+		if err != nil {
+			widgets.QMessageBox_Warning(window, "Failed to Add Attachment", err.Error(),
+				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		}
+		for path, data := range files {
+			name := path[strings.LastIndexAny(path, "/\\")+1:]
+			added[name] = data
+			changes = append(changes, attachmentChange{name, data, false})
+			addItem(name, 0, data)
+		}
+	}
+	// Files can also be dropped on the list
+	list.SetAcceptDrops(true)
+	list.ConnectDragEnterEvent(func(event *gui.QDragEnterEvent) {
+		if event.MimeData().HasUrls() {
+			event.AcceptProposedAction()
+		}
+	})
+	list.ConnectDragMoveEvent(func(event *gui.QDragMoveEvent) {
+		if event.MimeData().HasUrls() {
+			event.AcceptProposedAction()
+		}
+	})
+	list.ConnectDropEvent(func(event *gui.QDropEvent) {
+		addFiles(ReadFiles(event.MimeData().Urls()))
+	})
+	buttons := widgets.NewQHBoxLayout()
+	add := widgets.NewQPushButton2("Add...", nil)
+	add.ConnectReleased(func() {
+		paths := widgets.QFileDialog_GetOpenFileNames(window, "Add Attachment",
+			core.QStandardPaths_Locate(core.QStandardPaths__DocumentsLocation, "", 1), "", "", 0)
+		urls := make([]*core.QUrl, len(paths))
+		for i, path := range paths {
+			urls[i] = core.QUrl_FromLocalFile(path)
+		}
+		addFiles(ReadFiles(urls))
+	})
+	buttons.AddWidget(add, 1, 0)
+	paste := widgets.NewQPushButton2("Paste", nil)
+	paste.SetToolTip("Attach an image or files from the clipboard")
+	paste.ConnectReleased(func() {
+		addFiles(ClipboardFiles())
+	})
+	buttons.AddWidget(paste, 1, 0)
+	saveAs := widgets.NewQPushButton2("Save As...", nil)
+	saveAs.ConnectReleased(func() {
+		listItem := list.CurrentItem()
+		if listItem.Pointer() == nil {
+			return
+		}
+		path := widgets.QFileDialog_GetSaveFileName(window, "Save Attachment", listItem.Text(), "", "", 0)
+		if len(path) <= 0 {
+			return
+		}
+		data, err := content(listItem)
+		if err == nil {
+			err = ioutil.WriteFile(path, data, 0644)
+		}
+		if err != nil {
+			widgets.QMessageBox_Warning(window, "Failed to Save Attachment", err.Error(),
+				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		}
+	})
+	buttons.AddWidget(saveAs, 1, 0)
+	remove := widgets.NewQPushButton2("Remove", nil)
+	remove.ConnectReleased(func() {
+		listItem := list.CurrentItem()
+		if listItem.Pointer() == nil {
+			return
+		}
+		data, err := content(listItem)
+		if err != nil {
+			fmt.Println("error: failed to get attachment:", err)
+			return
+		}
+		changes = append(changes, attachmentChange{listItem.Text(), data, true})
+		delete(added, listItem.Text())
+		list.TakeItem(list.Row(listItem))
+	})
+	buttons.AddWidget(remove, 1, 0)
+	layout := widgets.NewQVBoxLayout()
+	layout.SetContentsMargins(0, 0, 0, 0)
+	layout.AddWidget(list, 1, 0)
+	layout.AddLayout(buttons, 0)
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetLayout(layout)
+	return widget, func(itemType ItemType) []Command {
+		commands := make([]Command, len(changes))
+		for i, change := range changes {
+			if change.remove {
+				commands[i] = NewDetachMediaCommand(uid, itemType, change.name, change.content)
+			} else {
+				commands[i] = NewAttachMediaCommand(uid, itemType, change.name, change.content)
+			}
+		}
+		return commands
+	}
+}
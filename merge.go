@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// mergeField is a property of an item that is merged on its own
+type mergeField struct {
+	name string
+	// value gets the property to compare it
+	value func(item ItemSnapshot) string
+	// copy sets the property from another version of the item
+	copy func(to *ItemSnapshot, from ItemSnapshot)
+}
+
+// mergeFields are all properties that can be changed independently of each other
+var mergeFields = []mergeField{
+	{"Type", snapshotName, func(to *ItemSnapshot, from ItemSnapshot) {
+		to.Type = from.Type
+	}},
+	{"Description", func(item ItemSnapshot) string { return item.Description }, func(to *ItemSnapshot, from ItemSnapshot) {
+		to.Description = from.Description
+	}},
+	{"Rationale", func(item ItemSnapshot) string { return item.Rationale }, func(to *ItemSnapshot, from ItemSnapshot) {
+		to.Rationale = from.Rationale
+	}},
+	{"FitCriterion", func(item ItemSnapshot) string { return item.FitCriterion }, func(to *ItemSnapshot, from ItemSnapshot) {
+		to.FitCriterion = from.FitCriterion
+	}},
+	{"Link", func(item ItemSnapshot) string { return item.Link }, func(to *ItemSnapshot, from ItemSnapshot) {
+		to.Link = from.Link
+	}},
+	{"Parent", formatParent, func(to *ItemSnapshot, from ItemSnapshot) {
+		to.HasParent, to.ParentUID = from.HasParent, from.ParentUID
+	}},
+	{"Position", func(item ItemSnapshot) string {
+		return fmt.Sprintf("%v, %v", item.X, item.Y)
+	}, func(to *ItemSnapshot, from ItemSnapshot) {
+		to.X, to.Y = from.X, from.Y
+	}},
+	{"Size", func(item ItemSnapshot) string {
+		return fmt.Sprintf("%v x %v", item.Width, item.Height)
+	}, func(to *ItemSnapshot, from ItemSnapshot) {
+		to.Width, to.Height = from.Width, from.Height
+	}},
+	{"Look", func(item ItemSnapshot) string {
+		return fmt.Sprintf("%v, %v, %v", item.Color, item.Border, item.Shape)
+	}, func(to *ItemSnapshot, from ItemSnapshot) {
+		to.Color, to.Border, to.Shape = from.Color, from.Border, from.Shape
+	}},
+}
+
+// Field used for conflicts where an item was deleted on one side and changed on the other
+const mergeItemField = "Item"
+
+// MergeConflict is a property of an item that was changed differently on both sides
+type MergeConflict struct {
+	UID   int64
+	Field string
+	// Values in each version, without formatting
+	Base, Ours, Theirs string
+}
+
+func (conflict MergeConflict) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		UID                string
+		Field              string
+		Base, Ours, Theirs string
+	}{
+		FormatUID(conflict.UID), conflict.Field, conflict.Base, conflict.Ours, conflict.Theirs,
+	})
+}
+
+// MergeResult is the result of merging two versions of a project.
+// Conflicts are resolved by using our version until resolved otherwise.
+type MergeResult struct {
+	Conflicts []MergeConflict
+	// Merged items, in the order they were found
+	items map[int64]ItemSnapshot
+	order []int64
+	// Both versions, used for resolving conflicts
+	ours, theirs map[int64]ItemSnapshot
+}
+
+// snapshotMap maps items by uid
+func snapshotMap(items []ItemSnapshot) map[int64]ItemSnapshot {
+	mapped := make(map[int64]ItemSnapshot)
+	for _, item := range items {
+		mapped[item.UID] = item
+	}
+	return mapped
+}
+
+// newParents gets all items that got a child they didn't have in base
+func newParents(base map[int64]ItemSnapshot, items []ItemSnapshot) map[int64]bool {
+	parents := make(map[int64]bool)
+	for _, item := range items {
+		baseItem, found := base[item.UID]
+		if item.HasParent && (!found || !baseItem.HasParent || baseItem.ParentUID != item.ParentUID) {
+			parents[item.ParentUID] = true
+		}
+	}
+	return parents
+}
+
+// changedFrom checks if an item has any changes compared to base
+func changedFrom(base, item ItemSnapshot) bool {
+	for _, field := range mergeFields {
+		if field.value(base) != field.value(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge combines ours and theirs, both changed from base, matching items by uid.
+// Items are merged field by field, and only fields changed differently on both sides conflict.
+func Merge(base, ours, theirs []ItemSnapshot) *MergeResult {
+	result := &MergeResult{
+		Conflicts: make([]MergeConflict, 0),
+		items:     make(map[int64]ItemSnapshot),
+		order:     make([]int64, 0),
+		ours:      snapshotMap(ours),
+		theirs:    snapshotMap(theirs),
+	}
+	baseItems := snapshotMap(base)
+	// Adding a child to an item counts as changing it
+	oursParents := newParents(baseItems, ours)
+	theirsParents := newParents(baseItems, theirs)
+	// Check every item that exists in any version once
+	found := make(map[int64]bool)
+	for _, items := range [][]ItemSnapshot{base, ours, theirs} {
+		for _, item := range items {
+			if !found[item.UID] {
+				found[item.UID] = true
+				result.order = append(result.order, item.UID)
+			}
+		}
+	}
+	for _, uid := range result.order {
+		baseItem, inBase := baseItems[uid]
+		ourItem, inOurs := result.ours[uid]
+		theirItem, inTheirs := result.theirs[uid]
+		switch {
+		case inOurs && inTheirs:
+			result.mergeItem(baseItem, inBase, ourItem, theirItem)
+		case inOurs && (!inBase || (!changedFrom(baseItem, ourItem) && !oursParents[uid])):
+			// Added by us, or deleted by them without us changing it
+			if !inBase {
+				result.items[uid] = ourItem
+			}
+		case inTheirs && (!inBase || (!changedFrom(baseItem, theirItem) && !theirsParents[uid])):
+			if !inBase {
+				result.items[uid] = theirItem
+			}
+		case inOurs:
+			// Changed by us, but deleted by them
+			result.items[uid] = ourItem
+			result.Conflicts = append(result.Conflicts, MergeConflict{
+				uid, mergeItemField, PlainText(baseItem.Description), "changed", "deleted",
+			})
+		case inTheirs:
+			// Deleted by us, but changed by them
+			result.Conflicts = append(result.Conflicts, MergeConflict{
+				uid, mergeItemField, PlainText(baseItem.Description), "deleted", "changed",
+			})
+		}
+	}
+	return result
+}
+
+// mergeItem merges an item that exists in both versions, field by field
+func (result *MergeResult) mergeItem(base ItemSnapshot, inBase bool, ours, theirs ItemSnapshot) {
+	merged := ours
+	for _, field := range mergeFields {
+		baseValue, ourValue, theirValue := field.value(base), field.value(ours), field.value(theirs)
+		if ourValue == theirValue || (inBase && theirValue == baseValue) {
+			// Same on both sides, or only changed by us
+			continue
+		}
+		if inBase && ourValue == baseValue {
+			// Only changed by them
+			field.copy(&merged, theirs)
+			continue
+		}
+		if !inBase {
+			baseValue = ""
+		}
+		result.Conflicts = append(result.Conflicts, MergeConflict{
+			ours.UID, field.name, PlainText(baseValue), PlainText(ourValue), PlainText(theirValue),
+		})
+	}
+	result.items[ours.UID] = merged
+}
+
+// Resolve resolves a conflict by using either our or their version
+func (result *MergeResult) Resolve(index int, useTheirs bool) {
+	conflict := result.Conflicts[index]
+	version := result.ours
+	if useTheirs {
+		version = result.theirs
+	}
+	item, found := version[conflict.UID]
+	if conflict.Field == mergeItemField {
+		// Either keep the changed item, or delete it
+		if found {
+			result.items[conflict.UID] = item
+		} else {
+			delete(result.items, conflict.UID)
+		}
+		return
+	}
+	merged, merging := result.items[conflict.UID]
+	if !merging || !found {
+		return
+	}
+	for _, field := range mergeFields {
+		if field.name == conflict.Field {
+			field.copy(&merged, item)
+		}
+	}
+	result.items[conflict.UID] = merged
+}
+
+// ResolveAll resolves all conflicts by using either our or their version
+func (result *MergeResult) ResolveAll(useTheirs bool) {
+	for i := range result.Conflicts {
+		result.Resolve(i, useTheirs)
+	}
+}
+
+// Items gets all merged items, where items with a deleted parent become roots
+func (result *MergeResult) Items() []ItemSnapshot {
+	items := make([]ItemSnapshot, 0, len(result.items))
+	for _, uid := range result.order {
+		item, found := result.items[uid]
+		if !found {
+			continue
+		}
+		if _, parentFound := result.items[item.ParentUID]; item.HasParent && !parentFound {
+			item.HasParent = false
+			item.ParentUID = 0
+		}
+		items = append(items, item)
+	}
+	return items
+}
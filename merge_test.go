@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+)
+
+// mergeTestItem creates a snapshot of an item for testing
+func mergeTestItem(uid int64, itemType ItemType, description string) ItemSnapshot {
+	item := ItemSnapshot{Type: itemType}
+	item.UID = uid
+	item.Description = description
+	item.Width, item.Height = 128, 64
+	return item
+}
+
+func TestMerge(t *testing.T) {
+	root := mergeTestItem(1, TypeRequirement, "root")
+	sol := mergeTestItem(2, TypeSolution, "sol")
+	sol.HasParent, sol.ParentUID = true, 1
+	old := mergeTestItem(3, TypeSolution, "old")
+	base := []ItemSnapshot{root, sol, old}
+	// We change the description of the root and delete old
+	ourRoot := root
+	ourRoot.Description = "our root"
+	ours := []ItemSnapshot{ourRoot, sol}
+	// They move the root, add a new item under the solution and keep old
+	theirRoot := root
+	theirRoot.X = 256
+	added := mergeTestItem(4, TypeRequirement, "added")
+	added.HasParent, added.ParentUID = true, 2
+	theirs := []ItemSnapshot{theirRoot, sol, old, added}
+	// Changes to different fields, or different items, should not conflict
+	result := Merge(base, ours, theirs)
+	if len(result.Conflicts) != 0 {
+		t.Error("unexpected conflicts, expected 0, but got", result.Conflicts)
+	}
+	items := snapshotMap(result.Items())
+	if len(items) != 3 {
+		t.Error("unexpected item count, expected 3, but got", len(items))
+	}
+	if merged := items[1]; merged.Description != "our root" || merged.X != 256 {
+		t.Error("expected root to have both changes, but got", merged.Description, merged.X)
+	}
+	if merged, found := items[4]; !found || merged.ParentUID != 2 {
+		t.Error("expected added item to be merged with its parent")
+	}
+	// Changing the same field differently should conflict
+	theirRoot.Description = "their root"
+	result = Merge(base, ours, []ItemSnapshot{theirRoot, sol, old})
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Field != "Description" {
+		t.Error("expected description conflict, but got", result.Conflicts)
+		return
+	}
+	if merged := snapshotMap(result.Items())[1]; merged.Description != "our root" {
+		t.Error("expected conflict to use our version until resolved, but got", merged.Description)
+	}
+	result.Resolve(0, true)
+	if merged := snapshotMap(result.Items())[1]; merged.Description != "their root" || merged.X != 256 {
+		t.Error("expected conflict to be resolved as theirs, but got", merged.Description, merged.X)
+	}
+	// Deleting the solution while they add a child to it should conflict
+	result = Merge(base, []ItemSnapshot{root, old}, theirs)
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Field != mergeItemField || result.Conflicts[0].UID != 2 {
+		t.Error("expected item conflict, but got", result.Conflicts)
+		return
+	}
+	// Keeping it deleted makes the new child a root
+	result.Resolve(0, false)
+	items = snapshotMap(result.Items())
+	if _, found := items[2]; found {
+		t.Error("expected solution to be deleted")
+	}
+	if merged := items[4]; merged.HasParent {
+		t.Error("expected child of deleted item to be a root")
+	}
+}
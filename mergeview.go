@@ -0,0 +1,133 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/widgets"
+)
+
+// Columns in the conflict list
+const (
+	conflictItem   = 0
+	conflictField  = 1
+	conflictBase   = 2
+	conflictOurs   = 3
+	conflictTheirs = 4
+)
+
+// ResolveConflicts lets the user pick our or their version for each conflict,
+// returns false if the merge was cancelled
+func ResolveConflicts(window *widgets.QMainWindow, result *MergeResult) bool {
+	dialog := widgets.NewQDialog(window, 0)
+	dialog.SetWindowTitle("Resolve Conflicts")
+	dialog.Resize2(720, 400)
+	layout := widgets.NewQVBoxLayout()
+	layout.AddWidget(widgets.NewQLabel2(fmt.Sprintf(
+		"%v changes could not be merged automatically, check the ones where their version should be used",
+		len(result.Conflicts)), nil, 0), 0, 0)
+	// One row for each conflict, checked if using their version
+	tree := widgets.NewQTreeWidget(nil)
+	tree.SetRootIsDecorated(false)
+	tree.SetHeaderLabels([]string{"Item", "Field", "Base", "Ours", "Theirs"})
+	rows := make([]*widgets.QTreeWidgetItem, len(result.Conflicts))
+	for i, conflict := range result.Conflicts {
+		rows[i] = widgets.NewQTreeWidgetItem2([]string{
+			FormatUID(conflict.UID), conflict.Field,
+			Truncate(conflict.Base, 40), Truncate(conflict.Ours, 40), Truncate(conflict.Theirs, 40),
+		}, 0)
+		rows[i].SetToolTip(conflictOurs, conflict.Ours)
+		rows[i].SetToolTip(conflictTheirs, conflict.Theirs)
+		rows[i].SetCheckState(conflictTheirs, core.Qt__Unchecked)
+		tree.AddTopLevelItem(rows[i])
+	}
+	layout.AddWidget(tree, 1, 0)
+	// Buttons for picking the same version for all
+	setAll := func(state core.Qt__CheckState) {
+		for _, row := range rows {
+			row.SetCheckState(conflictTheirs, state)
+		}
+	}
+	allButtons := widgets.NewQHBoxLayout()
+	allOurs := widgets.NewQPushButton2("Use All Ours", nil)
+	allOurs.ConnectReleased(func() {
+		setAll(core.Qt__Unchecked)
+	})
+	allButtons.AddWidget(allOurs, 0, 0)
+	allTheirs := widgets.NewQPushButton2("Use All Theirs", nil)
+	allTheirs.ConnectReleased(func() {
+		setAll(core.Qt__Checked)
+	})
+	allButtons.AddWidget(allTheirs, 0, 0)
+	allButtons.AddStretch(1)
+	layout.AddLayout(allButtons, 0)
+	// Merge or cancel
+	buttons := widgets.NewQDialogButtonBox3(widgets.QDialogButtonBox__Ok|widgets.QDialogButtonBox__Cancel, nil)
+	buttons.ConnectAccepted(dialog.Accept)
+	buttons.ConnectRejected(dialog.Reject)
+	layout.AddWidget(buttons, 0, 0)
+	dialog.SetLayout(layout)
+	if dialog.Exec() != int(widgets.QDialog__Accepted) {
+		return false
+	}
+	for i, row := range rows {
+		result.Resolve(i, row.CheckState(conflictTheirs) == core.Qt__Checked)
+	}
+	return true
+}
+
+// MergeProject merges changes from another copy of the current project, with a common ancestor
+func MergeProject(window *widgets.QMainWindow) {
+	if currentProject == nil || currentProject.ReadOnly() {
+		widgets.QMessageBox_Information(window, "Merge", "Open a project to merge changes into",
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	showError := func(err error) {
+		widgets.QMessageBox_Critical(window, "Failed to Merge", err.Error(),
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+	}
+	location := core.QStandardPaths_Locate(core.QStandardPaths__DocumentsLocation, "", 1)
+	theirsPath := widgets.QFileDialog_GetOpenFileName(window, "Project to Merge",
+		location, anyProjectFilter, "", 0)
+	if len(theirsPath) <= 0 {
+		return
+	}
+	basePath := widgets.QFileDialog_GetOpenFileName(window, "Common Ancestor of Both Projects",
+		location, anyProjectFilter, "", 0)
+	if len(basePath) <= 0 {
+		return
+	}
+	base, err := LoadSnapshots(basePath, "")
+	if err != nil {
+		showError(err)
+		return
+	}
+	theirs, err := LoadSnapshots(theirsPath, "")
+	if err != nil {
+		showError(err)
+		return
+	}
+	db := currentProject.Data()
+	ours, err := db.CurrentItems()
+	if err != nil {
+		showError(err)
+		return
+	}
+	result := Merge(base, ours, theirs)
+	if len(result.Conflicts) > 0 && !ResolveConflicts(window, result) {
+		return
+	}
+	// Merging can be undone like any other change
+	merged := result.Items()
+	if err := currentHistory.Do(db, NewReplaceItemsCommand("Merge", ours, merged)); err != nil {
+		showError(err)
+		return
+	}
+	ReloadProject(window)
+	widgets.QMessageBox_Information(window, "Merge", DiffSummary(Diff(ours, merged)),
+		widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// Migration upgrades the schema of a project by one version
+type Migration struct {
+	// Short description of what the migration changes
+	Info string
+	// Run performs the migration, nothing is saved if it fails
+	Run func(tx *sql.Tx) error
+}
+
+// All migrations in order, where migrations[0] upgrades from version 1 to 2,
+// tableData should always match the schema after the last migration
+var migrations = []Migration{
+	{
+		Info: "store item contents in baselines",
+		Run: func(tx *sql.Tx) error {
+			// Versions used to always be 1 and refer to item ids, which can't be used as a baseline
+			if _, err := tx.Exec("delete from ItemVersions"); err != nil {
+				return err
+			}
+			columns := []string{
+				"parent integer", "description text", "rationale text", "fitCriterion text", "link text",
+				"color integer", "border integer", "shape integer",
+				"x integer", "y integer", "width integer", "height integer",
+			}
+			for _, column := range columns {
+				if _, err := tx.Exec("alter table ItemVersions add column " + column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Info: "store media once for each hash",
+		Run: func(tx *sql.Tx) error {
+			// Media was never used before, and only allowed one solution per file
+			queries := []string{
+				"drop table Media",
+				"create table Media (hash text, format text, data blob)",
+				"create table MediaItems (media integer, item integer, type integer, name text, " +
+					"foreign key (media) references Media(id))",
+			}
+			for _, query := range queries {
+				if _, err := tx.Exec(query); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Info: "save severity of validation rules",
+		Run: func(tx *sql.Tx) error {
+			_, err := tx.Exec("alter table ValidationRules add column severity text")
+			return err
+		},
+	}, {
+		Info: "keep identifiers of objects imported from ReqIF",
+		Run: func(tx *sql.Tx) error {
+			_, err := tx.Exec("create table ReqIFIdentifiers (identifier text, item integer)")
+			return err
+		},
+	},
+}
+
+// SchemaVersion gets the schema version of projects created by this version
+func SchemaVersion() int {
+	return len(migrations) + 1
+}
+
+// MigrationBackupPath gets the path where the project is backed up to before migrating
+func MigrationBackupPath(path string, version int) string {
+	return fmt.Sprintf("%v.v%v.bak", path, version)
+}
+
+// Version gets the schema version of the project
+func (data *DataContext) Version() (int, error) {
+	var version int
+	if err := data.conn().QueryRow("select version from Info").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get project version: %v", err)
+	}
+	return version, nil
+}
+
+// Migrate upgrades the project in path to the latest schema version,
+// after making a backup of it
+func (data *DataContext) Migrate(path string) error {
+	version, err := data.Version()
+	if err != nil {
+		return err
+	}
+	// Newer projects may have data we don't know how to handle
+	if version > SchemaVersion() {
+		return fmt.Errorf("project was created with a newer version of OpenRQ "+
+			"(project version %v, latest supported version %v)", version, SchemaVersion())
+	}
+	// Already up-to-date
+	if version == SchemaVersion() {
+		return nil
+	}
+	// Backup project before changing anything
+	if err := BackupFile(path, MigrationBackupPath(path, version)); err != nil {
+		return fmt.Errorf("failed to backup project before upgrading: %v", err)
+	}
+	// Run all migrations in a single transaction
+	tx, err := data.Database.Begin()
+	if err != nil {
+		return err
+	}
+	for v := version; v < SchemaVersion(); v++ {
+		if err := migrations[v-1].Run(tx); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to upgrade project to version %v (%v): %v", v+1, migrations[v-1].Info, err)
+		}
+	}
+	if _, err := tx.Exec("update Info set version = ?", SchemaVersion()); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to update project version: %v", err)
+	}
+	return tx.Commit()
+}
+
+// BackupFile copies a file, keeping permissions
+func BackupFile(path, backupPath string) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(backupPath, data, fileInfo.Mode())
+}
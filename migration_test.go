@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	// Use test migrations instead of the real ones
+	oldMigrations := migrations
+	defer func() {
+		migrations = oldMigrations
+	}()
+	migrations = []Migration{}
+	// Create a project with the current schema
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	projectPath := fmt.Sprintf("%v/openrq_test.orq", tempDir)
+	project, err := NewProject(projectPath)
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	// Add a migration that adds a new column
+	addColumn := Migration{
+		Info: "add test column",
+		Run: func(tx *sql.Tx) error {
+			_, err := tx.Exec("alter table Info add column test text default 'migrated'")
+			return err
+		},
+	}
+	migrations = []Migration{addColumn}
+	// Opening the project should now upgrade it
+	db, err := OpenDataContext(projectPath)
+	if err != nil {
+		t.Error("failed to upgrade project:", err)
+		return
+	}
+	if version, err := db.Version(); err != nil || version != 2 {
+		t.Error("unexpected project version, expected 2, but got", version, err)
+	}
+	var test string
+	if err = db.conn().QueryRow("select test from Info").Scan(&test); err != nil || test != "migrated" {
+		t.Errorf("unexpected test column value, expected \"migrated\", but got \"%v\" (%v)", test, err)
+	}
+	db.Close()
+	// The old version should have been backed up
+	if _, err = os.Stat(MigrationBackupPath(projectPath, 1)); err != nil {
+		t.Error("failed to find backup:", err)
+	}
+	// A failing migration should not change anything
+	migrations = []Migration{addColumn, {
+		Info: "failing migration",
+		Run: func(tx *sql.Tx) error {
+			if _, err := tx.Exec("update Info set name = 'changed'"); err != nil {
+				return err
+			}
+			return fmt.Errorf("test failure")
+		},
+	}}
+	if _, err = OpenDataContext(projectPath); err == nil {
+		t.Error("expected failing migration to return an error")
+	}
+	// Projects from newer versions should not be opened
+	migrations = []Migration{}
+	if _, err = OpenDataContext(projectPath); err == nil {
+		t.Error("expected newer project to not be opened")
+	}
+	// Make sure the project was left untouched
+	migrations = []Migration{addColumn}
+	if db, err = OpenDataContext(projectPath); err != nil {
+		t.Error("failed to open project:", err)
+		return
+	}
+	defer db.Close()
+	if version, err := db.Version(); err != nil || version != 2 {
+		t.Error("unexpected project version, expected 2, but got", version, err)
+	}
+	if name := db.ProjectName(); name != "openrq_test" {
+		t.Errorf("unexpected project name, expected \"openrq_test\", but got \"%v\"", name)
+	}
+}
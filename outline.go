@@ -0,0 +1,496 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	outlineHeadingRegex = regexp.MustCompile(`^(#{1,6})[ \t]+(.*)$`)
+	outlineListRegex    = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])[ \t]+(.*)$`)
+	outlineUIDRegex     = regexp.MustCompile(`[ \t]*<!--[ \t]*uid:[ \t]*(\S*?)[ \t]*-->`)
+	outlineTypeRegex    = regexp.MustCompile(`(?i)^(p|s|problem|solution):[ \t]*`)
+	outlineFieldRegex   = regexp.MustCompile(`(?i)^(rationale|fit criterion|fit):[ \t]*`)
+)
+
+// Fields of an outline item that paragraphs are added to
+const (
+	outlineDescription = iota
+	outlineRationale
+	outlineFitCriterion
+)
+
+// OutlineItem is a heading or list item in a markdown outline
+type OutlineItem struct {
+	// Line in the file, starting at 1
+	Line int
+	Type ItemType
+	// Text of the item, as plain text
+	Description  string
+	Rationale    string
+	FitCriterion string
+	// UID from the comment in the file, or assigned when importing if HasUID is false
+	UID    int64
+	HasUID bool
+	// Parent by index in the outline, or -1 for roots
+	Parent int
+}
+
+// Outline is a tree of items written as markdown, where headings and nested lists are parents of
+// everything below them. Items are problems unless prefixed with "S:", or listed with "+", and indented
+// paragraphs below problems are the rationale and fit criterion, optionally prefixed with "Rationale:"
+// and "Fit criterion:". Each item has its uid in a comment, like <!-- uid: 1f -->, once imported.
+type Outline struct {
+	// All lines as read, to write back with uids
+	Lines []string
+	// All items, where parents always come before their children
+	Items []OutlineItem
+}
+
+// outlineIndent gets the width of whitespace at the start of a line, where tabs are 4 spaces
+func outlineIndent(line string) int {
+	width := 0
+	for _, char := range line {
+		switch char {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// ParseOutline reads an outline, failing on the first line with an error
+func ParseOutline(reader io.Reader) (*Outline, error) {
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	// Line endings are kept as they are, to only change lines that get a uid
+	outline := &Outline{Lines: strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")}
+	// Parents are found by depth, where headings are always above list items
+	type parent struct {
+		index, depth int
+	}
+	parents := make([]parent, 0)
+	uids := make(map[int64]int)
+	// Item that following paragraphs are added to, if indented at least textIndent
+	current, textIndent := -1, 0
+	field, inParagraph, inFence := outlineDescription, false, false
+	for i, line := range outline.Lines {
+		line = strings.TrimRight(line, "\r")
+		// Code blocks are skipped, as they may contain anything
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			current, inParagraph = -1, false
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			inParagraph = false
+			continue
+		}
+		var depth int
+		var text, marker string
+		if match := outlineHeadingRegex.FindStringSubmatch(line); match != nil {
+			depth, text, textIndent = len(match[1]), match[2], 0
+		} else if match := outlineListRegex.FindStringSubmatch(line); match != nil {
+			depth, text, marker = 100+outlineIndent(match[1]), match[3], match[2]
+			textIndent = outlineIndent(match[1]) + 1
+		} else {
+			if current < 0 || outlineIndent(line) < textIndent {
+				// Other text ends the item, and is ignored
+				current = -1
+				continue
+			}
+			if err := outline.addText(current, strings.TrimSpace(line), &field, inParagraph); err != nil {
+				return nil, fmt.Errorf("line %v: %v", i+1, err)
+			}
+			inParagraph = true
+			continue
+		}
+		item := OutlineItem{Line: i + 1, Type: TypeRequirement, Parent: -1}
+		if match := outlineUIDRegex.FindStringSubmatch(text); match != nil {
+			uid, err := ParseUID(match[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid uid \"%v\"", i+1, match[1])
+			}
+			if other, found := uids[uid]; found {
+				return nil, fmt.Errorf("line %v: same uid as line %v", i+1, outline.Items[other].Line)
+			}
+			uids[uid] = len(outline.Items)
+			item.UID, item.HasUID = uid, true
+			text = outlineUIDRegex.ReplaceAllString(text, "")
+		}
+		if match := outlineTypeRegex.FindStringSubmatch(text); match != nil {
+			if strings.HasPrefix(strings.ToLower(match[1]), "s") {
+				item.Type = TypeSolution
+			}
+			text = text[len(match[0]):]
+		} else if marker == "+" {
+			item.Type = TypeSolution
+		}
+		if item.Description = strings.TrimSpace(text); item.Description == "" {
+			return nil, fmt.Errorf("line %v: item has no description", i+1)
+		}
+		for len(parents) > 0 && parents[len(parents)-1].depth >= depth {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) > 0 {
+			item.Parent = parents[len(parents)-1].index
+		}
+		parents = append(parents, parent{len(outline.Items), depth})
+		// Lines right after list items continue the description, but headings are a paragraph of their own
+		current, field, inParagraph = len(outline.Items), outlineDescription, marker != ""
+		outline.Items = append(outline.Items, item)
+	}
+	return outline, nil
+}
+
+// addText adds a line of text to an item, continuing the current paragraph or starting a new one
+func (outline *Outline) addText(index int, text string, field *int, inParagraph bool) error {
+	item := &outline.Items[index]
+	if !inParagraph {
+		// Paragraphs are the rationale, then the fit criterion, unless prefixed
+		if match := outlineFieldRegex.FindStringSubmatch(text); match != nil {
+			*field = outlineFitCriterion
+			if strings.ToLower(match[1]) == "rationale" {
+				*field = outlineRationale
+			}
+			text = text[len(match[0]):]
+		} else if item.Type == TypeSolution {
+			*field = outlineDescription
+		} else if item.Rationale == "" {
+			*field = outlineRationale
+		} else if *field != outlineFitCriterion || item.FitCriterion == "" {
+			*field = outlineFitCriterion
+		}
+		if item.Type == TypeSolution && *field != outlineDescription {
+			return fmt.Errorf("solutions have no rationale or fit criterion")
+		}
+	}
+	value := &item.Description
+	switch *field {
+	case outlineRationale:
+		value = &item.Rationale
+	case outlineFitCriterion:
+		value = &item.FitCriterion
+	}
+	separator := "\n"
+	if inParagraph {
+		separator = " "
+	}
+	if *value == "" {
+		*value = text
+	} else if text != "" {
+		*value += separator + text
+	}
+	return nil
+}
+
+// Write writes the outline as it was read, adding uid comments to items that didn't have one
+func (outline *Outline) Write(writer io.Writer) error {
+	lines := make([]string, len(outline.Lines))
+	copy(lines, outline.Lines)
+	for _, item := range outline.Items {
+		if item.HasUID {
+			continue
+		}
+		line := lines[item.Line-1]
+		ending := line[len(strings.TrimRight(line, "\r")):]
+		lines[item.Line-1] = fmt.Sprintf("%v <!-- uid: %v -->%v",
+			strings.TrimRight(line, " \t\r"), FormatUID(item.UID), ending)
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(writer, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFile replaces a file with the outline, first writing it next to it
+// so the original is kept if writing fails
+func (outline *Outline) WriteFile(path string) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := outline.Write(file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		_ = os.Chmod(file.Name(), info.Mode())
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	for i := range outline.Items {
+		outline.Items[i].HasUID = true
+	}
+	return nil
+}
+
+// Actions when importing an outline item
+const (
+	OutlineAdd    = "add"
+	OutlineUpdate = "update"
+	OutlineKeep   = "keep"
+)
+
+// OutlineChange is what happens to an item in the outline when importing it
+type OutlineChange struct {
+	Item   OutlineItem
+	Action string
+	// Item as it will be after importing
+	Snapshot ItemSnapshot
+}
+
+// OutlineImport is a preview of importing an outline, nothing is changed until it's applied
+type OutlineImport struct {
+	Outline *Outline
+	// Change for each item in the outline, in the same order
+	Changes  []OutlineChange
+	commands []Command
+}
+
+// outlineText gets html as plain text on a single line, to compare it to text in an outline
+func outlineText(text string) string {
+	return strings.Join(strings.Fields(PlainText(text)), " ")
+}
+
+// PlanOutlineImport creates a preview of importing an outline, assigning uids to new items.
+// Items with a uid that already exists are updated, including what parent they have, except
+// roots in the outline, which are only unlinked if their parent is also in the outline.
+func (data *DataContext) PlanOutlineImport(outline *Outline) (*OutlineImport, error) {
+	items, err := data.LoadItems()
+	if err != nil {
+		return nil, err
+	}
+	byItem := make(map[Item]int64)
+	bottom := 0
+	for _, item := range items {
+		byItem[item.Item] = item.UID
+		if item.Y+item.Height+layoutRowHeight/2 > bottom {
+			bottom = item.Y + item.Height + layoutRowHeight/2
+		}
+	}
+	// Parent of every item after importing, to find loops with items not in the outline
+	parents := make(map[int64]int64)
+	for _, item := range items {
+		if item.Parent != nil {
+			parents[item.UID] = byItem[item.Parent]
+		}
+	}
+	inOutline := make(map[int64]bool)
+	for i := range outline.Items {
+		if !outline.Items[i].HasUID {
+			uid := data.ItemUID()
+			for inOutline[uid] || data.UIDExists(uid) {
+				uid = data.ItemUID()
+			}
+			outline.Items[i].UID = uid
+		}
+		inOutline[outline.Items[i].UID] = true
+	}
+	outlineImport := &OutlineImport{Outline: outline}
+	edits, unlinks, links := make([]Command, 0), make([]Command, 0), make([]Command, 0)
+	for _, item := range outline.Items {
+		change := OutlineChange{Item: item, Action: OutlineKeep}
+		existing, err := data.ItemByUID(item.UID)
+		if err != nil {
+			// New items are added, also when their uid is from another project
+			change.Action = OutlineAdd
+			change.Snapshot = ItemSnapshot{
+				ItemData: ItemData{
+					UID:         item.UID,
+					Description: TextToHTML(item.Description),
+					Width:       128,
+					Height:      64,
+				},
+				Type: item.Type,
+			}
+			if item.Type == TypeRequirement {
+				change.Snapshot.Rationale = TextToHTML(item.Rationale)
+				change.Snapshot.FitCriterion = TextToHTML(item.FitCriterion)
+			}
+			if item.Parent >= 0 {
+				change.Snapshot.HasParent, change.Snapshot.ParentUID = true, outline.Items[item.Parent].UID
+				parents[item.UID] = change.Snapshot.ParentUID
+			}
+			outlineImport.Changes = append(outlineImport.Changes, change)
+			continue
+		}
+		before, err := data.Snapshot(existing)
+		if err != nil {
+			return nil, err
+		}
+		// Formatting is kept when the text is the same
+		after := before
+		after.Type = item.Type
+		if outlineText(before.Description) != strings.Join(strings.Fields(item.Description), " ") {
+			after.Description = TextToHTML(item.Description)
+		}
+		if item.Type == TypeRequirement {
+			if outlineText(before.Rationale) != strings.Join(strings.Fields(item.Rationale), " ") {
+				after.Rationale = TextToHTML(item.Rationale)
+			}
+			if outlineText(before.FitCriterion) != strings.Join(strings.Fields(item.FitCriterion), " ") {
+				after.FitCriterion = TextToHTML(item.FitCriterion)
+			}
+		} else {
+			after.Rationale, after.FitCriterion = "", ""
+		}
+		if after.Type != before.Type || after.Description != before.Description ||
+			after.Rationale != before.Rationale || after.FitCriterion != before.FitCriterion {
+			change.Action = OutlineUpdate
+			edits = append(edits, NewEditItemCommand(before, after))
+		}
+		hasParent, parentUID := before.HasParent, before.ParentUID
+		if item.Parent >= 0 {
+			hasParent, parentUID = true, outline.Items[item.Parent].UID
+		} else if before.HasParent && inOutline[before.ParentUID] {
+			hasParent = false
+		}
+		if hasParent != before.HasParent || (hasParent && parentUID != before.ParentUID) {
+			change.Action = OutlineUpdate
+			if before.HasParent {
+				unlinks = append(unlinks, NewRemoveLinkCommand(before.ParentUID, item.UID))
+			}
+			if hasParent {
+				links = append(links, NewAddLinkCommand(parentUID, item.UID))
+			}
+		}
+		delete(parents, item.UID)
+		if hasParent {
+			parents[item.UID] = parentUID
+		}
+		after.HasParent, after.ParentUID = hasParent, parentUID
+		change.Snapshot = after
+		outlineImport.Changes = append(outlineImport.Changes, change)
+	}
+	// Moving items in the outline can't link them to their own children in the project
+	for _, item := range outline.Items {
+		uid, found := item.UID, true
+		for steps := 0; found && steps <= len(parents); steps++ {
+			if uid, found = parents[uid]; found && uid == item.UID {
+				return nil, fmt.Errorf("line %v: item would be linked to itself in a loop", item.Line)
+			}
+		}
+	}
+	// Place new items as trees below everything else, where items with an existing parent are roots
+	roots := make([]Item, 0)
+	children := make(map[Item][]Item)
+	keys := make(map[int64]Item)
+	for i, change := range outlineImport.Changes {
+		if change.Action != OutlineAdd {
+			continue
+		}
+		key := NewItem(int64(i+1), TypeRequirement)
+		keys[change.Snapshot.UID] = key
+		if parent, found := keys[change.Snapshot.ParentUID]; change.Snapshot.HasParent && found {
+			children[parent] = append(children[parent], key)
+		} else {
+			roots = append(roots, key)
+		}
+	}
+	positions := LayoutTree(roots, children)
+	adds := make([]Command, 0, len(keys))
+	for i := range outlineImport.Changes {
+		change := &outlineImport.Changes[i]
+		if change.Action != OutlineAdd {
+			continue
+		}
+		pos := positions[keys[change.Snapshot.UID]]
+		change.Snapshot.X, change.Snapshot.Y = pos[0], bottom+pos[1]
+		adds = append(adds, NewAddItemCommand(change.Snapshot))
+	}
+	// Edits first, as a changed type recreates the item, then links to items that now exist
+	outlineImport.commands = append(append(append(edits, unlinks...), adds...), links...)
+	return outlineImport, nil
+}
+
+// Count gets the number of items with the specified action
+func (outlineImport *OutlineImport) Count(action string) int {
+	count := 0
+	for _, change := range outlineImport.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Command gets the command adding and updating all items, or nil if nothing changed
+func (outlineImport *OutlineImport) Command() Command {
+	if len(outlineImport.commands) == 0 {
+		return nil
+	}
+	return NewCommandGroup("Import Outline", outlineImport.commands...)
+}
+
+// WriteOutline writes all items as a nested list, with their uids, that can be edited and imported again
+func (data *DataContext) WriteOutline(writer io.Writer) error {
+	items, err := data.LoadItems()
+	if err != nil {
+		return err
+	}
+	graph := NewGraphFromItems(items)
+	byItem := make(map[Item]ItemData)
+	for _, item := range items {
+		byItem[item.Item] = item
+	}
+	written := make(map[Item]bool)
+	var builder strings.Builder
+	var write func(item Item, depth int)
+	write = func(item Item, depth int) {
+		if written[item] {
+			return
+		}
+		written[item] = true
+		itemData := byItem[item]
+		indent := strings.Repeat("  ", depth)
+		prefix := "P"
+		if GetItemType(item) == TypeSolution {
+			prefix = "S"
+		}
+		if depth == 0 && builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+		fmt.Fprintf(&builder, "%v- %v: %v <!-- uid: %v -->\n", indent, prefix,
+			outlineText(itemData.Description), FormatUID(itemData.UID))
+		if GetItemType(item) == TypeRequirement {
+			if text := outlineText(itemData.Rationale); text != "" {
+				fmt.Fprintf(&builder, "\n%v  Rationale: %v\n", indent, text)
+			}
+			if text := outlineText(itemData.FitCriterion); text != "" {
+				fmt.Fprintf(&builder, "\n%v  Fit criterion: %v\n", indent, text)
+			}
+		}
+		for _, child := range graph.Children(item) {
+			write(child, depth+1)
+		}
+	}
+	for _, item := range append(graph.Roots(), graph.Items()...) {
+		write(item, 0)
+	}
+	_, err = io.WriteString(writer, builder.String())
+	return err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOutline(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	db := project.Data()
+	existingUID := db.ItemUID()
+	_, _ = db.AddRequirement("Ferry must be safe", "", "", existingUID)
+	// Headings and lists are parents, with types from prefixes and markers
+	text := "# Cars must board quickly\r\n" +
+		"Short stops\r\n" +
+		"\r\n" +
+		"Fit criterion: Under\r\n" +
+		"10 minutes\r\n" +
+		"\r\n" +
+		"- S: Bow ramp\r\n" +
+		"  - Ramp must not leak\r\n" +
+		"\r\n" +
+		"    Rationale: Water on the car deck\r\n" +
+		"  + Stern ramp\r\n" +
+		"\r\n" +
+		"Notes that aren't items\r\n" +
+		"```\r\n" +
+		"- not an item\r\n" +
+		"```\r\n" +
+		"## Passengers need seats\r\n"
+	outline, err := ParseOutline(strings.NewReader(text))
+	if err != nil {
+		t.Error("failed to parse outline:", err)
+		return
+	}
+	results := make([]string, len(outline.Items))
+	for i, item := range outline.Items {
+		results[i] = fmt.Sprintf("%v %v %v (%v|%v)", item.Parent, GetItemName(NewItem(0, item.Type)),
+			item.Description, item.Rationale, item.FitCriterion)
+	}
+	if result := strings.Join(results, ", "); result != "-1 Problem Cars must board quickly (Short stops|Under 10 minutes), "+
+		"0 Solution Bow ramp (|), 1 Problem Ramp must not leak (Water on the car deck|), "+
+		"1 Solution Stern ramp (|), 0 Problem Passengers need seats (|)" {
+		t.Error("unexpected outline, got", result)
+	}
+	for _, invalid := range []string{"- P:\n", "- S: Ramp\n\n  Rationale: Cheap\n", "- A <!-- uid: xyz -->\n",
+		"- A <!-- uid: 1f -->\n- B <!-- uid: 1f -->\n"} {
+		if _, err := ParseOutline(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected \"%v\" to fail", invalid)
+		}
+	}
+	// Importing adds all items, and writes their uids back
+	outlineImport, err := db.PlanOutlineImport(outline)
+	if err != nil {
+		t.Error("failed to plan import:", err)
+		return
+	}
+	if added := outlineImport.Count(OutlineAdd); added != 5 {
+		t.Error("expected 5 new items, but got", added)
+	}
+	history := NewHistory()
+	if err := history.Do(db, outlineImport.Command()); err != nil {
+		t.Error("failed to import:", err)
+		return
+	}
+	var buffer bytes.Buffer
+	if err := outline.Write(&buffer); err != nil {
+		t.Error("failed to write outline:", err)
+	}
+	written := strings.Split(buffer.String(), "\n")
+	bowRamp := outline.Items[1].UID
+	if expected := fmt.Sprintf("- S: Bow ramp <!-- uid: %v -->\r", FormatUID(bowRamp)); written[6] != expected ||
+		written[1] != "Short stops\r" {
+		t.Error("expected uids to be written back, but got", written)
+	}
+	// Importing the same outline again doesn't change anything
+	outline, _ = ParseOutline(&buffer)
+	if outlineImport, err = db.PlanOutlineImport(outline); err != nil || outlineImport.Command() != nil {
+		t.Error("expected nothing to change, but got", outlineImport, err)
+	}
+	// Moving an item and changing text updates it, and keeps all uids
+	edited := strings.NewReplacer("Ramp must not leak", "Ramp must never leak", "  + Stern ramp", "      + Stern ramp").
+		Replace(strings.Join(written, "\n"))
+	edited += fmt.Sprintf("# Ferry must be safe <!-- uid: %v -->\n", FormatUID(existingUID))
+	outline, _ = ParseOutline(strings.NewReader(edited))
+	if outlineImport, err = db.PlanOutlineImport(outline); err != nil {
+		t.Error("failed to plan import:", err)
+		return
+	}
+	if updated := outlineImport.Count(OutlineUpdate); updated != 2 || outlineImport.Count(OutlineAdd) != 0 {
+		t.Error("expected 2 updated items, but got", outlineImport.Changes)
+	}
+	if err := history.Do(db, outlineImport.Command()); err != nil {
+		t.Error("failed to import:", err)
+	}
+	ramp, _ := db.ItemByUID(outline.Items[2].UID)
+	if snapshot, _ := db.Snapshot(ramp); snapshot.Description != "Ramp must never leak" ||
+		snapshot.ParentUID != bowRamp || snapshot.Rationale != "Water on the car deck" {
+		t.Error("unexpected updated item, got", snapshot)
+	}
+	sternRamp, _ := db.ItemByUID(outline.Items[3].UID)
+	if snapshot, _ := db.Snapshot(sternRamp); snapshot.ParentUID != outline.Items[2].UID {
+		t.Error("expected item to be moved, but got", snapshot)
+	}
+	if items, _ := db.LoadItems(); len(items) != 6 {
+		t.Error("expected no new items, but got", items)
+	}
+	// Exported outlines have all items, and import without changes
+	buffer.Reset()
+	if err := db.WriteOutline(&buffer); err != nil {
+		t.Error("failed to write outline:", err)
+	}
+	if !strings.Contains(buffer.String(), "  - S: Bow ramp <!-- uid: ") ||
+		!strings.Contains(buffer.String(), "\n  Fit criterion: Under 10 minutes\n") {
+		t.Error("unexpected exported outline, got", buffer.String())
+	}
+	outline, err = ParseOutline(&buffer)
+	if err != nil || len(outline.Items) != 6 {
+		t.Error("failed to parse exported outline, got", outline, err)
+		return
+	}
+	if outlineImport, err = db.PlanOutlineImport(outline); err != nil || outlineImport.Command() != nil {
+		t.Error("expected exported outline to not change anything, but got", outlineImport, err)
+	}
+}
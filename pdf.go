@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Size of pages in pdf documents, A4 in points, and the margin on all sides
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 56
+	pdfFontSize   = 11
+)
+
+// pdfWidths are the widths of the printable ascii characters in Helvetica, in 1/1000 of the font size
+var pdfWidths = []int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// pdfWinAnsi are characters in WinAnsiEncoding that are not at the same position as in unicode
+var pdfWinAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfEncode converts text to WinAnsiEncoding, used by the standard fonts,
+// where characters that can't be encoded are replaced with a question mark
+func pdfEncode(text string) []byte {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		if b, found := pdfWinAnsi[r]; found {
+			encoded = append(encoded, b)
+		} else if r < 0x80 || (r >= 0xa0 && r <= 0xff) {
+			encoded = append(encoded, byte(r))
+		} else {
+			encoded = append(encoded, '?')
+		}
+	}
+	return encoded
+}
+
+// pdfTextWidth gets the width of text in points, where bold text is assumed to be slightly wider
+func pdfTextWidth(text string, size float64, bold bool) float64 {
+	width := 0
+	for _, r := range text {
+		if r >= 32 && r < 127 {
+			width += pdfWidths[r-32]
+		} else {
+			width += 556
+		}
+	}
+	if bold {
+		width = width * 11 / 10
+	}
+	return float64(width) * size / 1000
+}
+
+// pdfString gets text as a pdf string literal
+func pdfString(text string) string {
+	var builder strings.Builder
+	builder.WriteByte('(')
+	for _, b := range pdfEncode(text) {
+		switch {
+		case b == '(' || b == ')' || b == '\\':
+			builder.WriteByte('\\')
+			builder.WriteByte(b)
+		case b < 0x20 || b >= 0x80:
+			fmt.Fprintf(&builder, "\\%03o", b)
+		default:
+			builder.WriteByte(b)
+		}
+	}
+	builder.WriteByte(')')
+	return builder.String()
+}
+
+// pdfWrap splits text into lines that fit within width, keeping existing line breaks
+func pdfWrap(text string, size, width float64, bold bool) []string {
+	lines := make([]string, 0)
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.FieldsFunc(paragraph, unicode.IsSpace) {
+			next := word
+			if line != "" {
+				next = line + " " + word
+			}
+			if pdfTextWidth(next, size, bold) <= width {
+				line = next
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// Words longer than a line are split anywhere
+			line = ""
+			for _, r := range word {
+				if line != "" && pdfTextWidth(line+string(r), size, bold) > width {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// PDFDocument is a simple pdf document of headings and paragraphs,
+// using only the standard Helvetica fonts, so no fonts need to be embedded
+type PDFDocument struct {
+	title string
+	// Content stream of each page
+	pages []*bytes.Buffer
+	// Position from the bottom of the current page
+	y float64
+}
+
+// NewPDFDocument creates a new empty document
+func NewPDFDocument(title string) *PDFDocument {
+	return &PDFDocument{title: title}
+}
+
+// line adds a line of text, starting a new page if needed
+func (pdf *PDFDocument) line(x float64, text string, size float64, bold bool) {
+	height := size * 1.4
+	if len(pdf.pages) == 0 || pdf.y-height < pdfMargin {
+		pdf.pages = append(pdf.pages, new(bytes.Buffer))
+		pdf.y = pdfPageHeight - pdfMargin
+	}
+	pdf.y -= height
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(pdf.pages[len(pdf.pages)-1], "BT /%v %v Tf %.2f %.2f Td %v Tj ET\n",
+		font, size, pdfMargin+x, pdf.y, pdfString(text))
+}
+
+// space adds empty space before the next line, if not at the top of a page
+func (pdf *PDFDocument) space(height float64) {
+	if len(pdf.pages) > 0 && pdf.y < pdfPageHeight-pdfMargin {
+		pdf.y -= height
+	}
+}
+
+// Heading adds a bold heading, where level 0 is the title of the document
+func (pdf *PDFDocument) Heading(text string, level int) {
+	size := 12.0
+	switch level {
+	case 0:
+		size = 20
+	case 1:
+		size = 16
+	case 2:
+		size = 14
+	}
+	pdf.space(size)
+	// Keep the heading on the same page as at least a few lines of text
+	if pdf.y-size*1.4-pdfFontSize*1.4*3 < pdfMargin {
+		pdf.y = 0
+	}
+	for _, line := range pdfWrap(text, size, pdfPageWidth-2*pdfMargin, true) {
+		pdf.line(0, line, size, true)
+	}
+	pdf.space(size / 2)
+}
+
+// Paragraph adds text, with an optional bold label before it, indented from the margin
+func (pdf *PDFDocument) Paragraph(label, text string, indent float64) {
+	width := pdfPageWidth - 2*pdfMargin - indent
+	labelWidth := 0.0
+	if label != "" {
+		labelWidth = pdfTextWidth(label+" ", pdfFontSize, true)
+	}
+	// The label is on the same line as the start of the text
+	if label != "" {
+		pdf.line(indent, label, pdfFontSize, true)
+		if text != "" {
+			pdf.y += pdfFontSize * 1.4
+		}
+	}
+	if text != "" {
+		for _, line := range pdfWrap(text, pdfFontSize, width-labelWidth, false) {
+			pdf.line(indent+labelWidth, line, pdfFontSize, false)
+		}
+	}
+	pdf.space(pdfFontSize / 2)
+}
+
+// Write writes the document, with the cross-reference table pointing to each object
+func (pdf *PDFDocument) Write(writer io.Writer) error {
+	if len(pdf.pages) == 0 {
+		pdf.pages = append(pdf.pages, new(bytes.Buffer))
+	}
+	// Catalog, page tree, fonts and info first, and then each page with its contents
+	kids := make([]string, len(pdf.pages))
+	for i := range pdf.pages {
+		kids[i] = fmt.Sprintf("%v 0 R", 6+i*2)
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%v] /Count %v >>", strings.Join(kids, " "), len(pdf.pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title %v /Producer (OpenRQ) >>", pdfString(pdf.title)),
+	}
+	for i, page := range pdf.pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %v %v] "+
+				"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %v 0 R >>",
+				pdfPageWidth, pdfPageHeight, 7+i*2),
+			fmt.Sprintf("<< /Length %v >>\nstream\n%v\nendstream", page.Len(), page.String()))
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buffer.Len()
+		fmt.Fprintf(&buffer, "%v 0 obj\n%v\nendobj\n", i+1, object)
+	}
+	xref := buffer.Len()
+	fmt.Fprintf(&buffer, "xref\n0 %v\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buffer, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buffer, "trailer\n<< /Size %v /Root 1 0 R /Info 5 0 R >>\nstartxref\n%v\n%%%%EOF\n",
+		len(objects)+1, xref)
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
@@ -3,11 +3,12 @@ package main
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/sha1"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 )
 
@@ -16,62 +17,279 @@ var currentProject *Project
 type Project struct {
 	Open bool
 	path string
+	// Connection to the project, kept open until the project is closed
+	data *DataContext
+	// When viewing a baseline, the project it's from and the name of it
+	source   string
+	baseline string
+	// Temporary directory removed when closing the project
+	tempDir string
+	// Compressed project opened through a working copy in path,
+	// and the hash of what was last written to it, to only write changes
+	compressed     string
+	compressedHash string
 }
 
-func NewProject(path string) *Project {
+func NewProject(path string) (*Project, error) {
+	// Changes in compressed projects from before a crash are only used when asked to, see NewCompressedProject
+	if strings.HasSuffix(path, ".orqz") {
+		if currentProject != nil && currentProject.compressed == path {
+			if err := currentProject.Close(); err != nil {
+				return nil, err
+			}
+		}
+		recover, err := CompressedRecovery(path)
+		if err != nil {
+			return nil, err
+		}
+		if recover {
+			return nil, fmt.Errorf("project has changes that were not saved to it, " +
+				"choose to recover or discard them before opening it")
+		}
+		return NewCompressedProject(path, false)
+	}
+	if !strings.HasSuffix(path, ".orq") {
+		path += ".orq"
+	}
+	// Create, or upgrade, the project before using it
+	db, err := OpenDataContext(path)
+	if err != nil {
+		return nil, err
+	}
+	// Close previous project, if any
+	if currentProject != nil {
+		if err := currentProject.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "warning: failed to close previous project:", err)
+		}
+	}
 	currentProject = new(Project)
 	currentProject.Open = true
 	currentProject.path = path
-	if !strings.HasSuffix(path, ".orq") {
-		path += ".orq"
+	currentProject.data = db
+	return currentProject, nil
+}
+
+// CompressedWorkingCopy gets the path of the working copy of a compressed project.
+// The working copy only exists while the project is open, or if it wasn't closed properly.
+func CompressedWorkingCopy(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
 	}
-	NewDataContext(path).Close()
-	return currentProject
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	// Only the current user should be able to read the working copies
+	workDir := filepath.Join(cacheDir, "OpenRQ", "compressed")
+	if err := os.MkdirAll(workDir, 0700); err != nil {
+		return "", err
+	}
+	hash := sha1.Sum([]byte(abs))
+	return filepath.Join(workDir, fmt.Sprintf("%x-%v", hash[:8],
+		strings.TrimSuffix(filepath.Base(abs), ".orqz")+".orq")), nil
 }
 
-func NewCompressedProject(path string) (*Project, error) {
-	// Name of final output file
-	newPath := path[0:len(path)-1]
-	// Check if decompressed file already exists
-	_, err := os.Stat(newPath)
-	if !os.IsNotExist(err) {
-		return nil, fmt.Errorf("file with name \"%v\" already exists", newPath)
+// CompressedRecovery checks if a compressed project has a working copy with changes that weren't written to it
+func CompressedRecovery(path string) (bool, error) {
+	workPath, err := CompressedWorkingCopy(path)
+	if err != nil {
+		return false, err
+	}
+	working, err := ioutil.ReadFile(workPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	compressed, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, err
 	}
-	// Get file permissions of original file for later
-	fileInfo, err := os.Stat(path)
+	decompressed, err := Decompress(compressed)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(working, decompressed), nil
+}
+
+// NewCompressedProject opens a compressed project through a working copy, that is written back to it
+// when saving or closing the project. If recover is set, an existing working copy with changes that weren't
+// written back is used instead of the compressed project. The compressed project is never removed.
+func NewCompressedProject(path string, recover bool) (*Project, error) {
+	workPath, err := CompressedWorkingCopy(path)
 	if err != nil {
 		return nil, err
 	}
-	// Load compressed file
+	// Opening the same project again writes it back first, as the working copy is replaced
+	if currentProject != nil && currentProject.path == workPath {
+		if err := currentProject.Close(); err != nil {
+			return nil, err
+		}
+	}
 	compressed, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	// Decompress it
 	decompressed, err := Decompress(compressed)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to decompress project: %v", err)
 	}
-	// Create new decompressed copy
-	if err := ioutil.WriteFile(newPath, decompressed, fileInfo.Mode()); err != nil {
-		return nil, err
+	_, err = os.Stat(workPath)
+	created := os.IsNotExist(err) || !recover
+	if created {
+		if err := writeFileAtomic(workPath, decompressed, 0600); err != nil {
+			return nil, err
+		}
 	}
-	// Remove old project
-	if err := os.Remove(path); err != nil {
+	project, err := NewProject(workPath)
+	if err != nil {
+		// Recovered changes are kept, even if they can't be opened right now
+		if created {
+			os.Remove(workPath)
+		}
 		return nil, err
 	}
-	// Everything seems fine, load newly created project
-	return NewProject(newPath), nil
+	project.compressed = path
+	project.compressedHash = fmt.Sprintf("%x", sha1.Sum(decompressed))
+	return project, nil
+}
+
+// writeFileAtomic writes to a temporary file, that replaces path once everything is written,
+// so path always has either the old or the new contents
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	temp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
+
+// FilePath gets the path of the project as opened by the user, the compressed project for compressed projects
+func (proj *Project) FilePath() string {
+	if proj.compressed != "" {
+		return proj.compressed
+	}
+	return proj.path
+}
+
+// Save writes changes back to the compressed project, other projects are saved when changed
+func (proj *Project) Save() error {
+	if proj.compressed == "" || !proj.Open {
+		return nil
+	}
+	return proj.writeCompressed()
 }
 
+// writeCompressed writes the working copy to the compressed project, if it changed
+func (proj *Project) writeCompressed() error {
+	data, err := ioutil.ReadFile(proj.path)
+	if err != nil {
+		return err
+	}
+	hash := fmt.Sprintf("%x", sha1.Sum(data))
+	if hash == proj.compressedHash {
+		return nil
+	}
+	compressed, err := Compress(data)
+	if err != nil {
+		return fmt.Errorf("failed to compress project: %v", err)
+	}
+	mode := os.FileMode(0644)
+	if fileInfo, err := os.Stat(proj.compressed); err == nil {
+		mode = fileInfo.Mode()
+	}
+	if err := writeFileAtomic(proj.compressed, compressed, mode); err != nil {
+		return fmt.Errorf("failed to save compressed project, changes are kept in \"%v\": %v", proj.path, err)
+	}
+	proj.compressedHash = hash
+	return nil
+}
+
+// Data gets the connection to the project, which should not be closed
 func (proj *Project) Data() *DataContext {
-	return NewDataContext(proj.path)
+	return proj.data
+}
+
+// Close closes the connection to the project
+func (proj *Project) Close() error {
+	if !proj.Open {
+		return nil
+	}
+	proj.Open = false
+	err := proj.data.Close()
+	// The working copy is only removed once everything is written back
+	if proj.compressed != "" && err == nil {
+		if err = proj.writeCompressed(); err == nil {
+			err = os.Remove(proj.path)
+		}
+	}
+	if proj.tempDir != "" {
+		if removeErr := os.RemoveAll(proj.tempDir); removeErr != nil && err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
+
+// ReadOnly checks if the project is a baseline that can't be changed
+func (proj *Project) ReadOnly() bool {
+	return proj.baseline != ""
+}
+
+// Baseline gets the name of the baseline being viewed, and the project it's from
+func (proj *Project) Baseline() (name, source string) {
+	return proj.baseline, proj.source
 }
 
 func (proj *Project) Name() string {
-	db := proj.Data()
-	defer db.Close()
-	return db.ProjectName()
+	return proj.Data().ProjectName()
+}
+
+// SaveJSON exports all items and links in the graph as a json file
+func (proj *Project) SaveJSON(path string, graph *Graph) error {
+	// All labels are saved, even if no item uses them
+	labels, err := proj.Data().LabelsJSON()
+	if err != nil {
+		return err
+	}
+	rules, err := proj.Data().RulesJSON()
+	if err != nil {
+		return err
+	}
+	baselines, err := proj.Data().BaselinesJSON()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"Version":     JSONVersion,
+		"ProjectName": proj.Name(),
+		"Created":     proj.Data().ProjectCreated(),
+		"Labels":      labels,
+		"Rules":       rules,
+		"Baselines":   baselines,
+		"Tree":        graph.JSONTree(proj.Data()),
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
 }
 
 func Compress(data []byte) ([]byte, error) {
@@ -116,91 +334,44 @@ func (proj *Project) CopyTo(path string) error {
 	return ioutil.WriteFile(path, file, fileInfo.Mode())
 }
 
-func JSONObjectToItem(data map[string]interface{}, db *DataContext) (Item, error) {
-	_, ok := data["Rationale"]
-	uid, err := strconv.ParseInt(data["ID"].(string), 16, 64)
+// ImportJSON creates a new project in newPath from the json file in path.
+// Nothing is created if anything in the file is invalid.
+func ImportJSON(path, newPath string) (*Project, error) {
+	jsonImport, err := ReadJSONFile(path)
 	if err != nil {
 		return nil, err
 	}
-	if ok {
-		// Has rationale, probably requirement
-		id, err := db.AddRequirement(data["Description"].(string), data["Rationale"].(string), data["FitCriterion"].(string), uid)
+	return jsonImport.CreateProject(newPath)
+}
+
+// OpenProjectCopy opens a temporary copy of a project in any supported format, .orq, .orqz, .json or .reqif,
+// without changing the file or the current project. The copy is removed when closed.
+func OpenProjectCopy(path string) (*DataContext, error) {
+	switch filepath.Ext(path) {
+	case ".orq":
+		return OpenDataContextCopy(path)
+	case ".orqz":
+		compressed, err := ioutil.ReadFile(path)
 		if err != nil {
 			return nil, err
 		}
-		return NewRequirement(id), nil
-	} else {
-		// Hasn't rationale, probably solution
-		id, err := db.AddSolution(data["Description"].(string), uid)
+		decompressed, err := Decompress(compressed)
 		if err != nil {
 			return nil, err
 		}
-		return NewSolution(id), nil
-	}
-}
-
-func ParseJSON(parent Item, db *DataContext, tree map[string]interface{}) error {
-	// Add to DB
-	item, err := JSONObjectToItem(tree, db)
-	if err != nil {
-		return err
-	}
-	// Set parent if needed
-	if parent != nil {
-		item.SetParent(parent)
-	}
-	// Set position and size
-	pos := tree["Pos"].([]interface{})
-	item.SetPos(int(pos[0].(float64)), int(pos[1].(float64)))
-	// Do the same for children
-	data, ok := tree["Children"].([]interface{})
-	if ok {
-		for _, child := range data {
-			dataMap := child.(map[string]interface{})
-			if err = ParseJSON(item, db, dataMap); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
-func NewJSONProject(path string) (*Project, error) {
-	// Try to read file
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	// Try to parse json
-	var jsonData map[string]interface{}
-	if err := json.Unmarshal(data, &jsonData); err != nil {
-		return nil, err
-	}
-	// Check if valid project
-	projectName, found := jsonData["ProjectName"]
-	if !found {
-		return nil, fmt.Errorf("not a valid project")
-	}
-	// Check if destination file already exists
-	newPath := path[0:len(path)-4] + "orq"
-	_, err = os.Stat(newPath)
-	if !os.IsNotExist(err) {
-		return nil, fmt.Errorf("file with name \"%v\" already exists", newPath)
-	}
-	// Create new empty project
-	NewProject(newPath)
-	db := currentProject.Data()
-	// Set project name
-	db.SetProjectName(projectName.(string))
-	// Parse each root
-	for _, root := range jsonData["Tree"].([]interface{}) {
-		if err = ParseJSON(nil, db, root.(map[string]interface{})); err != nil {
+		return OpenTempDataContext(func(copyPath string) error {
+			return ioutil.WriteFile(copyPath, decompressed, 0600)
+		})
+	case ".json":
+		jsonImport, err := ReadJSONFile(path)
+		if err != nil {
 			return nil, err
 		}
+		return OpenTempDataContext(jsonImport.WriteProject)
+	case ".reqif":
+		return OpenTempDataContext(func(copyPath string) error {
+			return WriteReqIFProject(path, copyPath)
+		})
 	}
-	// Everything is hopefully fine
-	if err := db.Close(); err != nil {
-		fmt.Println("failed to close temporary database from json:", err)
-	}
-	return currentProject, nil
-}
\ No newline at end of file
+	return nil, fmt.Errorf("unknown project format \"%v\"", filepath.Ext(path))
+}
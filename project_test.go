@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCompressedProject(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	// Working copies are kept in the cache directory
+	t.Setenv("XDG_CACHE_HOME", fmt.Sprintf("%v/cache", tempDir))
+	t.Setenv("HOME", tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	_, _ = project.Data().AddRequirement("Ferry must be safe", "", "", 0x10)
+	path := fmt.Sprintf("%v/openrq_test.orqz", tempDir)
+	if err := project.CopyTo(path); err != nil {
+		t.Error("failed to compress project:", err)
+		return
+	}
+	project.Close()
+	_ = os.Chmod(path, 0640)
+	// Items in the compressed project, read from a separate copy
+	count := func() int {
+		compressed, _ := ioutil.ReadFile(path)
+		decompressed, err := Decompress(compressed)
+		if err != nil {
+			t.Error("failed to decompress project:", err)
+			return -1
+		}
+		copyPath := fmt.Sprintf("%v/openrq_copy.orq", tempDir)
+		_ = ioutil.WriteFile(copyPath, decompressed, 0644)
+		defer os.Remove(copyPath)
+		db, err := OpenDataContext(copyPath)
+		if err != nil {
+			t.Error("failed to open project:", err)
+			return -1
+		}
+		defer db.Close()
+		items, _ := db.LoadItems()
+		return len(items)
+	}
+	// The compressed project is opened in place, and only changed when saved
+	project, err = NewProject(path)
+	if err != nil {
+		t.Error("failed to open compressed project:", err)
+		return
+	}
+	if project.FilePath() != path || project.path == path {
+		t.Error("expected project to be opened through a working copy, but got", project.path)
+	}
+	_, _ = project.Data().AddSolution("Bow ramp", 0x20)
+	if items := count(); items != 1 {
+		t.Error("expected compressed project to be unchanged before saving, but got", items, "items")
+	}
+	if err := project.Save(); err != nil {
+		t.Error("failed to save compressed project:", err)
+	}
+	if items := count(); items != 2 {
+		t.Error("expected saved item, but got", items, "items")
+	}
+	if fileInfo, err := os.Stat(path); err != nil || fileInfo.Mode() != 0640 {
+		t.Error("expected compressed project to keep its permissions, but got", fileInfo, err)
+	}
+	// Changes in the working copy can be recovered after a crash
+	workPath := project.path
+	_, _ = project.Data().AddSolution("Stern ramp", 0x30)
+	_ = project.data.Close()
+	currentProject = nil
+	if recover, err := CompressedRecovery(path); err != nil || !recover {
+		t.Error("expected changes to recover, but got", recover, err)
+	}
+	// Only when asked to, as opening it without asking would use changes the user doesn't know about
+	if _, err := NewProject(path); err == nil {
+		t.Error("expected opening project with changes to recover to fail")
+	}
+	if project, err = NewCompressedProject(path, true); err != nil {
+		t.Error("failed to recover compressed project:", err)
+		return
+	}
+	if err := project.Close(); err != nil {
+		t.Error("failed to close compressed project:", err)
+	}
+	if items := count(); items != 3 {
+		t.Error("expected recovered item to be saved when closing, but got", items, "items")
+	}
+	if _, err := os.Stat(workPath); !os.IsNotExist(err) {
+		t.Error("expected working copy to be removed when closing")
+	}
+	if recover, err := CompressedRecovery(path); err != nil || recover {
+		t.Error("expected nothing to recover, but got", recover, err)
+	}
+	// Or discarded, opening the compressed project as it was saved
+	project, _ = NewProject(path)
+	_, _ = project.Data().AddSolution("Pumps", 0x40)
+	_ = project.data.Close()
+	currentProject = nil
+	if project, err = NewCompressedProject(path, false); err != nil {
+		t.Error("failed to open compressed project:", err)
+		return
+	}
+	defer project.Close()
+	if items, _ := project.Data().LoadItems(); len(items) != 3 {
+		t.Error("expected changes to be discarded, but got", len(items), "items")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("expected compressed project to never be removed:", err)
+	}
+}
@@ -0,0 +1,676 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// JSONVersion is the version of the json format written by this version.
+// Version 1, without a version field, only has items, labels and media, version 2 has everything in a project.
+const JSONVersion = 2
+
+// RuleData is the settings of a validation rule as exported to json, as they're saved in the project
+type RuleData struct {
+	Tag      string
+	Enabled  bool
+	Severity string
+	Data     string
+}
+
+// BaselineItemData is an item frozen in a baseline as exported to json
+type BaselineItemData struct {
+	ID string
+	// Revision of the item, increased every time it changed when freezing
+	Revision                             int
+	Type                                 string
+	Parent                               string `json:",omitempty"`
+	Description, Rationale, FitCriterion string
+	Link                                 string
+	// Color, border and shape
+	Look []uint
+	Pos  []int
+	Size []int
+}
+
+// BaselineData is a baseline, with all its items, as exported to json
+type BaselineData struct {
+	ID      string
+	Name    string
+	Created string
+	Size    []int `json:",omitempty"`
+	Items   []BaselineItemData
+}
+
+// ProjectCreated gets when the project was created
+func (data *DataContext) ProjectCreated() string {
+	var created string
+	if err := data.conn().QueryRow("select coalesce(created, '') from Info").Scan(&created); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: failed to get project creation date:", err)
+	}
+	return created
+}
+
+// SetProjectCreated sets when the project was created, when importing it
+func (data *DataContext) SetProjectCreated(created string) error {
+	_, err := data.conn().Exec("update Info set created = ?", created)
+	return err
+}
+
+// RulesJSON gets the settings of all validation rules saved in the project as they should be exported to json
+func (data *DataContext) RulesJSON() ([]RuleData, error) {
+	rows, err := data.conn().Query("select coalesce(tag, ''), coalesce(enabled, 1), coalesce(severity, ''), " +
+		"coalesce(data, '') from ValidationRules order by _rowid_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get validation rules: %v", err)
+	}
+	defer rows.Close()
+	rules := make([]RuleData, 0)
+	for rows.Next() {
+		var rule RuleData
+		if err := rows.Scan(&rule.Tag, &rule.Enabled, &rule.Severity, &rule.Data); err != nil {
+			return rules, fmt.Errorf("failed to get validation rule: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// AddRuleData saves the settings of a validation rule from a json project
+func (data *DataContext) AddRuleData(rule RuleData) error {
+	_, err := data.conn().Exec("insert into ValidationRules (tag, enabled, severity, data) values (?, ?, ?, ?)",
+		rule.Tag, rule.Enabled, rule.Severity, rule.Data)
+	return err
+}
+
+// BaselinesJSON gets all baselines, oldest first, as they should be exported to json
+func (data *DataContext) BaselinesJSON() ([]BaselineData, error) {
+	baselines, err := data.Baselines()
+	if err != nil {
+		return nil, err
+	}
+	exported := make([]BaselineData, 0, len(baselines))
+	for _, baseline := range baselines {
+		var width, height sql.NullInt64
+		if err := data.conn().QueryRow("select width, height from Projects where _rowid_ = ?",
+			baseline.ID).Scan(&width, &height); err != nil {
+			return exported, fmt.Errorf("failed to get baseline %v: %v", baseline.Name, err)
+		}
+		baselineData := BaselineData{
+			ID:      FormatUID(baseline.UID),
+			Name:    baseline.Name,
+			Created: baseline.Created,
+			Items:   make([]BaselineItemData, 0, baseline.Items),
+		}
+		if width.Valid && height.Valid {
+			baselineData.Size = []int{int(width.Int64), int(height.Int64)}
+		}
+		rows, err := data.conn().Query(fmt.Sprintf("select itemV, %v from ItemVersions "+
+			"where version = ? order by _rowid_", baselineColumns), baseline.ID)
+		if err != nil {
+			return exported, fmt.Errorf("failed to get items in baseline %v: %v", baseline.Name, err)
+		}
+		for rows.Next() {
+			var revision int
+			item, err := scanBaselineItem(scanWith(rows, &revision))
+			if err != nil {
+				rows.Close()
+				return exported, fmt.Errorf("failed to get item in baseline %v: %v", baseline.Name, err)
+			}
+			itemData := BaselineItemData{
+				ID:           FormatUID(item.UID),
+				Revision:     revision,
+				Type:         snapshotName(item),
+				Description:  item.Description,
+				Rationale:    item.Rationale,
+				FitCriterion: item.FitCriterion,
+				Link:         item.Link,
+				Look:         []uint{uint(item.Color), uint(item.Border), uint(item.Shape)},
+				Pos:          []int{item.X, item.Y},
+				Size:         []int{item.Width, item.Height},
+			}
+			if item.HasParent {
+				itemData.Parent = FormatUID(item.ParentUID)
+			}
+			baselineData.Items = append(baselineData.Items, itemData)
+		}
+		if err := rows.Close(); err != nil {
+			return exported, err
+		}
+		exported = append(exported, baselineData)
+	}
+	return exported, nil
+}
+
+// AddBaselineData adds a baseline, with all its items, from a json project
+func (data *DataContext) AddBaselineData(baseline BaselineData) error {
+	uid, err := ParseUID(baseline.ID)
+	if err != nil {
+		return fmt.Errorf("invalid id of baseline %v: %v", baseline.Name, err)
+	}
+	var width, height interface{}
+	if len(baseline.Size) == 2 {
+		width, height = baseline.Size[0], baseline.Size[1]
+	}
+	result, err := data.conn().Exec("insert into Projects (uid, name, created, width, height) values (?, ?, ?, ?, ?)",
+		uid, baseline.Name, baseline.Created, width, height)
+	if err != nil {
+		return err
+	}
+	version, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, item := range baseline.Items {
+		snapshot, err := item.Snapshot()
+		if err != nil {
+			return fmt.Errorf("invalid item in baseline %v: %v", baseline.Name, err)
+		}
+		var parent interface{}
+		if snapshot.HasParent {
+			parent = snapshot.ParentUID
+		}
+		if _, err := data.conn().Exec(fmt.Sprintf("insert into ItemVersions (version, itemV, %v) "+
+			"values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", baselineColumns),
+			version, item.Revision, snapshot.UID, snapshot.Type, parent, snapshot.Description, snapshot.Rationale,
+			snapshot.FitCriterion, snapshot.Link, snapshot.Color, snapshot.Border, snapshot.Shape,
+			snapshot.X, snapshot.Y, snapshot.Width, snapshot.Height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot gets the item as a snapshot, with its parent by uid
+func (item BaselineItemData) Snapshot() (ItemSnapshot, error) {
+	var snapshot ItemSnapshot
+	var err error
+	if snapshot.UID, err = ParseUID(item.ID); err != nil {
+		return snapshot, err
+	}
+	switch item.Type {
+	case "Problem":
+		snapshot.Type = TypeRequirement
+	case "Solution":
+		snapshot.Type = TypeSolution
+	default:
+		return snapshot, fmt.Errorf("unknown type \"%v\" of item %v", item.Type, item.ID)
+	}
+	if item.Parent != "" {
+		if snapshot.ParentUID, err = ParseUID(item.Parent); err != nil {
+			return snapshot, err
+		}
+		snapshot.HasParent = true
+	}
+	if len(item.Look) != 3 || len(item.Pos) != 2 || len(item.Size) != 2 {
+		return snapshot, fmt.Errorf("item %v needs 3 values in Look, and 2 in Pos and Size", item.ID)
+	}
+	snapshot.Description, snapshot.Rationale, snapshot.FitCriterion = item.Description, item.Rationale, item.FitCriterion
+	snapshot.Link = item.Link
+	snapshot.Color, snapshot.Border, snapshot.Shape = int(item.Look[0]), int(item.Look[1]), int(item.Look[2])
+	snapshot.X, snapshot.Y = item.Pos[0], item.Pos[1]
+	snapshot.Width, snapshot.Height = item.Size[0], item.Size[1]
+	return snapshot, nil
+}
+
+// JSONError is an error in a json project, with the path of the value causing it, like Tree[0].Children[1].Pos
+type JSONError struct {
+	Path string
+	Err  error
+}
+
+func (err *JSONError) Error() string {
+	if err.Path == "" {
+		return err.Err.Error()
+	}
+	return fmt.Sprintf("%v: %v", err.Path, err.Err)
+}
+
+// jsonErrorf creates an error for the value at path
+func jsonErrorf(path, format string, args ...interface{}) error {
+	return &JSONError{path, fmt.Errorf(format, args...)}
+}
+
+// jsonPath gets the path of a field in the value at path
+func jsonPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	if field == "" {
+		return path
+	}
+	return path + "." + field
+}
+
+// jsonTypeName gets the name of the json type a value is decoded into
+func jsonTypeName(valueType reflect.Type) string {
+	switch valueType.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a positive integer"
+	case reflect.Ptr:
+		return jsonTypeName(valueType.Elem())
+	}
+	return "a number"
+}
+
+// decodeJSON decodes the value at path, with the path, or position, of the value that doesn't match
+func decodeJSON(data []byte, path string, value interface{}) error {
+	err := json.Unmarshal(data, value)
+	switch typed := err.(type) {
+	case nil:
+		return nil
+	case *json.SyntaxError:
+		// The offset is after the invalid character
+		before := data[:typed.Offset]
+		if len(before) > 0 {
+			before = before[:len(before)-1]
+		}
+		line, column := bytes.Count(before, []byte("\n"))+1, len(before)-bytes.LastIndexByte(before, '\n')
+		return jsonErrorf(path, "invalid json on line %v, column %v: %v", line, column, typed)
+	case *json.UnmarshalTypeError:
+		return jsonErrorf(jsonPath(path, typed.Field), "expected %v, but got %v", jsonTypeName(typed.Type), typed.Value)
+	}
+	return &JSONError{path, err}
+}
+
+// jsonItem is a problem or solution in a json project, where problems always have a rationale
+type jsonItem struct {
+	ID                        *string
+	Description, FitCriterion string
+	Rationale                 *string
+	Link                      string
+	Labels                    []string
+	Media                     []json.RawMessage
+	Look                      []uint
+	Pos, Size                 []int
+	Parent                    string
+	ReqIF                     string
+	Children                  []json.RawMessage
+}
+
+// JSONImportItem is an item to add from a json project
+type JSONImportItem struct {
+	// Path of the item in the file, like Tree[0].Children[1]
+	Path string
+	// Item with its parent by uid
+	Snapshot ItemSnapshot
+	Labels   []string
+	Media    []MediaData
+	ReqIF    string
+}
+
+// JSONImport is a json project that has been read and checked, that can be previewed before it's imported
+type JSONImport struct {
+	Version              int
+	ProjectName, Created string
+	Labels               []LabelData
+	Rules                []RuleData
+	Baselines            []BaselineData
+	// All items, where parents come before their children, except for items in loops
+	Items []JSONImportItem
+}
+
+// ReadJSONFile reads and checks a json project
+func ReadJSONFile(path string) (*JSONImport, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ReadJSONProject(data)
+}
+
+// ReadJSONProject reads and checks a json project, without changing anything,
+// where all errors are a JSONError with the path of the invalid value
+func ReadJSONProject(data []byte) (*JSONImport, error) {
+	var project struct {
+		Version              int
+		ProjectName, Created *string
+		Labels, Rules        []json.RawMessage
+		Baselines, Tree      []json.RawMessage
+	}
+	if err := decodeJSON(data, "", &project); err != nil {
+		return nil, err
+	}
+	if project.ProjectName == nil {
+		return nil, jsonErrorf("ProjectName", "missing, not a valid project")
+	}
+	if project.Version > JSONVersion {
+		return nil, jsonErrorf("Version", "project was exported with a newer version of OpenRQ "+
+			"(version %v, expected %v or older)", project.Version, JSONVersion)
+	}
+	jsonImport := &JSONImport{Version: project.Version, ProjectName: *project.ProjectName}
+	if project.Created != nil {
+		jsonImport.Created = *project.Created
+	}
+	// Labels and rules, which are referred to by name
+	labelNames := make(map[string]bool)
+	for i, raw := range project.Labels {
+		path := fmt.Sprintf("Labels[%v]", i)
+		var label LabelData
+		if err := decodeJSON(raw, path, &label); err != nil {
+			return nil, err
+		}
+		if label.Name = strings.TrimSpace(label.Name); label.Name == "" {
+			return nil, jsonErrorf(path+".Name", "label name can't be empty")
+		}
+		if labelNames[label.Name] {
+			return nil, jsonErrorf(path+".Name", "label with name \"%v\" already exists", label.Name)
+		}
+		labelNames[label.Name] = true
+		jsonImport.Labels = append(jsonImport.Labels, label)
+	}
+	for i, raw := range project.Rules {
+		path := fmt.Sprintf("Rules[%v]", i)
+		var rule RuleData
+		if err := decodeJSON(raw, path, &rule); err != nil {
+			return nil, err
+		}
+		if rule.Tag == "" {
+			return nil, jsonErrorf(path+".Tag", "missing")
+		}
+		// Built-in rules without a severity keep their default one
+		custom := (ValidationRule{Tag: rule.Tag}).IsCustom()
+		if rule.Severity != "" || custom {
+			if _, err := ParseSeverity(rule.Severity); err != nil {
+				return nil, jsonErrorf(path+".Severity", "%v", err)
+			}
+		}
+		if custom {
+			if _, err := NewCustomRule(rule.Tag, rule.Data, SeverityError); err != nil {
+				return nil, jsonErrorf(path+".Data", "%v", err)
+			}
+		}
+		jsonImport.Rules = append(jsonImport.Rules, rule)
+	}
+	for i, raw := range project.Baselines {
+		baseline, err := readJSONBaseline(raw, fmt.Sprintf("Baselines[%v]", i))
+		if err != nil {
+			return nil, err
+		}
+		jsonImport.Baselines = append(jsonImport.Baselines, baseline)
+	}
+	// Items depth first, where only roots in loops have a parent from the file
+	paths := make(map[int64]string)
+	var readItem func(raw json.RawMessage, path string, parent *ItemSnapshot) error
+	readItem = func(raw json.RawMessage, path string, parent *ItemSnapshot) error {
+		item, err := readJSONItem(raw, path)
+		if err != nil {
+			return err
+		}
+		if other, found := paths[item.Snapshot.UID]; found {
+			return jsonErrorf(path+".ID", "same id as %v", other)
+		}
+		paths[item.Snapshot.UID] = path
+		if parent != nil {
+			item.Snapshot.HasParent, item.Snapshot.ParentUID = true, parent.UID
+		}
+		jsonImport.Items = append(jsonImport.Items, item)
+		var children []json.RawMessage
+		_ = json.Unmarshal(raw, &struct{ Children *[]json.RawMessage }{&children})
+		for i, child := range children {
+			if err := readItem(child, fmt.Sprintf("%v.Children[%v]", path, i), &item.Snapshot); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i, raw := range project.Tree {
+		if err := readItem(raw, fmt.Sprintf("Tree[%v]", i), nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range jsonImport.Items {
+		if _, found := paths[item.Snapshot.ParentUID]; item.Snapshot.HasParent && !found {
+			return nil, jsonErrorf(item.Path+".Parent", "no item with id %v", FormatUID(item.Snapshot.ParentUID))
+		}
+	}
+	return jsonImport, nil
+}
+
+// readJSONItem reads a single item, without its children, where parents of roots in loops are kept
+func readJSONItem(raw json.RawMessage, path string) (JSONImportItem, error) {
+	var item jsonItem
+	if err := decodeJSON(raw, path, &item); err != nil {
+		return JSONImportItem{}, err
+	}
+	importItem := JSONImportItem{
+		Path:   path,
+		Labels: item.Labels,
+		ReqIF:  item.ReqIF,
+	}
+	snapshot := &importItem.Snapshot
+	if item.ID == nil {
+		return importItem, jsonErrorf(path+".ID", "missing")
+	}
+	uid, err := ParseUID(*item.ID)
+	if err != nil {
+		return importItem, jsonErrorf(path+".ID", "invalid id \"%v\"", *item.ID)
+	}
+	snapshot.UID = uid
+	snapshot.Type, snapshot.Description = TypeSolution, item.Description
+	if item.Rationale != nil {
+		snapshot.Type, snapshot.Rationale, snapshot.FitCriterion = TypeRequirement, *item.Rationale, item.FitCriterion
+	} else {
+		snapshot.Link = item.Link
+	}
+	// Only the position is required, as older versions don't have the rest
+	if len(item.Pos) != 2 {
+		return importItem, jsonErrorf(path+".Pos", "expected x and y, but got %v values", len(item.Pos))
+	}
+	snapshot.X, snapshot.Y = item.Pos[0], item.Pos[1]
+	snapshot.Width, snapshot.Height = 128, 64
+	if item.Size != nil {
+		if len(item.Size) != 2 {
+			return importItem, jsonErrorf(path+".Size", "expected width and height, but got %v values", len(item.Size))
+		}
+		snapshot.Width, snapshot.Height = item.Size[0], item.Size[1]
+	}
+	if item.Look != nil {
+		if len(item.Look) != 3 {
+			return importItem, jsonErrorf(path+".Look", "expected color, border and shape, but got %v values",
+				len(item.Look))
+		}
+		snapshot.Color, snapshot.Border, snapshot.Shape = int(item.Look[0]), int(item.Look[1]), int(item.Look[2])
+	}
+	if item.Parent != "" {
+		if snapshot.ParentUID, err = ParseUID(item.Parent); err != nil {
+			return importItem, jsonErrorf(path+".Parent", "invalid id \"%v\"", item.Parent)
+		}
+		snapshot.HasParent = true
+	}
+	for i, name := range item.Labels {
+		if strings.TrimSpace(name) == "" {
+			return importItem, jsonErrorf(fmt.Sprintf("%v.Labels[%v]", path, i), "label name can't be empty")
+		}
+	}
+	for i, raw := range item.Media {
+		var media MediaData
+		if err := decodeJSON(raw, fmt.Sprintf("%v.Media[%v]", path, i), &media); err != nil {
+			return importItem, err
+		}
+		importItem.Media = append(importItem.Media, media)
+	}
+	return importItem, nil
+}
+
+// readJSONBaseline reads a baseline, with all its items
+func readJSONBaseline(raw json.RawMessage, path string) (BaselineData, error) {
+	var baseline struct {
+		BaselineData
+		Items []json.RawMessage
+	}
+	if err := decodeJSON(raw, path, &baseline); err != nil {
+		return BaselineData{}, err
+	}
+	if _, err := ParseUID(baseline.ID); err != nil {
+		return BaselineData{}, jsonErrorf(path+".ID", "invalid id \"%v\"", baseline.ID)
+	}
+	if strings.TrimSpace(baseline.Name) == "" {
+		return BaselineData{}, jsonErrorf(path+".Name", "baseline name can't be empty")
+	}
+	if baseline.Size != nil && len(baseline.Size) != 2 {
+		return BaselineData{}, jsonErrorf(path+".Size", "expected width and height, but got %v values",
+			len(baseline.Size))
+	}
+	baseline.BaselineData.Items = make([]BaselineItemData, 0, len(baseline.Items))
+	for i, raw := range baseline.Items {
+		itemPath := fmt.Sprintf("%v.Items[%v]", path, i)
+		var item BaselineItemData
+		if err := decodeJSON(raw, itemPath, &item); err != nil {
+			return BaselineData{}, err
+		}
+		if _, err := item.Snapshot(); err != nil {
+			return BaselineData{}, &JSONError{itemPath, err}
+		}
+		baseline.BaselineData.Items = append(baseline.BaselineData.Items, item)
+	}
+	return baseline.BaselineData, nil
+}
+
+// Count gets the number of items of a type
+func (jsonImport *JSONImport) Count(itemType ItemType) int {
+	count := 0
+	for _, item := range jsonImport.Items {
+		if item.Snapshot.Type == itemType {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary describes what will be imported, like "2 problems, 1 solution, 3 labels"
+func (jsonImport *JSONImport) Summary() string {
+	parts := make([]string, 0, 5)
+	for _, count := range []struct {
+		count            int
+		singular, plural string
+	}{
+		{jsonImport.Count(TypeRequirement), "problem", "problems"},
+		{jsonImport.Count(TypeSolution), "solution", "solutions"},
+		{len(jsonImport.Labels), "label", "labels"},
+		{len(jsonImport.Rules), "rule", "rules"},
+		{len(jsonImport.Baselines), "baseline", "baselines"},
+	} {
+		if count.count == 1 {
+			parts = append(parts, "1 "+count.singular)
+		} else if count.count > 1 || count.singular == "problem" || count.singular == "solution" {
+			parts = append(parts, fmt.Sprintf("%v %v", count.count, count.plural))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Apply adds everything to a new, empty, project, and should be done in a transaction
+func (jsonImport *JSONImport) Apply(db *DataContext) error {
+	db.SetProjectName(jsonImport.ProjectName)
+	if jsonImport.Created != "" {
+		if err := db.SetProjectCreated(jsonImport.Created); err != nil {
+			return err
+		}
+	}
+	for i, label := range jsonImport.Labels {
+		if _, err := db.AddLabel(label.Name, label.Color); err != nil {
+			return &JSONError{fmt.Sprintf("Labels[%v]", i), err}
+		}
+	}
+	for i, rule := range jsonImport.Rules {
+		if err := db.AddRuleData(rule); err != nil {
+			return &JSONError{fmt.Sprintf("Rules[%v]", i), err}
+		}
+	}
+	for i, baseline := range jsonImport.Baselines {
+		if err := db.AddBaselineData(baseline); err != nil {
+			return &JSONError{fmt.Sprintf("Baselines[%v]", i), err}
+		}
+	}
+	// All items are added before being linked, as parents of items in loops may come later
+	snapshots := make([]ItemSnapshot, len(jsonImport.Items))
+	for i, item := range jsonImport.Items {
+		snapshots[i] = item.Snapshot
+	}
+	if err := db.ReplaceItems(snapshots); err != nil {
+		return err
+	}
+	for _, item := range jsonImport.Items {
+		if len(item.Labels) > 0 {
+			if err := db.SetItemLabelNames(item.Snapshot.UID, item.Snapshot.Type, item.Labels); err != nil {
+				return &JSONError{item.Path + ".Labels", err}
+			}
+		}
+		for i, media := range item.Media {
+			if _, _, err := db.AttachMedia(item.Snapshot.UID, item.Snapshot.Type, media.Name, media.Data); err != nil {
+				return &JSONError{fmt.Sprintf("%v.Media[%v]", item.Path, i), err}
+			}
+		}
+		if item.ReqIF != "" {
+			if err := db.SetReqIFIdentifier(item.Snapshot.UID, item.ReqIF); err != nil {
+				return &JSONError{item.Path + ".ReqIF", err}
+			}
+		}
+	}
+	return nil
+}
+
+// CreateProject creates a new project in path with everything in the json project, and opens it
+func (jsonImport *JSONImport) CreateProject(path string) (*Project, error) {
+	if !strings.HasSuffix(path, ".orq") {
+		path += ".orq"
+	}
+	// The current project is only closed once the new one is complete
+	if err := jsonImport.WriteProject(path); err != nil {
+		return nil, err
+	}
+	return NewProject(path)
+}
+
+// WriteProject creates a new project in path with everything in the json project, without opening it.
+// Everything is added in a single transaction, and the file is removed if anything fails.
+func (jsonImport *JSONImport) WriteProject(path string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return fmt.Errorf("file with name \"%v\" already exists", path)
+	}
+	db, err := OpenDataContext(path)
+	if err != nil {
+		return err
+	}
+	err = db.Transaction(func() error {
+		return jsonImport.Apply(db)
+	})
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// rowScanner scans a row, where the first values are read into values, and the rest are left for scan
+type rowScanner struct {
+	rows   *sql.Rows
+	values []interface{}
+}
+
+// scanWith reads the first columns of a row into values, passing the rest on to another scan function
+func scanWith(rows *sql.Rows, values ...interface{}) rowScanner {
+	return rowScanner{rows, values}
+}
+
+func (scanner rowScanner) Scan(dest ...interface{}) error {
+	return scanner.rows.Scan(append(scanner.values, dest...)...)
+}
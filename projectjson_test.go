@@ -0,0 +1,288 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// projectDump gets the contents of all tables, with items by uid instead of row id, to compare projects
+func projectDump(db *DataContext) (string, error) {
+	var builder strings.Builder
+	items, err := db.CurrentItems()
+	if err != nil {
+		return "", err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].UID < items[j].UID
+	})
+	for _, item := range items {
+		fmt.Fprintf(&builder, "item %v %v %v %v %q %q %q %q %v %v %v %v %v %v %v\n", item.UID, item.Type,
+			item.HasParent, item.ParentUID, item.Description, item.Rationale, item.FitCriterion, item.Link,
+			item.Color, item.Border, item.Shape, item.X, item.Y, item.Width, item.Height)
+	}
+	for _, query := range []string{
+		"select name, created from Info",
+		"select tag, color from Labels order by tag",
+		"select l.tag, i.item, i.type from LabelItems i join Labels l on l._rowid_ = i.label order by 1, 2",
+		"select m.hash, m.format, m.data, i.item, i.type, i.name from MediaItems i " +
+			"join Media m on m._rowid_ = i.media order by 4, 6",
+		"select tag, enabled, severity, data from ValidationRules order by _rowid_",
+		"select p.uid, p.name, p.created, p.width, p.height, v.itemV, v.item, v.type, v.parent, v.description, " +
+			"v.rationale, v.fitCriterion, v.link, v.color, v.border, v.shape, v.x, v.y, v.width, v.height " +
+			"from ItemVersions v join Projects p on p._rowid_ = v.version order by p.uid, v.item",
+		"select identifier, item from ReqIFIdentifiers order by item",
+	} {
+		rows, err := db.conn().Query(query)
+		if err != nil {
+			return "", err
+		}
+		columns, _ := rows.Columns()
+		for rows.Next() {
+			values := make([]sql.RawBytes, len(columns))
+			pointers := make([]interface{}, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				rows.Close()
+				return "", err
+			}
+			fmt.Fprintf(&builder, "%q\n", values)
+		}
+		rows.Close()
+	}
+	return builder.String(), nil
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	db := project.Data()
+	db.SetProjectName("Ferry")
+	// Items with every property set, and two items in a loop
+	rootUID, solutionUID, loopUID, otherUID := db.ItemUID(), db.ItemUID(), db.ItemUID(), db.ItemUID()
+	root, _ := db.AddRequirement("<p>Cars must board</p>", "Short stops", "Under 10 min", rootUID)
+	solution, _ := db.AddSolution("Bow ramp", solutionUID)
+	loop, _ := db.AddRequirement("Ramp must not leak", "", "", loopUID)
+	other, _ := db.AddSolution("Pumps", otherUID)
+	_ = db.AddItemChild(NewRequirement(root), NewSolution(solution))
+	_ = db.AddItemChild(NewRequirement(loop), NewSolution(other))
+	_ = db.AddItemChild(NewSolution(other), NewRequirement(loop))
+	if err := db.SetItemValues(solution, "Solutions", map[string]interface{}{
+		"link": "https://example.com/ramp", "color": 0xff0000, "border": 2, "shape": 1,
+		"x": -40, "y": 300, "width": 200, "height": 90,
+	}); err != nil {
+		t.Error("failed to set item values:", err)
+		return
+	}
+	_ = db.SetItemLabelNames(rootUID, TypeRequirement, []string{"Safety", "Later"})
+	_, _ = db.AddLabel("Unused", 0x2196f3)
+	if _, _, err := db.AttachMedia(solutionUID, TypeSolution, "ramp.png", []byte{0x89, 'P', 'N', 'G', 0}); err != nil {
+		t.Error("failed to attach media:", err)
+		return
+	}
+	_ = db.SetReqIFIdentifier(loopUID, "REQ-12")
+	rules, _ := db.ValidationRules()
+	rules[0].Enabled = false
+	_ = db.SaveValidationRule(rules[0])
+	custom, _ := NewCustomRule("Fit criterion", "fitCriterion != \"\"", SeverityWarning)
+	_ = db.SaveCustomRule("", custom)
+	// Two baselines, where the root changed in between
+	if _, err := db.CreateBaseline("v1"); err != nil {
+		t.Error("failed to create baseline:", err)
+		return
+	}
+	db.SetItemValues(root, "Requirements", map[string]interface{}{"description": "<p>Cars must board quickly</p>"})
+	if _, err := db.CreateBaseline("v2"); err != nil {
+		t.Error("failed to create baseline:", err)
+		return
+	}
+	before, err := projectDump(db)
+	if err != nil {
+		t.Error("failed to read project:", err)
+		return
+	}
+	// Export and import again, which should give the same contents
+	items, _ := db.LoadItems()
+	jsonPath := fmt.Sprintf("%v/openrq_test.json", tempDir)
+	if err := project.SaveJSON(jsonPath, NewGraphFromItems(items)); err != nil {
+		t.Error("failed to export project:", err)
+		return
+	}
+	// Items that can't be loaded fail the export, instead of being exported empty
+	missing := NewGraphFromItems(append(items, ItemData{Item: NewSolution(-1)}))
+	if err := project.SaveJSON(fmt.Sprintf("%v/openrq_missing.json", tempDir), missing); err == nil {
+		t.Error("expected exporting missing item to fail")
+	}
+	imported, err := ImportJSON(jsonPath, fmt.Sprintf("%v/openrq_imported.orq", tempDir))
+	if err != nil {
+		t.Error("failed to import project:", err)
+		return
+	}
+	defer imported.Close()
+	after, err := projectDump(imported.Data())
+	if err != nil {
+		t.Error("failed to read imported project:", err)
+		return
+	}
+	if before != after {
+		t.Errorf("expected imported project to be the same, but got\n%v\ninstead of\n%v", after, before)
+	}
+	// Everything written is described in the schema
+	data, _ := ioutil.ReadFile(jsonPath)
+	var exported map[string]interface{}
+	if err := json.Unmarshal(data, &exported); err != nil || exported["Version"] != float64(JSONVersion) {
+		t.Error("expected version in exported project, but got", exported["Version"], err)
+	}
+	schemaData, err := ioutil.ReadFile("project.schema.json")
+	if err != nil {
+		t.Error("failed to read schema:", err)
+		return
+	}
+	var schema struct {
+		Properties  map[string]interface{}
+		Definitions map[string]struct {
+			Properties map[string]interface{}
+			AllOf      []struct {
+				Properties map[string]interface{}
+			}
+		}
+	}
+	if err := json.Unmarshal(schemaData, &schema); err != nil {
+		t.Error("invalid schema:", err)
+		return
+	}
+	described := func(definition string, object map[string]interface{}) {
+		properties := schema.Definitions[definition].Properties
+		if definition == "" {
+			properties = schema.Properties
+		}
+		for key := range object {
+			_, found := properties[key]
+			if !found && len(schema.Definitions[definition].AllOf) > 1 {
+				_, found = schema.Definitions[definition].AllOf[1].Properties[key]
+				_, inItem := schema.Definitions["itemProperties"].Properties[key]
+				found = found || inItem
+			}
+			if !found {
+				t.Errorf("expected %v in %v to be in schema", key, definition)
+			}
+		}
+	}
+	described("", exported)
+	var visit func(items []interface{})
+	visit = func(items []interface{}) {
+		for _, value := range items {
+			item := value.(map[string]interface{})
+			if _, problem := item["Rationale"]; problem {
+				described("problem", item)
+			} else {
+				described("solution", item)
+			}
+			children, _ := item["Children"].([]interface{})
+			visit(children)
+		}
+	}
+	visit(exported["Tree"].([]interface{}))
+	for _, baseline := range exported["Baselines"].([]interface{}) {
+		described("baseline", baseline.(map[string]interface{}))
+		for _, item := range baseline.(map[string]interface{})["Items"].([]interface{}) {
+			described("baselineItem", item.(map[string]interface{}))
+		}
+	}
+	// Newer versions can't be imported
+	newer := strings.Replace(string(data), fmt.Sprintf("\"Version\": %v", JSONVersion), "\"Version\": 99", 1)
+	_ = ioutil.WriteFile(jsonPath, []byte(newer), 0644)
+	if _, err := ImportJSON(jsonPath, fmt.Sprintf("%v/openrq_newer.orq", tempDir)); err == nil {
+		t.Error("expected newer version to fail")
+	}
+}
+
+func TestJSONImportErrors(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	item := func(id, extra string) string {
+		return fmt.Sprintf(`{"ID": "%v", "Description": "Item", "Pos": [0, 0]%v}`, id, extra)
+	}
+	// Malformed files give the path of the invalid value instead of panicking
+	for content, expected := range map[string]string{
+		"{\n\"ProjectName\": \"Ferry\",\n\"Tree\": [}": "invalid json on line 3, column 10",
+		`{"Tree": []}`:                         "ProjectName: missing",
+		`{"ProjectName": "Ferry", "Tree": {}}`: "Tree: expected an array, but got object",
+		`{"ProjectName": "Ferry", "Tree": [` + item("1", `, "Pos": "0,0"`) + `]}`:                                "Tree[0].Pos: expected an array, but got string",
+		`{"ProjectName": "Ferry", "Tree": [` + item("1", `, "Pos": [1]`) + `]}`:                                  "Tree[0].Pos: expected x and y",
+		`{"ProjectName": "Ferry", "Tree": [` + item("1", `, "Look": [1, 2]`) + `]}`:                              "Tree[0].Look: expected color",
+		`{"ProjectName": "Ferry", "Tree": [` + item("1", `, "Rationale": 4`) + `]}`:                              "Tree[0].Rationale: expected a string, but got number",
+		`{"ProjectName": "Ferry", "Tree": [` + item("x", "") + `]}`:                                              `Tree[0].ID: invalid id "x"`,
+		`{"ProjectName": "Ferry", "Tree": [` + item("1", `, "Children": [{"Pos": [0, 0]}]`) + `]}`:               "Tree[0].Children[0].ID: missing",
+		`{"ProjectName": "Ferry", "Tree": [` + item("1", "") + `, ` + item("1", "") + `]}`:                       "Tree[1].ID: same id as Tree[0]",
+		`{"ProjectName": "Ferry", "Tree": [` + item("1", `, "Parent": "5"`) + `]}`:                               "Tree[0].Parent: no item with id 5",
+		`{"ProjectName": "Ferry", "Tree": [` + item("1", `, "Media": [{"Name": "a", "Data": 1}]`) + `]}`:         "Tree[0].Media[0].Data: expected",
+		`{"ProjectName": "Ferry", "Labels": [{"Name": " "}], "Tree": []}`:                                        "Labels[0].Name: label name can't be empty",
+		`{"ProjectName": "Ferry", "Rules": [{"Enabled": true}], "Tree": []}`:                                     "Rules[0].Tag: missing",
+		`{"ProjectName": "Ferry", "Rules": [{"Tag": "link-loop", "Severity": "fatal"}], "Tree": []}`:             "Rules[0].Severity: unknown severity",
+		`{"ProjectName": "Ferry", "Rules": [{"Tag": "Fit", "Severity": "error", "Data": "uid =="}], "Tree": []}`: "Rules[0].Data: ",
+		`{"ProjectName": "Ferry", "Baselines": [{"ID": "1", "Name": "v1", "Items": [{"ID": "2", ` +
+			`"Type": "Other", "Look": [0, 0, 0], "Pos": [0, 0], "Size": [1, 1]}]}], "Tree": []}`: "Baselines[0].Items[0]: ",
+	} {
+		path := fmt.Sprintf("%v/openrq_test.json", tempDir)
+		_ = ioutil.WriteFile(path, []byte(content), 0644)
+		_, err := ImportJSON(path, fmt.Sprintf("%v/openrq_test.orq", tempDir))
+		if _, isPath := err.(*JSONError); err == nil || !isPath || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error with \"%v\" for %v, but got %v", expected, content, err)
+		}
+		if _, err := os.Stat(fmt.Sprintf("%v/openrq_test.orq", tempDir)); !os.IsNotExist(err) {
+			t.Errorf("expected no project to be created for %v", content)
+		}
+	}
+	// Files without a version, from older versions, are still valid
+	jsonImport, err := ReadJSONProject([]byte(`{"ProjectName": "Ferry", "Tree": [` +
+		item("1", `, "Rationale": "", "FitCriterion": "", "Children": [`+item("2", `, "Media": []`)+`]`) + `]}`))
+	if err != nil {
+		t.Error("failed to read project:", err)
+		return
+	}
+	if summary := jsonImport.Summary(); summary != "1 problem, 1 solution" {
+		t.Error("expected 1 problem and 1 solution, but got", summary)
+	}
+	if child := jsonImport.Items[1].Snapshot; !child.HasParent || child.ParentUID != 1 || child.Width != 128 {
+		t.Error("expected child with default size, but got", child)
+	}
+	// Everything is undone if importing fails halfway, without closing the current project
+	project, err := NewProject(fmt.Sprintf("%v/openrq_current.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	jsonImport.Labels = []LabelData{{Name: "Safety"}, {Name: "Safety"}}
+	path := fmt.Sprintf("%v/openrq_partial.orq", tempDir)
+	if _, err := jsonImport.CreateProject(path); err == nil || !strings.Contains(err.Error(), "Labels[1]") {
+		t.Error("expected import with the same label twice to fail, but got", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected partial project to be removed")
+	}
+	if currentProject != project {
+		t.Error("expected current project to still be open")
+	}
+}
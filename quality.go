@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Text fields of requirements that are analyzed, in the same order as in the edit dock
+const (
+	FieldDescription  = "description"
+	FieldRationale    = "rationale"
+	FieldFitCriterion = "fitCriterion"
+)
+
+// QualityFields gets all analyzed fields, in the same order as in the edit dock
+func QualityFields() []string {
+	return []string{FieldDescription, FieldRationale, FieldFitCriterion}
+}
+
+// QualityIssue is a phrase in a requirement that makes it ambiguous or hard to verify
+type QualityIssue struct {
+	// Check is the tag of the validation rule finding the issue
+	Check string
+	Field string
+	// Start and End of the phrase in plain text, in characters
+	Start, End int
+	Phrase     string
+	Message    string
+}
+
+// weakWords are words and phrases that can't be verified, or mean different things to different readers
+var weakWords = []string{
+	"fast", "quick", "quickly", "slow", "user-friendly", "user friendly", "easy", "easily", "simple",
+	"intuitive", "flexible", "efficient", "robust", "seamless", "adequate", "appropriate", "sufficient",
+	"reasonable", "normal", "normally", "usually", "typically", "generally", "several", "some", "many",
+	"few", "approximately", "etc.", "and/or", "as possible", "if possible", "where possible",
+	"as appropriate", "if necessary", "as needed", "minimal", "maximal", "minimize", "maximize",
+	"optimize", "better", "best", "improved", "state-of-the-art",
+}
+
+var (
+	weakWordsRegex = phraseRegex(weakWords)
+	// Forms of "to be" followed by a past participle, optionally with an adverb in between
+	passiveRegex = regexp.MustCompile(`(?i)\b(?:is|are|was|were|be|been|being)\s+(?:\w+ly\s+)?` +
+		`(?:\w{2,}ed|built|chosen|done|drawn|driven|found|given|held|kept|known|made|put|read|` +
+		`run|seen|sent|set|shown|taken|written)\b`)
+	shallRegex = regexp.MustCompile(`(?i)\bshall\b`)
+	// Statements end with a full stop, question or exclamation mark
+	statementEndRegex = regexp.MustCompile(`[.!?](?:\s|$)`)
+	// Measurable fit criteria have a number, or at least a unit
+	measurableRegex = regexp.MustCompile(`(?i)[0-9%]|\b(?:zero|one|two|three|four|five|six|seven|eight|nine|` +
+		`ten|hundred|thousand|million|percent|ms|milliseconds?|seconds?|minutes?|hours?|days?|weeks?|` +
+		`months?|years?|bytes?|[kmgt]b|hz|users?|times|per)\b`)
+)
+
+// phraseRegex creates a case insensitive regex matching any of the phrases as whole words,
+// longest first to prefer "as possible" over "possible"
+func phraseRegex(phrases []string) *regexp.Regexp {
+	sorted := append([]string{}, phrases...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	patterns := make([]string, len(sorted))
+	for i, phrase := range sorted {
+		patterns[i] = regexp.QuoteMeta(phrase)
+		// Phrases like "etc." end with punctuation, so only check for word end after letters
+		if last, _ := utf8.DecodeLastRuneInString(phrase); unicode.IsLetter(last) {
+			patterns[i] += `\b`
+		}
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(patterns, "|") + `)`)
+}
+
+// newQualityIssue creates an issue from a byte range in text
+func newQualityIssue(check, field, text string, start, end int, message string) QualityIssue {
+	return QualityIssue{
+		Check:   check,
+		Field:   field,
+		Start:   utf8.RuneCountInString(text[:start]),
+		End:     utf8.RuneCountInString(text[:end]),
+		Phrase:  text[start:end],
+		Message: message,
+	}
+}
+
+// UTF16Range gets the start and length of the phrase in text in UTF-16 code units, as used by Qt,
+// where characters outside the basic multilingual plane, like emoji, are two code units
+func (issue QualityIssue) UTF16Range(text string) (start, length int) {
+	for i, char := range []rune(text) {
+		if i >= issue.End {
+			break
+		}
+		units := len(utf16.Encode([]rune{char}))
+		if i < issue.Start {
+			start += units
+		} else {
+			length += units
+		}
+	}
+	return start, length
+}
+
+// AnalyzeText finds issues in the plain text of a field
+func AnalyzeText(field, text string) []QualityIssue {
+	issues := make([]QualityIssue, 0)
+	for _, match := range weakWordsRegex.FindAllStringIndex(text, -1) {
+		issues = append(issues, newQualityIssue("weak-words", field, text, match[0], match[1],
+			fmt.Sprintf("\"%v\" is vague, use something measurable instead", text[match[0]:match[1]])))
+	}
+	if field == FieldDescription {
+		for _, match := range passiveRegex.FindAllStringIndex(text, -1) {
+			issues = append(issues, newQualityIssue("passive-voice", field, text, match[0], match[1],
+				fmt.Sprintf("\"%v\" is passive, say what or who does it", text[match[0]:match[1]])))
+		}
+		// Every "shall" after the first in the same statement
+		ends := statementEndRegex.FindAllStringIndex(text, -1)
+		lastStatement := -1
+		for _, match := range shallRegex.FindAllStringIndex(text, -1) {
+			statement := 0
+			for statement < len(ends) && ends[statement][0] < match[0] {
+				statement++
+			}
+			if statement == lastStatement {
+				issues = append(issues, newQualityIssue("multiple-shall", field, text, match[0], match[1],
+					"Several \"shall\" in one statement, split it into several requirements"))
+			}
+			lastStatement = statement
+		}
+	}
+	if field == FieldFitCriterion && strings.TrimSpace(text) != "" && !measurableRegex.MatchString(text) {
+		issues = append(issues, newQualityIssue("fit-measurable", field, text, 0, len(text),
+			"Fit criterion has no number or unit, so it can't be measured"))
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Start < issues[j].Start
+	})
+	return issues
+}
+
+// AnalyzeRequirement finds issues in all fields of a requirement, where the text is html
+func AnalyzeRequirement(description, rationale, fitCriterion string) []QualityIssue {
+	issues := make([]QualityIssue, 0)
+	texts := []string{description, rationale, fitCriterion}
+	for i, field := range QualityFields() {
+		issues = append(issues, AnalyzeText(field, PlainText(texts[i]))...)
+	}
+	if strings.TrimSpace(PlainText(rationale)) == "" {
+		issues = append(issues, QualityIssue{
+			Check:   "empty-rationale",
+			Field:   FieldRationale,
+			Message: "Rationale is empty, explain why the requirement is needed",
+		})
+	}
+	return issues
+}
+
+// qualityCheck creates a validation check, finding problems with issues from the check with the specified tag
+func qualityCheck(tag string) func(context *ValidationContext, params map[string]int) [][]Item {
+	return func(context *ValidationContext, params map[string]int) [][]Item {
+		items := make([]Item, 0)
+		for _, item := range context.Graph.Items() {
+			data, found := context.Items[item]
+			if _, isReq := item.(Requirement); !found || !isReq {
+				continue
+			}
+			for _, issue := range AnalyzeRequirement(data.Description, data.Rationale, data.FitCriterion) {
+				if issue.Check == tag {
+					items = append(items, item)
+					break
+				}
+			}
+		}
+		return eachItem(items)
+	}
+}
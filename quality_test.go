@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAnalyzeText(t *testing.T) {
+	tests := []struct {
+		field, text string
+		// Checks and phrases of all expected issues, in order
+		expected []string
+	}{
+		{FieldDescription, "The system shall respond to all requests within 2 seconds.", nil},
+		{FieldDescription, "The UI shall be fast and user-friendly, with icons, menus etc. where possible.",
+			[]string{"weak-words: fast", "weak-words: user-friendly", "weak-words: etc.", "weak-words: where possible"}},
+		{FieldDescription, "Reports are generated by the server every night.",
+			[]string{"passive-voice: are generated"}},
+		{FieldDescription, "The robot shall stop and shall sound an alarm. It shall log the event.",
+			[]string{"multiple-shall: shall"}},
+		{FieldRationale, "Reports are generated too late", nil},
+		{FieldFitCriterion, "Response time is acceptable to testers", []string{
+			"fit-measurable: Response time is acceptable to testers",
+		}},
+		{FieldFitCriterion, "95% of responses within 200 ms", nil},
+		{FieldFitCriterion, "", nil},
+	}
+	for _, test := range tests {
+		issues := AnalyzeText(test.field, test.text)
+		if len(issues) != len(test.expected) {
+			t.Errorf("expected %v issues in \"%v\", but got %v", len(test.expected), test.text, issues)
+			continue
+		}
+		for i, issue := range issues {
+			if found := issue.Check + ": " + issue.Phrase; found != test.expected[i] {
+				t.Errorf("expected \"%v\" in \"%v\", but got \"%v\"", test.expected[i], test.text, found)
+			}
+			// Positions are in characters, for highlighting in the edit dock
+			if phrase := string([]rune(test.text)[issue.Start:issue.End]); phrase != issue.Phrase {
+				t.Errorf("expected phrase \"%v\" at %v-%v, but got \"%v\"", issue.Phrase, issue.Start, issue.End, phrase)
+			}
+		}
+	}
+	// Qt counts characters outside the basic multilingual plane as two
+	text := "🚢 Ferries shall be fast"
+	if issues := AnalyzeText(FieldDescription, text); len(issues) != 1 {
+		t.Error("expected weak word after emoji, but got", issues)
+	} else if start, length := issues[0].UTF16Range(text); start != 20 || length != 4 {
+		t.Errorf("expected UTF-16 range 20+4, but got %v+%v", start, length)
+	}
+	// Rules only check problems, and an empty rationale is also an issue
+	graph := NewGraph()
+	graph.AddItem(NewRequirement(1), "<p>The app shall start quickly</p>")
+	graph.AddItem(NewSolution(1), "A simple cache")
+	context := &ValidationContext{
+		Graph: graph,
+		Items: map[Item]ItemData{
+			NewRequirement(1): {Description: "<p>The app shall start quickly</p>", FitCriterion: "Testers like it"},
+			NewSolution(1):    {Description: "A simple cache"},
+		},
+	}
+	rules := BuiltinRules()[WeakWords:]
+	failed := FailedRules(Validate(context, rules))
+	for _, tag := range []string{"weak-words", "fit-measurable", "empty-rationale"} {
+		if !failed[tag] {
+			t.Errorf("expected %v to fail, but got %v", tag, failed)
+		}
+	}
+	if findings := Validate(context, rules); len(findings) != 3 {
+		t.Error("expected only problem to be checked, but got", findings)
+	}
+}
@@ -0,0 +1,72 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"strings"
+
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// qualityFormat gets the format for highlighting phrases with quality issues
+func qualityFormat(check string) *gui.QTextCharFormat {
+	format := gui.NewQTextCharFormat()
+	format.SetUnderlineStyle(gui.QTextCharFormat__WaveUnderline)
+	// Issues that are only informational are less visible
+	color := gui.NewQColor3(255, 152, 0, 255)
+	for _, rule := range validationRules {
+		if rule.Tag == check && rule.Severity == SeverityInfo {
+			color = gui.NewQColor3(33, 150, 243, 255)
+		}
+	}
+	format.SetUnderlineColor(color)
+	return format
+}
+
+// qualityEnabled checks if the rule finding an issue is enabled in the current project
+func qualityEnabled(check string) bool {
+	for _, rule := range validationRules {
+		if rule.Tag == check {
+			return rule.Enabled
+		}
+	}
+	return true
+}
+
+// HighlightQuality underlines phrases with quality issues while editing a field,
+// and lists all issues in the tooltip. Like the validation rules, only requirements are checked,
+// so nothing is shown unless isRequirement is true, and refresh updates it when the item type changes.
+func HighlightQuality(textEdit *widgets.QTextEdit, field string, isRequirement func() bool) (refresh func()) {
+	// Only shown while editing, so formats are never saved to the project
+	highlighter := gui.NewQSyntaxHighlighter2(textEdit.Document())
+	highlighter.ConnectHighlightBlock(func(text string) {
+		if !isRequirement() {
+			return
+		}
+		for _, issue := range AnalyzeText(field, text) {
+			if qualityEnabled(issue.Check) {
+				start, length := issue.UTF16Range(text)
+				highlighter.SetFormat(start, length, qualityFormat(issue.Check))
+			}
+		}
+	})
+	updateToolTip := func() {
+		messages := make([]string, 0)
+		if isRequirement() {
+			for _, issue := range AnalyzeText(field, textEdit.ToPlainText()) {
+				if qualityEnabled(issue.Check) {
+					messages = append(messages, issue.Message)
+				}
+			}
+		}
+		textEdit.SetToolTip(strings.Join(messages, "\n"))
+	}
+	textEdit.ConnectTextChanged(updateToolTip)
+	updateToolTip()
+	return func() {
+		highlighter.Rehighlight()
+		updateToolTip()
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// ReportFormats are all formats validation reports can be written as
+var ReportFormats = []string{"text", "json", "junit", "sarif"}
+
+// IsReportFormat checks if format is one of ReportFormats
+func IsReportFormat(format string) bool {
+	for _, reportFormat := range ReportFormats {
+		if reportFormat == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportItem is an item that failed a rule, as shown in reports
+type ReportItem struct {
+	UID  string
+	Type string
+	// Excerpt is the start of the description as plain text
+	Excerpt string
+}
+
+// ReportFinding is an item that failed a rule
+type ReportFinding struct {
+	Rule     string
+	Severity Severity
+	Message  string
+	Items    []ReportItem
+}
+
+// ReportRule is a rule that was checked, and how many findings it had
+type ReportRule struct {
+	Tag, Name, Info string
+	Enabled         bool
+	Severity        Severity
+	Findings        int
+}
+
+// ValidationReport is the result of validating a project, that can be written in several formats
+type ValidationReport struct {
+	Project  string
+	Rules    []ReportRule
+	Findings []ReportFinding
+}
+
+// NewValidationReport creates a report from the findings of validating a project
+func NewValidationReport(project string, context *ValidationContext, rules []ValidationRule,
+	findings []ValidationFinding) ValidationReport {
+	report := ValidationReport{
+		Project:  project,
+		Rules:    make([]ReportRule, 0, len(rules)),
+		Findings: make([]ReportFinding, 0, len(findings)),
+	}
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		counts[finding.Rule.Tag]++
+		items := make([]ReportItem, len(finding.Items))
+		for i, item := range finding.Items {
+			items[i] = NewReportItem(context, item)
+		}
+		report.Findings = append(report.Findings, ReportFinding{
+			Rule:     finding.Rule.Tag,
+			Severity: finding.Rule.Severity,
+			Message:  finding.Rule.Info,
+			Items:    items,
+		})
+	}
+	for _, rule := range rules {
+		report.Rules = append(report.Rules, ReportRule{
+			rule.Tag, rule.Name, rule.Info, rule.Enabled, rule.Severity, counts[rule.Tag],
+		})
+	}
+	return report
+}
+
+// NewReportItem gets the uid and excerpt of an item
+func NewReportItem(context *ValidationContext, item Item) ReportItem {
+	// Items are loaded with the context, except when only validating a graph
+	data, found := context.Items[item]
+	uid := data.UID
+	if !found {
+		uid = item.UID()
+	}
+	excerpt := strings.ReplaceAll(PlainText(context.Graph.Description(item)), "\n", " ")
+	return ReportItem{FormatUID(uid), GetItemName(item), Truncate(excerpt, 80)}
+}
+
+// Count gets how many findings have the specified severity
+func (report ValidationReport) Count(severity Severity) int {
+	count := 0
+	for _, finding := range report.Findings {
+		if finding.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Write writes the report in the specified format, one of ReportFormats
+func (report ValidationReport) Write(writer io.Writer, format string) error {
+	switch format {
+	case "text":
+		return report.WriteText(writer)
+	case "json":
+		return report.WriteJSON(writer)
+	case "junit":
+		return report.WriteJUnit(writer)
+	case "sarif":
+		return report.WriteSARIF(writer)
+	}
+	return fmt.Errorf("unknown report format \"%v\" (expected %v)", format, strings.Join(ReportFormats, ", "))
+}
+
+// WriteText writes a table with one row for each item that failed a rule
+func (report ValidationReport) WriteText(writer io.Writer) error {
+	table := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "SEVERITY\tRULE\tUID\tDESCRIPTION")
+	for _, finding := range report.Findings {
+		// Findings without items, like invalid rules, show the message instead
+		if len(finding.Items) == 0 {
+			fmt.Fprintf(table, "%v\t%v\t-\t%v\n", finding.Severity, finding.Rule, Truncate(finding.Message, 60))
+		}
+		for _, item := range finding.Items {
+			fmt.Fprintf(table, "%v\t%v\t%v\t%v\n", finding.Severity, finding.Rule, item.UID,
+				Truncate(item.Excerpt, 60))
+		}
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(writer, "\n%v findings (%v errors, %v warnings)\n", len(report.Findings),
+		report.Count(SeverityError), report.Count(SeverityWarning))
+	return err
+}
+
+// WriteJSON writes the report as indented json
+func (report ValidationReport) WriteJSON(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "\t")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(report)
+}
+
+// junitSuite is a JUnit test suite, where each rule is a test case
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit xml, with one test case for each rule.
+// Only rules with error severity fail, others are listed in the output of passing tests.
+func (report ValidationReport) WriteJUnit(writer io.Writer) error {
+	suite := junitSuite{Name: report.Project, Tests: len(report.Rules)}
+	for _, rule := range report.Rules {
+		testCase := junitCase{Name: rule.Tag, ClassName: "orq.validation"}
+		lines := make([]string, 0, rule.Findings)
+		for _, finding := range report.Findings {
+			if finding.Rule != rule.Tag {
+				continue
+			}
+			if len(finding.Items) == 0 {
+				lines = append(lines, finding.Message)
+			}
+			for _, item := range finding.Items {
+				lines = append(lines, fmt.Sprintf("%v %v: %v", item.Type, item.UID, item.Excerpt))
+			}
+		}
+		switch {
+		case !rule.Enabled:
+			testCase.Skipped = &struct{}{}
+			suite.Skipped++
+		case rule.Findings > 0 && rule.Severity == SeverityError:
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%v: %v findings", rule.Name, rule.Findings),
+				Type:    string(rule.Severity),
+				Text:    strings.Join(lines, "\n"),
+			}
+			suite.Failures++
+		case rule.Findings > 0:
+			testCase.SystemOut = fmt.Sprintf("%v:\n%v", rule.Severity, strings.Join(lines, "\n"))
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
+
+// sarifLevel gets the SARIF level for a severity
+func sarifLevel(severity Severity) string {
+	if severity == SeverityInfo {
+		return "note"
+	}
+	return string(severity)
+}
+
+// sarifMessage is a message in a SARIF log
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// WriteSARIF writes the report as a SARIF 2.1.0 log, where items are logical locations in the project
+func (report ValidationReport) WriteSARIF(writer io.Writer) error {
+	type rule struct {
+		ID                   string       `json:"id"`
+		Name                 string       `json:"name"`
+		ShortDescription     sarifMessage `json:"shortDescription"`
+		DefaultConfiguration struct {
+			Enabled bool   `json:"enabled"`
+			Level   string `json:"level"`
+		} `json:"defaultConfiguration"`
+	}
+	type logicalLocation struct {
+		Name               string `json:"name"`
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+		Kind               string `json:"kind"`
+	}
+	type location struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+		} `json:"physicalLocation"`
+		LogicalLocations []logicalLocation `json:"logicalLocations"`
+		Message          sarifMessage      `json:"message"`
+	}
+	type result struct {
+		RuleID    string       `json:"ruleId"`
+		RuleIndex int          `json:"ruleIndex"`
+		Level     string       `json:"level"`
+		Message   sarifMessage `json:"message"`
+		Locations []location   `json:"locations"`
+	}
+	rules := make([]rule, len(report.Rules))
+	ruleIndex := make(map[string]int)
+	for i, reportRule := range report.Rules {
+		rules[i].ID = reportRule.Tag
+		rules[i].Name = reportRule.Name
+		rules[i].ShortDescription.Text = reportRule.Info
+		rules[i].DefaultConfiguration.Enabled = reportRule.Enabled
+		rules[i].DefaultConfiguration.Level = sarifLevel(reportRule.Severity)
+		ruleIndex[reportRule.Tag] = i
+	}
+	results := make([]result, 0, len(report.Findings))
+	for _, finding := range report.Findings {
+		locations := make([]location, len(finding.Items))
+		names := make([]string, len(finding.Items))
+		for i, item := range finding.Items {
+			locations[i].PhysicalLocation.ArtifactLocation.URI = report.Project
+			locations[i].LogicalLocations = []logicalLocation{{
+				Name:               item.UID,
+				FullyQualifiedName: fmt.Sprintf("%v/%v", item.Type, item.UID),
+				Kind:               "object",
+			}}
+			locations[i].Message.Text = item.Excerpt
+			names[i] = fmt.Sprintf("%v %v (%v)", item.Type, item.UID, item.Excerpt)
+		}
+		message := finding.Message
+		if len(names) > 0 {
+			message = fmt.Sprintf("%v: %v", finding.Message, strings.Join(names, ", "))
+		}
+		results = append(results, result{
+			RuleID:    finding.Rule,
+			RuleIndex: ruleIndex[finding.Rule],
+			Level:     sarifLevel(finding.Severity),
+			Message:   sarifMessage{message},
+			Locations: locations,
+		})
+	}
+	log := map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name":           "orq",
+						"version":        versionTagName,
+						"informationUri": "https://github.com/kraxarn/OpenRQ",
+						"rules":          rules,
+					},
+				},
+				"results": results,
+			},
+		},
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "\t")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(log)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestValidationReport(t *testing.T) {
+	// A problem linking to another problem, and a root with two children
+	graph := NewGraph()
+	root := NewRequirement(1)
+	graph.AddItem(root, "<p>The root problem</p>")
+	graph.AddItem(NewRequirement(2), "Child problem")
+	graph.AddItem(NewSolution(1), "Child solution")
+	graph.AddLink(root, NewRequirement(2))
+	graph.AddLink(root, NewSolution(1))
+	context := &ValidationContext{
+		Graph: graph,
+		Items: map[Item]ItemData{
+			root:              {UID: 0x10},
+			NewRequirement(2): {UID: 0x20},
+			NewSolution(1):    {UID: 0x30},
+		},
+	}
+	// Only rules checking links
+	rules := BuiltinRules()[:LinkError+1]
+	rules[LinkError].Enabled = false
+	report := NewValidationReport("test.orq", context, rules, Validate(context, rules))
+	if report.Count(SeverityError) != 1 || report.Count(SeverityWarning) != 1 {
+		t.Error("expected one error and one warning, but got", report.Findings)
+	}
+	finding := report.Findings[0]
+	if finding.Rule != "same-type" || len(finding.Items) != 1 ||
+		finding.Items[0].UID != FormatUID(0x20) || finding.Items[0].Excerpt != "Child problem" {
+		t.Error("unexpected finding, got", finding)
+	}
+	// All formats include uids and excerpts
+	for _, format := range ReportFormats {
+		var buffer bytes.Buffer
+		if err := report.Write(&buffer, format); err != nil {
+			t.Errorf("failed to write %v report: %v", format, err)
+			continue
+		}
+		output := buffer.String()
+		if !strings.Contains(output, FormatUID(0x10)) || !strings.Contains(output, "The root problem") {
+			t.Errorf("expected %v report to contain uid and excerpt, but got %v", format, output)
+		}
+		// Also check they can be read back
+		var decoded interface{}
+		switch format {
+		case "json", "sarif":
+			if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil {
+				t.Errorf("invalid %v report: %v", format, err)
+			}
+		case "junit":
+			var suite junitSuite
+			if err := xml.Unmarshal(buffer.Bytes(), &suite); err != nil {
+				t.Error("invalid junit report:", err)
+			} else if suite.Tests != 4 || suite.Failures != 1 || suite.Skipped != 1 {
+				t.Error("expected one failure and one skipped, but got", suite)
+			}
+		}
+	}
+	if err := report.Write(&bytes.Buffer{}, "pdf"); err == nil {
+		t.Error("expected unknown format to fail")
+	}
+}
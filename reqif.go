@@ -0,0 +1,523 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Namespaces used in ReqIF documents
+const (
+	reqifNamespace = "http://www.omg.org/spec/ReqIF/20110401/reqif.xsd"
+	xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+)
+
+// Identifiers of objects exported by OpenRQ start with this prefix, followed by the uid
+const reqifPrefix = "orq-"
+
+// Identifiers of the types, attributes and specification in exported documents
+const (
+	reqifXHTMLType         = "orq-datatype-xhtml"
+	reqifProblemType       = "orq-type-problem"
+	reqifSolutionType      = "orq-type-solution"
+	reqifParentType        = "orq-type-parent"
+	reqifSpecificationType = "orq-type-specification"
+	reqifSpecificationID   = "orq-specification"
+)
+
+// reqifIdentifiable has the attributes every element with an identifier has
+type reqifIdentifiable struct {
+	Identifier string `xml:"IDENTIFIER,attr"`
+	LastChange string `xml:"LAST-CHANGE,attr"`
+	LongName   string `xml:"LONG-NAME,attr,omitempty"`
+}
+
+type reqifDocument struct {
+	XMLName xml.Name     `xml:"REQ-IF"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	XHTML   string       `xml:"xmlns:xhtml,attr"`
+	Header  reqifHeader  `xml:"THE-HEADER>REQ-IF-HEADER"`
+	Content reqifContent `xml:"CORE-CONTENT>REQ-IF-CONTENT"`
+}
+
+type reqifHeader struct {
+	Identifier   string `xml:"IDENTIFIER,attr"`
+	CreationTime string `xml:"CREATION-TIME"`
+	ToolID       string `xml:"REQ-IF-TOOL-ID"`
+	Version      string `xml:"REQ-IF-VERSION"`
+	SourceToolID string `xml:"SOURCE-TOOL-ID"`
+	Title        string `xml:"TITLE"`
+}
+
+type reqifContent struct {
+	Datatypes      []reqifIdentifiable  `xml:"DATATYPES>DATATYPE-DEFINITION-XHTML"`
+	ObjectTypes    []reqifSpecType      `xml:"SPEC-TYPES>SPEC-OBJECT-TYPE"`
+	RelationTypes  []reqifSpecType      `xml:"SPEC-TYPES>SPEC-RELATION-TYPE"`
+	SpecTypes      []reqifSpecType      `xml:"SPEC-TYPES>SPECIFICATION-TYPE"`
+	Objects        []reqifSpecObject    `xml:"SPEC-OBJECTS>SPEC-OBJECT"`
+	Relations      []reqifSpecRelation  `xml:"SPEC-RELATIONS>SPEC-RELATION"`
+	Specifications []reqifSpecification `xml:"SPECIFICATIONS>SPECIFICATION"`
+}
+
+type reqifSpecType struct {
+	reqifIdentifiable
+	XHTML   []reqifAttributeDefinition `xml:"SPEC-ATTRIBUTES>ATTRIBUTE-DEFINITION-XHTML"`
+	Strings []reqifAttributeDefinition `xml:"SPEC-ATTRIBUTES>ATTRIBUTE-DEFINITION-STRING"`
+}
+
+type reqifAttributeDefinition struct {
+	reqifIdentifiable
+	Type string `xml:"TYPE>DATATYPE-DEFINITION-XHTML-REF,omitempty"`
+}
+
+type reqifSpecObject struct {
+	reqifIdentifiable
+	XHTML   []reqifXHTMLValue  `xml:"VALUES>ATTRIBUTE-VALUE-XHTML"`
+	Strings []reqifStringValue `xml:"VALUES>ATTRIBUTE-VALUE-STRING"`
+	Type    string             `xml:"TYPE>SPEC-OBJECT-TYPE-REF"`
+}
+
+type reqifXHTMLValue struct {
+	Definition string `xml:"DEFINITION>ATTRIBUTE-DEFINITION-XHTML-REF"`
+	Value      struct {
+		Content string `xml:",innerxml"`
+	} `xml:"THE-VALUE"`
+}
+
+type reqifStringValue struct {
+	Value      string `xml:"THE-VALUE,attr"`
+	Definition string `xml:"DEFINITION>ATTRIBUTE-DEFINITION-STRING-REF"`
+}
+
+type reqifSpecRelation struct {
+	reqifIdentifiable
+	Source string `xml:"SOURCE>SPEC-OBJECT-REF"`
+	Target string `xml:"TARGET>SPEC-OBJECT-REF"`
+	Type   string `xml:"TYPE>SPEC-RELATION-TYPE-REF"`
+}
+
+type reqifSpecification struct {
+	reqifIdentifiable
+	Children []reqifHierarchy `xml:"CHILDREN>SPEC-HIERARCHY"`
+	Type     string           `xml:"TYPE>SPECIFICATION-TYPE-REF"`
+}
+
+type reqifHierarchy struct {
+	reqifIdentifiable
+	Children []reqifHierarchy `xml:"CHILDREN>SPEC-HIERARCHY"`
+	Object   string           `xml:"OBJECT>SPEC-OBJECT-REF"`
+}
+
+// ReqIFIdentifiers gets the identifiers of all items imported from ReqIF, by uid
+func (data *DataContext) ReqIFIdentifiers() (map[int64]string, error) {
+	identifiers := make(map[int64]string)
+	rows, err := data.conn().Query("select identifier, item from ReqIFIdentifiers")
+	if err != nil {
+		return identifiers, err
+	}
+	defer rows.Close()
+	var identifier string
+	var uid int64
+	for rows.Next() {
+		if err := rows.Scan(&identifier, &uid); err != nil {
+			return identifiers, err
+		}
+		identifiers[uid] = identifier
+	}
+	return identifiers, rows.Err()
+}
+
+// ReqIFIdentifier gets the identifier of an item imported from ReqIF, or an empty string if it has none
+func (data *DataContext) ReqIFIdentifier(uid int64) string {
+	var identifier string
+	_ = data.conn().QueryRow("select identifier from ReqIFIdentifiers where item = ?", uid).Scan(&identifier)
+	return identifier
+}
+
+// SetReqIFIdentifier saves the identifier an item has in ReqIF documents
+func (data *DataContext) SetReqIFIdentifier(uid int64, identifier string) error {
+	if _, err := data.conn().Exec("delete from ReqIFIdentifiers where item = ?", uid); err != nil {
+		return err
+	}
+	_, err := data.conn().Exec("insert into ReqIFIdentifiers (identifier, item) values (?, ?)", identifier, uid)
+	return err
+}
+
+// ReqIFUID gets the uid of an object in a ReqIF document, which is always the same for the same identifier
+func ReqIFUID(identifier string) int64 {
+	// Objects exported from OpenRQ get back their original uid
+	if strings.HasPrefix(identifier, reqifPrefix) {
+		if uid, err := ParseUID(identifier[len(reqifPrefix):]); err == nil {
+			return uid
+		}
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(identifier))
+	return int64(hash.Sum64())
+}
+
+// reqifXHTML converts html, as saved by the edit window, to the value of an xhtml attribute
+func reqifXHTML(text string) string {
+	var builder strings.Builder
+	builder.WriteString("<xhtml:div>")
+	if plain := PlainText(text); plain != "" {
+		for _, line := range strings.Split(plain, "\n") {
+			builder.WriteString("<xhtml:p>" + html.EscapeString(line) + "</xhtml:p>")
+		}
+	}
+	builder.WriteString("</xhtml:div>")
+	return builder.String()
+}
+
+// xhtmlPrefixRegex matches namespace prefixes of tags, like xhtml:p
+var xhtmlPrefixRegex = regexp.MustCompile(`(</?)[\w.-]+:`)
+
+// reqifHTML converts the value of an xhtml attribute to html that can be shown in the edit window
+func reqifHTML(xhtml string) string {
+	return TextToHTML(PlainText(xhtmlPrefixRegex.ReplaceAllString(xhtml, "$1")))
+}
+
+// WriteReqIF writes all items as spec objects, and all links as spec relations
+func (data *DataContext) WriteReqIF(writer io.Writer) error {
+	items, err := data.LoadItems()
+	if err != nil {
+		return err
+	}
+	identifiers, err := data.ReqIFIdentifiers()
+	if err != nil {
+		return err
+	}
+	trace, err := data.Traceability()
+	if err != nil {
+		return err
+	}
+	// Items keep the identifier they were imported with
+	identifier := func(uid int64) string {
+		if id, found := identifiers[uid]; found {
+			return id
+		}
+		return reqifPrefix + FormatUID(uid)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	identifiable := func(id, name string) reqifIdentifiable {
+		return reqifIdentifiable{Identifier: id, LastChange: now, LongName: name}
+	}
+	attribute := func(typeName, name string) reqifAttributeDefinition {
+		return reqifAttributeDefinition{
+			reqifIdentifiable: identifiable(typeName+"-"+name, name),
+			Type:              reqifXHTMLType,
+		}
+	}
+	document := reqifDocument{
+		XMLNS: reqifNamespace,
+		XHTML: xhtmlNamespace,
+		Header: reqifHeader{
+			Identifier:   fmt.Sprintf("orq-header-%v", time.Now().Unix()),
+			CreationTime: now,
+			ToolID:       "OpenRQ",
+			Version:      "1.0",
+			SourceToolID: "OpenRQ",
+			Title:        data.ProjectName(),
+		},
+		Content: reqifContent{
+			Datatypes: []reqifIdentifiable{identifiable(reqifXHTMLType, "XHTML")},
+			ObjectTypes: []reqifSpecType{
+				{
+					reqifIdentifiable: identifiable(reqifProblemType, "Problem"),
+					XHTML: []reqifAttributeDefinition{
+						attribute(reqifProblemType, "description"),
+						attribute(reqifProblemType, "rationale"),
+						attribute(reqifProblemType, "fitCriterion"),
+					},
+				},
+				{
+					reqifIdentifiable: identifiable(reqifSolutionType, "Solution"),
+					XHTML:             []reqifAttributeDefinition{attribute(reqifSolutionType, "description")},
+				},
+			},
+			RelationTypes: []reqifSpecType{{reqifIdentifiable: identifiable(reqifParentType, "parent")}},
+			SpecTypes:     []reqifSpecType{{reqifIdentifiable: identifiable(reqifSpecificationType, "Specification")}},
+		},
+	}
+	// Items as spec objects, with parent links as relations from parent to child
+	uids := make(map[Item]int64)
+	for _, item := range items {
+		uids[item.Item] = item.UID
+	}
+	for _, item := range items {
+		object := reqifSpecObject{reqifIdentifiable: identifiable(identifier(item.UID), "")}
+		value := func(typeName, name, text string) {
+			xhtmlValue := reqifXHTMLValue{Definition: typeName + "-" + name}
+			xhtmlValue.Value.Content = reqifXHTML(text)
+			object.XHTML = append(object.XHTML, xhtmlValue)
+		}
+		if GetItemType(item.Item) == TypeRequirement {
+			object.Type = reqifProblemType
+			value(reqifProblemType, "description", item.Description)
+			value(reqifProblemType, "rationale", item.Rationale)
+			value(reqifProblemType, "fitCriterion", item.FitCriterion)
+		} else {
+			object.Type = reqifSolutionType
+			value(reqifSolutionType, "description", item.Description)
+		}
+		document.Content.Objects = append(document.Content.Objects, object)
+		if parentUID, found := uids[item.Parent]; found {
+			document.Content.Relations = append(document.Content.Relations, reqifSpecRelation{
+				reqifIdentifiable: identifiable(fmt.Sprintf("orq-link-%v", FormatUID(item.UID)), ""),
+				Source:            identifier(parentUID),
+				Target:            identifier(item.UID),
+				Type:              reqifParentType,
+			})
+		}
+	}
+	// The specification has the same tree as the project
+	children := make(map[Item][]TraceItem)
+	roots := make([]TraceItem, 0)
+	for _, item := range trace.Items {
+		if item.Depth == 1 {
+			roots = append(roots, item)
+		} else {
+			children[item.Parent] = append(children[item.Parent], item)
+		}
+	}
+	var hierarchy func(items []TraceItem) []reqifHierarchy
+	hierarchy = func(items []TraceItem) []reqifHierarchy {
+		nodes := make([]reqifHierarchy, 0, len(items))
+		for _, item := range items {
+			nodes = append(nodes, reqifHierarchy{
+				reqifIdentifiable: identifiable(fmt.Sprintf("orq-node-%v", FormatUID(item.UID)), ""),
+				Object:            identifier(item.UID),
+				Children:          hierarchy(children[item.Item]),
+			})
+		}
+		return nodes
+	}
+	document.Content.Specifications = []reqifSpecification{{
+		reqifIdentifiable: identifiable(reqifSpecificationID, data.ProjectName()),
+		Type:              reqifSpecificationType,
+		Children:          hierarchy(roots),
+	}}
+	// Write indented document
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer, "\n")
+	return err
+}
+
+// ExportReqIF saves the project as a ReqIF document
+func (project *Project) ExportReqIF(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := project.Data().WriteReqIF(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// reqifField gets what field an attribute is imported to from its name, where
+// a higher priority replaces values already found
+func reqifField(name string) (field string, priority int) {
+	switch strings.ToLower(strings.Replace(name, " ", "", -1)) {
+	case "description", "reqif.text", "text":
+		return FieldDescription, 2
+	case "reqif.chaptername", "reqif.name", "name", "title":
+		return FieldDescription, 1
+	case "rationale":
+		return FieldRationale, 2
+	case "fitcriterion":
+		return FieldFitCriterion, 2
+	}
+	return "", 0
+}
+
+// ImportReqIF creates a new project in newPath from the ReqIF document in path, and opens it
+func ImportReqIF(path, newPath string) (*Project, error) {
+	// The current project is only closed once the new one is complete
+	if err := WriteReqIFProject(path, newPath); err != nil {
+		return nil, err
+	}
+	return NewProject(newPath)
+}
+
+// WriteReqIFProject creates a new project in newPath from the ReqIF document in path, without opening it
+func WriteReqIFProject(path, newPath string) error {
+	// Try to read and parse file
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var document reqifDocument
+	if err := xml.Unmarshal(content, &document); err != nil {
+		return fmt.Errorf("not a valid ReqIF document: %v", err)
+	}
+	// Check if destination file already exists
+	if _, err = os.Stat(newPath); !os.IsNotExist(err) {
+		return fmt.Errorf("file with name \"%v\" already exists", newPath)
+	}
+	// Find what types are solutions, and what field each attribute is imported to
+	solutionTypes := make(map[string]bool)
+	fields := make(map[string]string)
+	priorities := make(map[string]int)
+	for _, specType := range document.Content.ObjectTypes {
+		solutionTypes[specType.Identifier] = specType.Identifier == reqifSolutionType ||
+			strings.Contains(strings.ToLower(specType.LongName), "solution")
+		for _, definition := range append(specType.XHTML, specType.Strings...) {
+			fields[definition.Identifier], priorities[definition.Identifier] = reqifField(definition.LongName)
+		}
+	}
+	// Create new empty project
+	db, err := OpenDataContext(newPath)
+	if err != nil {
+		return err
+	}
+	title := document.Header.Title
+	if title == "" && len(document.Content.Specifications) > 0 {
+		title = document.Content.Specifications[0].LongName
+	}
+	err = db.Transaction(func() error {
+		db.SetProjectName(title)
+		// Add all objects, where each field gets the value with the highest priority
+		items := make(map[string]Item)
+		order := make([]string, 0, len(document.Content.Objects))
+		for _, object := range document.Content.Objects {
+			if _, found := items[object.Identifier]; found {
+				return fmt.Errorf("duplicate spec object \"%v\"", object.Identifier)
+			}
+			values := make(map[string]string)
+			found := make(map[string]int)
+			setValue := func(definition, text string) {
+				field := fields[definition]
+				if field != "" && priorities[definition] > found[field] {
+					values[field], found[field] = text, priorities[definition]
+				}
+			}
+			for _, value := range object.XHTML {
+				setValue(value.Definition, reqifHTML(value.Value.Content))
+			}
+			for _, value := range object.Strings {
+				setValue(value.Definition, TextToHTML(value.Value))
+			}
+			uid := ReqIFUID(object.Identifier)
+			if db.UIDExists(uid) {
+				return fmt.Errorf("spec object \"%v\" has the same uid as another object", object.Identifier)
+			}
+			var item Item
+			if solutionTypes[object.Type] {
+				id, err := db.AddSolution(values[FieldDescription], uid)
+				if err != nil {
+					return err
+				}
+				item = NewSolution(id)
+			} else {
+				id, err := db.AddRequirement(values[FieldDescription], values[FieldRationale],
+					values[FieldFitCriterion], uid)
+				if err != nil {
+					return err
+				}
+				item = NewRequirement(id)
+			}
+			// Keep identifiers from other tools, to export them again
+			if object.Identifier != reqifPrefix+FormatUID(uid) {
+				if err := db.SetReqIFIdentifier(uid, object.Identifier); err != nil {
+					return err
+				}
+			}
+			items[object.Identifier] = item
+			order = append(order, object.Identifier)
+		}
+		// Relations are from parent to child, and objects without one get their parent from the specification
+		parents := make(map[Item]Item)
+		for _, relation := range document.Content.Relations {
+			parent, parentFound := items[relation.Source]
+			child, childFound := items[relation.Target]
+			if !parentFound || !childFound {
+				fmt.Fprintln(os.Stderr, "warning: ignoring relation to unknown spec object:", relation.Identifier)
+				continue
+			}
+			if _, found := parents[child]; found {
+				fmt.Fprintln(os.Stderr, "warning: ignoring additional parent of spec object:", relation.Target)
+				continue
+			}
+			parents[child] = parent
+		}
+		var addHierarchy func(parent Item, nodes []reqifHierarchy)
+		addHierarchy = func(parent Item, nodes []reqifHierarchy) {
+			for _, node := range nodes {
+				item, found := items[node.Object]
+				if !found {
+					continue
+				}
+				if _, hasParent := parents[item]; !hasParent && parent != nil && parent != item {
+					parents[item] = parent
+				}
+				addHierarchy(item, node.Children)
+			}
+		}
+		for _, specification := range document.Content.Specifications {
+			addHierarchy(nil, specification.Children)
+		}
+		// Link and place all items as a tree
+		children := make(map[Item][]Item)
+		roots := make([]Item, 0)
+		for _, identifier := range order {
+			item := items[identifier]
+			if parent, found := parents[item]; found {
+				if err := db.AddItemChild(parent, item); err != nil {
+					return err
+				}
+				children[parent] = append(children[parent], item)
+			} else {
+				roots = append(roots, item)
+			}
+		}
+		positions := LayoutTree(roots, children)
+		// Items only linked in a loop are placed to the right of everything else
+		unplaced := make([]Item, 0)
+		right := 0
+		for _, identifier := range order {
+			if pos, found := positions[items[identifier]]; !found {
+				unplaced = append(unplaced, items[identifier])
+			} else if pos[0]+layoutColumnWidth > right {
+				right = pos[0] + layoutColumnWidth
+			}
+		}
+		for item, pos := range LayoutTree(unplaced, children) {
+			positions[item] = [2]int{pos[0] + right, pos[1]}
+		}
+		for _, identifier := range order {
+			item := items[identifier]
+			pos := positions[item]
+			if err := db.SetItemValues(item.ID(), GetItemTableName(GetItemType(item)), map[string]interface{}{
+				"x": pos[0],
+				"y": pos[1],
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	// Nothing is left behind if anything failed
+	if err != nil {
+		os.Remove(newPath)
+	}
+	return err
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReqIF(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	db := project.Data()
+	db.SetProjectName("Hull")
+	rootUID, solutionUID := db.ItemUID(), db.ItemUID()
+	root, _ := db.AddRequirement("<p>Hull &amp; deck</p><p>shall not leak</p>", "Safety", "No water after 24 h", rootUID)
+	solution, _ := db.AddSolution("Welded seams", solutionUID)
+	_ = db.AddItemChild(NewRequirement(root), NewSolution(solution))
+	// Export, and import the result again
+	exported := fmt.Sprintf("%v/hull.reqif", tempDir)
+	if err := project.ExportReqIF(exported); err != nil {
+		t.Error("failed to export:", err)
+		return
+	}
+	project.Close()
+	content, _ := ioutil.ReadFile(exported)
+	for _, expected := range []string{
+		"<SPEC-OBJECT IDENTIFIER=\"orq-" + FormatUID(rootUID) + "\"",
+		"<xhtml:p>Hull &amp; deck</xhtml:p><xhtml:p>shall not leak</xhtml:p>",
+		"<SPEC-RELATION-TYPE-REF>orq-type-parent</SPEC-RELATION-TYPE-REF>",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("expected \"%v\" in exported document, but got %v", expected, string(content))
+		}
+	}
+	// Elements are in the order of the schema, with children before the type or object
+	for _, order := range [][]string{
+		{"<SPECIFICATION ", "<CHILDREN>", "<TYPE>"},
+		{"<SPEC-HIERARCHY ", "<CHILDREN>", "<OBJECT>"},
+	} {
+		element := strings.Index(string(content), order[0])
+		if element < 0 {
+			t.Errorf("expected \"%v\" in exported document, but got %v", order[0], string(content))
+			continue
+		}
+		first := strings.Index(string(content)[element:], order[1])
+		second := strings.Index(string(content)[element:], order[2])
+		if first < 0 || second < 0 || first > second {
+			t.Errorf("expected %v before %v in %v, but got %v", order[1], order[2], order[0], string(content))
+		}
+	}
+	imported, err := ImportReqIF(exported, fmt.Sprintf("%v/imported.orq", tempDir))
+	if err != nil {
+		t.Error("failed to import:", err)
+		return
+	}
+	items, _ := imported.Data().LoadItems()
+	imported.Close()
+	if len(items) != 2 {
+		t.Error("expected 2 items, but got", items)
+		return
+	}
+	// Items get back the same uids and contents
+	for _, item := range items {
+		if item.UID == rootUID {
+			if item.Rationale != "Safety" || PlainText(item.Description) != "Hull & deck\nshall not leak" {
+				t.Error("unexpected problem, got", item)
+			}
+		} else if item.UID != solutionUID || GetItemType(item.Item) != TypeSolution || item.Parent == nil {
+			t.Error("unexpected solution, got", item)
+		}
+	}
+	// Objects from other tools always get the same uid, and keep their identifiers when exported
+	foreign := fmt.Sprintf("%v/foreign.reqif", tempDir)
+	_ = ioutil.WriteFile(foreign, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<REQ-IF xmlns="http://www.omg.org/spec/ReqIF/20110401/reqif.xsd" xmlns:xhtml="http://www.w3.org/1999/xhtml">
+  <THE-HEADER><REQ-IF-HEADER IDENTIFIER="h"><TITLE>Shipyard</TITLE></REQ-IF-HEADER></THE-HEADER>
+  <CORE-CONTENT><REQ-IF-CONTENT>
+    <SPEC-TYPES>
+      <SPEC-OBJECT-TYPE IDENTIFIER="t" LONG-NAME="Requirement"><SPEC-ATTRIBUTES>
+        <ATTRIBUTE-DEFINITION-XHTML IDENTIFIER="text" LONG-NAME="ReqIF.Text"/>
+        <ATTRIBUTE-DEFINITION-STRING IDENTIFIER="name" LONG-NAME="ReqIF.ChapterName"/>
+      </SPEC-ATTRIBUTES></SPEC-OBJECT-TYPE>
+    </SPEC-TYPES>
+    <SPEC-OBJECTS>
+      <SPEC-OBJECT IDENTIFIER="_a1"><VALUES>
+        <ATTRIBUTE-VALUE-STRING THE-VALUE="Chapter"><DEFINITION><ATTRIBUTE-DEFINITION-STRING-REF>name</ATTRIBUTE-DEFINITION-STRING-REF></DEFINITION></ATTRIBUTE-VALUE-STRING>
+      </VALUES><TYPE><SPEC-OBJECT-TYPE-REF>t</SPEC-OBJECT-TYPE-REF></TYPE></SPEC-OBJECT>
+      <SPEC-OBJECT IDENTIFIER="_a2"><VALUES>
+        <ATTRIBUTE-VALUE-STRING THE-VALUE="Ignored"><DEFINITION><ATTRIBUTE-DEFINITION-STRING-REF>name</ATTRIBUTE-DEFINITION-STRING-REF></DEFINITION></ATTRIBUTE-VALUE-STRING>
+        <ATTRIBUTE-VALUE-XHTML><DEFINITION><ATTRIBUTE-DEFINITION-XHTML-REF>text</ATTRIBUTE-DEFINITION-XHTML-REF></DEFINITION>
+          <THE-VALUE><xhtml:div>The hull <xhtml:b>shall</xhtml:b> float</xhtml:div></THE-VALUE></ATTRIBUTE-VALUE-XHTML>
+      </VALUES><TYPE><SPEC-OBJECT-TYPE-REF>t</SPEC-OBJECT-TYPE-REF></TYPE></SPEC-OBJECT>
+    </SPEC-OBJECTS>
+    <SPECIFICATIONS><SPECIFICATION IDENTIFIER="s"><CHILDREN>
+      <SPEC-HIERARCHY IDENTIFIER="n1"><OBJECT><SPEC-OBJECT-REF>_a1</SPEC-OBJECT-REF></OBJECT><CHILDREN>
+        <SPEC-HIERARCHY IDENTIFIER="n2"><OBJECT><SPEC-OBJECT-REF>_a2</SPEC-OBJECT-REF></OBJECT></SPEC-HIERARCHY>
+      </CHILDREN></SPEC-HIERARCHY>
+    </CHILDREN></SPECIFICATION></SPECIFICATIONS>
+  </REQ-IF-CONTENT></CORE-CONTENT>
+</REQ-IF>
+`), 0644)
+	uids := make([][]int64, 2)
+	for i := range uids {
+		imported, err := ImportReqIF(foreign, fmt.Sprintf("%v/foreign%v.orq", tempDir, i))
+		if err != nil {
+			t.Error("failed to import foreign document:", err)
+			return
+		}
+		db := imported.Data()
+		items, _ := db.LoadItems()
+		for _, item := range items {
+			uids[i] = append(uids[i], item.UID)
+			if item.UID == ReqIFUID("_a2") && (item.Description != "The hull shall float" || item.Parent == nil) {
+				t.Error("unexpected item from hierarchy, got", item)
+			}
+		}
+		if db.ProjectName() != "Shipyard" {
+			t.Error("unexpected project name, got", db.ProjectName())
+		}
+		if i == 0 {
+			exported := fmt.Sprintf("%v/foreign-exported.reqif", tempDir)
+			if err := imported.ExportReqIF(exported); err != nil {
+				t.Error("failed to export foreign document:", err)
+			}
+			content, _ := ioutil.ReadFile(exported)
+			if !strings.Contains(string(content), "<SPEC-OBJECT IDENTIFIER=\"_a2\"") ||
+				!strings.Contains(string(content), "<SPEC-OBJECT-REF>_a1</SPEC-OBJECT-REF>") {
+				t.Error("expected foreign identifiers to be kept, but got", string(content))
+			}
+		}
+		imported.Close()
+	}
+	if fmt.Sprint(uids[0]) != fmt.Sprint(uids[1]) || len(uids[0]) != 2 {
+		t.Error("expected repeated imports to get the same uids, but got", uids)
+	}
+	if _, err := ImportReqIF(exported, fmt.Sprintf("%v/imported.orq", tempDir)); err == nil {
+		t.Error("expected import to existing file to fail")
+	}
+	// Failed imports leave nothing behind, and keep the current project open
+	current := currentProject
+	content, _ = ioutil.ReadFile(foreign)
+	duplicate := strings.Replace(string(content), `IDENTIFIER="_a2"`, `IDENTIFIER="_a1"`, 1)
+	_ = ioutil.WriteFile(foreign, []byte(duplicate), 0644)
+	partial := fmt.Sprintf("%v/partial.orq", tempDir)
+	if _, err := ImportReqIF(foreign, partial); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Error("expected duplicate spec object to fail, but got", err)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Error("expected partial project to be removed")
+	}
+	if currentProject != current {
+		t.Error("expected current project to still be open")
+	}
+}
+
+func TestLayoutTree(t *testing.T) {
+	root, left, right, leaf := NewRequirement(1), NewSolution(1), NewSolution(2), NewRequirement(2)
+	positions := LayoutTree([]Item{root}, map[Item][]Item{
+		root: {left, right},
+		left: {leaf},
+	})
+	// Leaves get a column each, and parents are centered above their children
+	if positions[leaf] != [2]int{0, 2 * layoutRowHeight} || positions[right] != [2]int{layoutColumnWidth, layoutRowHeight} ||
+		positions[root] != [2]int{layoutColumnWidth / 2, 0} || positions[left] != [2]int{0, layoutRowHeight} {
+		t.Error("unexpected layout, got", positions)
+	}
+}
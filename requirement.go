@@ -37,7 +37,7 @@ func (req Requirement) Description() string {
 	return req.GetValueString("description")
 }
 
-func (req Requirement) SetDescription(value string)  {
+func (req Requirement) SetDescription(value string) {
 	req.SetValue("description", value)
 }
 
@@ -45,7 +45,7 @@ func (req *Requirement) Rationale() string {
 	return req.GetValueString("rationale")
 }
 
-func (req *Requirement) SetRationale(value string)  {
+func (req *Requirement) SetRationale(value string) {
 	req.SetValue("rationale", value)
 }
 
@@ -53,7 +53,7 @@ func (req *Requirement) FitCriterion() string {
 	return req.GetValueString("fitCriterion")
 }
 
-func (req *Requirement) SetFitCriterion(value string)  {
+func (req *Requirement) SetFitCriterion(value string) {
 	req.SetValue("fitCriterion", value)
 }
 
@@ -67,7 +67,7 @@ func (req Requirement) Pos() (int, int) {
 }
 
 func (req Requirement) SetPos(x, y int) {
-	req.SetValues(map[string]interface{} {
+	req.SetValues(map[string]interface{}{
 		"x": x,
 		"y": y,
 	})
@@ -76,15 +76,15 @@ func (req Requirement) SetPos(x, y int) {
 func (req Requirement) Size() (int, int) {
 	var width, height int
 	req.GetValues(map[string]interface{}{
-		"width": &width,
+		"width":  &width,
 		"height": &height,
 	})
 	return width, height
 }
 
 func (req Requirement) SetSize(w, h int) {
-	req.SetValues(map[string]interface{} {
-		"width": w,
+	req.SetValues(map[string]interface{}{
+		"width":  w,
 		"height": h,
 	})
 }
@@ -123,12 +123,8 @@ func (req *Requirement) GetValue(name string, value interface{}) {
 }
 
 func (req *Requirement) GetValues(nameValues map[string]interface{}) {
-	db := currentProject.Data()
-	defer db.Close()
-	for key, value := range nameValues {
-		if err := db.GetItemValue(req.ID(), "Requirements", key, value); err != nil {
-			fmt.Fprintln(os.Stderr, "warning: failed to get property", key, ":", err)
-		}
+	if err := currentProject.Data().GetItemValues(req.ID(), "Requirements", nameValues); err != nil {
+		fmt.Fprintln(os.Stderr, "warning:", err)
 	}
 }
 
@@ -157,17 +153,13 @@ func (req *Requirement) SetValue(name string, value interface{}) {
 }
 
 func (req *Requirement) SetValues(nameValues map[string]interface{}) {
-	db := currentProject.Data()
-	defer db.Close()
-	for key, value := range nameValues {
-		db.SetItemValue(req.ID(), "Requirements", key, value)
+	if err := currentProject.Data().SetItemValues(req.ID(), "Requirements", nameValues); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: failed to set properties of", req.ToString(), ":", err)
 	}
 }
 
 func (req Requirement) IsPropertyNull(columnName string) bool {
-	db := currentProject.Data()
-	defer db.Close()
-	return db.IsItemPropertyNull(req.id, "Requirements", columnName)
+	return currentProject.Data().IsItemPropertyNull(req.id, "Requirements", columnName)
 }
 
 func (req Requirement) ToString() string {
@@ -175,44 +167,68 @@ func (req Requirement) ToString() string {
 }
 
 func (req Requirement) Children() []Item {
-	children := make([]Item, 0)
-	for _, link := range links[req] {
-		if link.parent == req {
-			children = append(children, link.child)
-		}
+	children, err := currentProject.Data().ItemChildren(req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "warning: failed to get children:", err)
 	}
 	return children
 }
 
 type RequirementData struct {
-	ID string
+	ID                                   string
 	Description, Rationale, FitCriterion string
 
 	LinkText string
+	Labels   []string    `json:",omitempty"`
+	Media    []MediaData `json:",omitempty"`
+	// Color, border and shape
 	Look []uint
-	Pos []int
+	Pos  []int
 	Size []int
-	Children []Item
+	// Parent of an item exported as a root, which only happens for items in loops
+	Parent string `json:",omitempty"`
+	// Identifier of the item if it was imported from ReqIF
+	ReqIF    string `json:",omitempty"`
+	Children []json.Marshaler
+}
+
+// JSONData gets the data to export for the requirement from a project, with the specified children
+func (req Requirement) JSONData(data *DataContext, children []json.Marshaler) (RequirementData, error) {
+	item, err := data.LoadItem(req)
+	if err != nil {
+		return RequirementData{}, fmt.Errorf("failed to get %v: %v", req.ToString(), err)
+	}
+	labels, err := data.ItemLabels(item.UID)
+	if err != nil {
+		return RequirementData{}, fmt.Errorf("failed to get labels of %v: %v", req.ToString(), err)
+	}
+	media, err := data.MediaJSON(item.UID)
+	if err != nil {
+		return RequirementData{}, fmt.Errorf("failed to get media of %v: %v", req.ToString(), err)
+	}
+	return RequirementData{
+		ID:           fmt.Sprintf("%x", item.UID),
+		Description:  item.Description,
+		Rationale:    item.Rationale,
+		FitCriterion: item.FitCriterion,
+		Labels:       LabelNames(labels),
+		Media:        media,
+		Children:     children,
+		Look:         []uint{uint(item.Color), uint(item.Border), uint(item.Shape)},
+		Pos:          []int{item.X, item.Y},
+		Size:         []int{item.Width, item.Height},
+		ReqIF:        data.ReqIFIdentifier(item.UID),
+	}, nil
 }
 
 func (req Requirement) MarshalJSON() ([]byte, error) {
-	var description, rationale, fitCriterion string
-	req.GetValues(map[string]interface{}{
-		"description":  &description,
-		"rationale":    &rationale,
-		"fitCriterion": &fitCriterion,
-	})
-	x, y := req.Pos()
-	w, h := req.Size()
-	jsonData, err := json.Marshal(RequirementData{
-		ID:				fmt.Sprintf("%x", req.UID()),
-		Description:	description,
-		Rationale:		rationale,
-		FitCriterion:	fitCriterion,
-		Children:		req.Children(),
-		Look: 			[]uint{0, 0, 0},
-		Pos:			[]int{x, y},
-		Size: 			[]int{w, h},
-	})
-	return jsonData, err
-}
\ No newline at end of file
+	children := make([]json.Marshaler, 0)
+	for _, child := range req.Children() {
+		children = append(children, child)
+	}
+	data, err := req.JSONData(currentProject.Data(), children)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(data)
+}
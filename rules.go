@@ -0,0 +1,414 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity is how serious it is when a validation rule fails
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Severities gets all severities, the most serious first
+func Severities() []Severity {
+	return []Severity{SeverityError, SeverityWarning, SeverityInfo}
+}
+
+// ParseSeverity gets a severity from its name
+func ParseSeverity(name string) (Severity, error) {
+	for _, severity := range Severities() {
+		if string(severity) == name {
+			return severity, nil
+		}
+	}
+	return "", fmt.Errorf("unknown severity \"%v\" (expected error, warning or info)", name)
+}
+
+// ValidationRule is a check that can be run on all items in a project.
+// Which rules are enabled, and how, is saved in ValidationRules in each project.
+type ValidationRule struct {
+	// Tag identifies the rule in ValidationRules
+	Tag string
+	// Name and Info describe the rule to the user
+	Name, Info string
+	Enabled    bool
+	Severity   Severity
+	// Params are rule specific settings, like limits
+	Params map[string]int
+	// Expression is the source of custom rules, that every item should match
+	Expression string
+	// check finds all items that fail the rule, where each finding can include several items
+	check func(context *ValidationContext, params map[string]int) [][]Item
+}
+
+// ParamNames gets the names of all parameters, sorted
+func (rule ValidationRule) ParamNames() []string {
+	names := make([]string, 0, len(rule.Params))
+	for name := range rule.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// eachItem gets a finding for each item
+func eachItem(items []Item) [][]Item {
+	findings := make([][]Item, len(items))
+	for i, item := range items {
+		findings[i] = []Item{item}
+	}
+	return findings
+}
+
+// BuiltinRules gets all built-in rules with their default settings,
+// in the same order as ValidationOption
+func BuiltinRules() []ValidationRule {
+	return []ValidationRule{
+		{
+			Tag:      "same-type",
+			Name:     "Links to same type",
+			Info:     "Linking items of the same type",
+			Enabled:  true,
+			Severity: SeverityError,
+			check: func(context *ValidationContext, params map[string]int) [][]Item {
+				return eachItem(ValidateLinks(context.Graph))
+			},
+		},
+		{
+			Tag:      "one-root",
+			Name:     "One-to-one root",
+			Info:     "Roots of the current tree having an one-to-one relation to its child",
+			Enabled:  true,
+			Severity: SeverityWarning,
+			Params: map[string]int{
+				"maxChildren": 1,
+			},
+			check: func(context *ValidationContext, params map[string]int) [][]Item {
+				items := make([]Item, 0)
+				for _, root := range context.Graph.Roots() {
+					if len(context.Graph.Children(root)) > params["maxChildren"] {
+						items = append(items, root)
+					}
+				}
+				return eachItem(items)
+			},
+		},
+		{
+			Tag:      "link-loop",
+			Name:     "Linking loop",
+			Info:     "Items that link to each other in a loop",
+			Enabled:  true,
+			Severity: SeverityError,
+			check: func(context *ValidationContext, params map[string]int) [][]Item {
+				// Each loop is a finding, with the full path
+				return context.Graph.Cycles()
+			},
+		},
+		{
+			Tag:      "link-error",
+			Name:     "Invalid links",
+			Info:     "Link that could not be saved to the project file",
+			Enabled:  true,
+			Severity: SeverityError,
+			check: func(context *ValidationContext, params map[string]int) [][]Item {
+				return eachItem(ValidateLinkErrors(context.Graph))
+			},
+		},
+		{
+			Tag:      "weak-words",
+			Name:     "Weak words",
+			Info:     "Problems with vague words, like \"fast\", \"user-friendly\" or \"etc.\"",
+			Enabled:  true,
+			Severity: SeverityWarning,
+			check:    qualityCheck("weak-words"),
+		},
+		{
+			Tag:      "passive-voice",
+			Name:     "Passive voice",
+			Info:     "Problem descriptions not saying who or what does something",
+			Enabled:  true,
+			Severity: SeverityInfo,
+			check:    qualityCheck("passive-voice"),
+		},
+		{
+			Tag:      "multiple-shall",
+			Name:     "Several shall",
+			Info:     "Problem descriptions with more than one \"shall\" in the same statement",
+			Enabled:  true,
+			Severity: SeverityWarning,
+			check:    qualityCheck("multiple-shall"),
+		},
+		{
+			Tag:      "fit-measurable",
+			Name:     "Unmeasurable fit criterion",
+			Info:     "Fit criteria without a number or unit",
+			Enabled:  true,
+			Severity: SeverityWarning,
+			check:    qualityCheck("fit-measurable"),
+		},
+		{
+			Tag:      "empty-rationale",
+			Name:     "Empty rationale",
+			Info:     "Problems without a rationale",
+			Enabled:  true,
+			Severity: SeverityWarning,
+			check:    qualityCheck("empty-rationale"),
+		},
+	}
+}
+
+// IsCustom checks if the rule was added by the user, instead of being built-in
+func (rule ValidationRule) IsCustom() bool {
+	for _, builtin := range BuiltinRules() {
+		if builtin.Tag == rule.Tag {
+			return false
+		}
+	}
+	return true
+}
+
+// NewCustomRule creates a rule that fails for all items where expression is false,
+// or returns an error if the expression is invalid
+func NewCustomRule(name, expression string, severity Severity) (ValidationRule, error) {
+	rule := ValidationRule{
+		Tag:        strings.TrimSpace(name),
+		Enabled:    true,
+		Severity:   severity,
+		Params:     map[string]int{},
+		Expression: strings.TrimSpace(expression),
+	}
+	rule.Name = rule.Tag
+	rule.Info = rule.Expression
+	if rule.Tag == "" {
+		return rule, fmt.Errorf("rule name can't be empty")
+	}
+	if !rule.IsCustom() {
+		return rule, fmt.Errorf("\"%v\" is a built-in rule", rule.Tag)
+	}
+	expr, err := ParseExpression(rule.Expression)
+	if err != nil {
+		// Still usable for showing and editing the rule, failing with a single finding without items
+		rule.Info = fmt.Sprintf("Invalid rule: %v", err)
+		rule.check = func(context *ValidationContext, params map[string]int) [][]Item {
+			return [][]Item{{}}
+		}
+		return rule, err
+	}
+	rule.check = func(context *ValidationContext, params map[string]int) [][]Item {
+		items := make([]Item, 0)
+		for _, item := range context.Graph.Items() {
+			if !expr.Check(context, item) {
+				items = append(items, item)
+			}
+		}
+		return eachItem(items)
+	}
+	return rule, nil
+}
+
+// ValidationRules gets all rules, with the settings saved in the project,
+// followed by all custom rules in the order they were added
+func (data *DataContext) ValidationRules() ([]ValidationRule, error) {
+	rules := BuiltinRules()
+	rows, err := data.conn().Query("select coalesce(tag, ''), coalesce(enabled, 1), " +
+		"coalesce(severity, ''), coalesce(data, '') from ValidationRules order by _rowid_")
+	if err != nil {
+		return rules, fmt.Errorf("failed to get validation rules: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tag, severity, params string
+		var enabled bool
+		if err := rows.Scan(&tag, &enabled, &severity, &params); err != nil {
+			return rules, fmt.Errorf("failed to get validation rule: %v", err)
+		}
+		parsedSeverity, severityErr := ParseSeverity(severity)
+		if (ValidationRule{Tag: tag}).IsCustom() {
+			// Custom rules save their expression in data, invalid ones are kept to be fixed,
+			// but always fail validation
+			if severityErr != nil {
+				parsedSeverity = SeverityError
+			}
+			rule, err := NewCustomRule(tag, params, parsedSeverity)
+			if err != nil {
+				rule.Severity = SeverityError
+			}
+			rule.Enabled = enabled
+			rules = append(rules, rule)
+			continue
+		}
+		for i := range rules {
+			if rules[i].Tag != tag {
+				continue
+			}
+			rules[i].Enabled = enabled
+			if severityErr == nil {
+				rules[i].Severity = parsedSeverity
+			}
+			// Only known parameters are used, others keep their default value
+			saved := make(map[string]int)
+			if params != "" {
+				if err := json.Unmarshal([]byte(params), &saved); err != nil {
+					return rules, fmt.Errorf("invalid parameters for rule %v: %v", tag, err)
+				}
+			}
+			for name, value := range saved {
+				if _, found := rules[i].Params[name]; found {
+					rules[i].Params[name] = value
+				}
+			}
+		}
+	}
+	return rules, rows.Err()
+}
+
+// SaveValidationRule saves the settings of a rule in the project,
+// or the expression for custom rules
+func (data *DataContext) SaveValidationRule(rule ValidationRule) error {
+	value := rule.Expression
+	if !rule.IsCustom() {
+		params, err := json.Marshal(rule.Params)
+		if err != nil {
+			return err
+		}
+		value = string(params)
+	}
+	result, err := data.conn().Exec("update ValidationRules set enabled = ?, severity = ?, data = ? where tag = ?",
+		rule.Enabled, rule.Severity, value, rule.Tag)
+	if err != nil {
+		return fmt.Errorf("failed to save validation rule: %v", err)
+	}
+	if count, err := result.RowsAffected(); err != nil || count > 0 {
+		return err
+	}
+	_, err = data.conn().Exec("insert into ValidationRules (tag, enabled, severity, data) values (?, ?, ?, ?)",
+		rule.Tag, rule.Enabled, rule.Severity, value)
+	return err
+}
+
+// SaveCustomRule adds a custom rule, or replaces the rule previously named previous
+func (data *DataContext) SaveCustomRule(previous string, rule ValidationRule) error {
+	if !rule.IsCustom() {
+		return fmt.Errorf("\"%v\" is a built-in rule", rule.Tag)
+	}
+	if rule.Tag != previous {
+		var count int
+		if err := data.conn().QueryRow("select count(*) from ValidationRules where tag = ?",
+			rule.Tag).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("a rule named \"%v\" already exists", rule.Tag)
+		}
+	}
+	return data.Transaction(func() error {
+		if previous != "" && previous != rule.Tag {
+			if err := data.RemoveCustomRule(previous); err != nil {
+				return err
+			}
+		}
+		return data.SaveValidationRule(rule)
+	})
+}
+
+// RemoveCustomRule removes a custom rule from the project
+func (data *DataContext) RemoveCustomRule(tag string) error {
+	if !(ValidationRule{Tag: tag}).IsCustom() {
+		return fmt.Errorf("\"%v\" is a built-in rule and can only be disabled", tag)
+	}
+	result, err := data.conn().Exec("delete from ValidationRules where tag = ?", tag)
+	if err != nil {
+		return fmt.Errorf("failed to remove validation rule: %v", err)
+	}
+	if count, err := result.RowsAffected(); err == nil && count == 0 {
+		return fmt.Errorf("no rule named \"%v\"", tag)
+	}
+	return nil
+}
+
+// ValidationContext is what rules are run on
+type ValidationContext struct {
+	Graph *Graph
+	// Contents and labels of all items, only used by custom rules
+	Items  map[Item]ItemData
+	Labels map[int64][]Label
+	// Depth of each item, found when first needed
+	depths map[Item]int
+}
+
+// NewValidationContext creates a context for validating graph,
+// with contents and labels of the items in db
+func NewValidationContext(db *DataContext, graph *Graph) (*ValidationContext, error) {
+	context := &ValidationContext{
+		Graph:  graph,
+		Items:  make(map[Item]ItemData),
+		Labels: make(map[int64][]Label),
+	}
+	items, err := db.LoadItems()
+	if err != nil {
+		return context, err
+	}
+	for _, item := range items {
+		context.Items[item.Item] = item
+	}
+	context.Labels, err = db.AllItemLabels()
+	return context, err
+}
+
+// Depth gets how deep an item is, using the longest path from a root, where roots are 1.
+// Items in loops are only counted once.
+func (context *ValidationContext) Depth(item Item) int {
+	if context.depths == nil {
+		context.depths = make(map[Item]int)
+	}
+	if depth, found := context.depths[item]; found {
+		return depth
+	}
+	// Visiting items are marked as roots, to stop at loops
+	context.depths[item] = 1
+	depth := 1
+	for _, parent := range context.Graph.Parents(item) {
+		if parentDepth := context.Depth(parent) + 1; parentDepth > depth {
+			depth = parentDepth
+		}
+	}
+	context.depths[item] = depth
+	return depth
+}
+
+// ValidationFinding is an item that failed a validation rule,
+// or several items, like all items in a loop in the order they link to each other
+type ValidationFinding struct {
+	Rule  ValidationRule
+	Items []Item
+}
+
+// Validate runs all enabled rules
+func Validate(context *ValidationContext, rules []ValidationRule) []ValidationFinding {
+	findings := make([]ValidationFinding, 0)
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		for _, items := range rule.check(context, rule.Params) {
+			findings = append(findings, ValidationFinding{rule, items})
+		}
+	}
+	return findings
+}
+
+// FailedRules gets the tags of all rules with any findings
+func FailedRules(findings []ValidationFinding) map[string]bool {
+	failed := make(map[string]bool)
+	for _, finding := range findings {
+		failed[finding.Rule.Tag] = true
+	}
+	return failed
+}
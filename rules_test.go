@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestValidationRules(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	db := project.Data()
+	// New projects use the default settings
+	rules, err := db.ValidationRules()
+	if err != nil || len(rules) != len(BuiltinRules()) {
+		t.Error("failed to get default rules:", err)
+		return
+	}
+	// A root with two children fails the default one-to-one rule
+	graph := NewGraph()
+	root := NewRequirement(1)
+	graph.AddItem(root, "root")
+	for i := int64(1); i <= 2; i++ {
+		graph.AddItem(NewSolution(i), "child")
+		graph.AddLink(root, NewSolution(i))
+	}
+	findings := Validate(&ValidationContext{Graph: graph}, rules)
+	if len(findings) != 1 || findings[0].Rule.Tag != "one-root" || findings[0].Rule.Severity != SeverityWarning {
+		t.Error("expected one-to-one root warning, but got", findings)
+	}
+	// Saved settings are used next time, and the limit allows two children
+	rules[OneRoot].Params["maxChildren"] = 2
+	rules[OneRoot].Severity = SeverityInfo
+	rules[SameType].Enabled = false
+	for _, rule := range rules {
+		if err := db.SaveValidationRule(rule); err != nil {
+			t.Error("failed to save rule:", err)
+		}
+	}
+	if err := db.SaveValidationRule(rules[OneRoot]); err != nil {
+		t.Error("failed to save rule again:", err)
+	}
+	if rules, err = db.ValidationRules(); err != nil {
+		t.Error("failed to get saved rules:", err)
+		return
+	}
+	if rule := rules[OneRoot]; rule.Params["maxChildren"] != 2 || rule.Severity != SeverityInfo || rules[SameType].Enabled {
+		t.Error("expected saved settings, but got", rules)
+	}
+	if findings := Validate(&ValidationContext{Graph: graph}, rules); len(findings) != 0 {
+		t.Error("expected no findings with new limit, but got", findings)
+	}
+	var count int
+	if err := db.conn().QueryRow("select count(*) from ValidationRules").Scan(&count); err != nil || count != len(rules) {
+		t.Error("expected one row for each rule, but got", count, err)
+	}
+}
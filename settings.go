@@ -1,3 +1,6 @@
+//go:build !headless
+// +build !headless
+
 package main
 
 import (
@@ -27,4 +30,4 @@ func (set *Settings) LastProject() string {
 
 func (set *Settings) SetLastProject(value string) {
 	set.settings.SetValue("lastProject", core.NewQVariant1(value))
-}
\ No newline at end of file
+}
@@ -51,7 +51,7 @@ func (sol Solution) Pos() (int, int) {
 }
 
 func (sol Solution) SetPos(x, y int) {
-	sol.SetValues(map[string]interface{} {
+	sol.SetValues(map[string]interface{}{
 		"x": x,
 		"y": y,
 	})
@@ -60,15 +60,15 @@ func (sol Solution) SetPos(x, y int) {
 func (sol Solution) Size() (int, int) {
 	var width, height int
 	sol.GetValues(map[string]interface{}{
-		"width": &width,
+		"width":  &width,
 		"height": &height,
 	})
 	return width, height
 }
 
 func (sol Solution) SetSize(w, h int) {
-	sol.SetValues(map[string]interface{} {
-		"width": w,
+	sol.SetValues(map[string]interface{}{
+		"width":  w,
 		"height": h,
 	})
 }
@@ -107,12 +107,8 @@ func (sol *Solution) GetValue(name string, value interface{}) {
 }
 
 func (sol *Solution) GetValues(nameValues map[string]interface{}) {
-	db := currentProject.Data()
-	defer db.Close()
-	for key, value := range nameValues {
-		if err := db.GetItemValue(sol.ID(), "Solutions", key, value); err != nil {
-			fmt.Fprintln(os.Stderr, "warning: failed to get property", key, ":", err)
-		}
+	if err := currentProject.Data().GetItemValues(sol.ID(), "Solutions", nameValues); err != nil {
+		fmt.Fprintln(os.Stderr, "warning:", err)
 	}
 }
 
@@ -141,17 +137,13 @@ func (sol *Solution) SetValue(name string, value interface{}) {
 }
 
 func (sol *Solution) SetValues(nameValues map[string]interface{}) {
-	db := currentProject.Data()
-	defer db.Close()
-	for key, value := range nameValues {
-		db.SetItemValue(sol.ID(), "Solutions", key, value)
+	if err := currentProject.Data().SetItemValues(sol.ID(), "Solutions", nameValues); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: failed to set properties of", sol.ToString(), ":", err)
 	}
 }
 
 func (sol Solution) IsPropertyNull(columnName string) bool {
-	db := currentProject.Data()
-	defer db.Close()
-	return db.IsItemPropertyNull(sol.id, "Solutions", columnName)
+	return currentProject.Data().IsItemPropertyNull(sol.id, "Solutions", columnName)
 }
 
 func (sol Solution) ToString() string {
@@ -159,36 +151,66 @@ func (sol Solution) ToString() string {
 }
 
 func (sol Solution) Children() []Item {
-	children := make([]Item, 0)
-	for _, link := range links[sol] {
-		if link.parent == sol {
-			children = append(children, link.child)
-		}
+	children, err := currentProject.Data().ItemChildren(sol)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "warning: failed to get children:", err)
 	}
 	return children
 }
 
 type SolutionData struct {
-	ID string
+	ID          string
 	Description string
-	Media []string
+	Link        string `json:",omitempty"`
+	Media       []MediaData
+	Labels      []string `json:",omitempty"`
+	// Color, border and shape
 	Look []uint
-	Pos []int
+	Pos  []int
 	Size []int
-	Children []Item
+	// Parent of an item exported as a root, which only happens for items in loops
+	Parent string `json:",omitempty"`
+	// Identifier of the item if it was imported from ReqIF
+	ReqIF    string `json:",omitempty"`
+	Children []json.Marshaler
+}
+
+// JSONData gets the data to export for the solution from a project, with the specified children
+func (sol Solution) JSONData(data *DataContext, children []json.Marshaler) (SolutionData, error) {
+	item, err := data.LoadItem(sol)
+	if err != nil {
+		return SolutionData{}, fmt.Errorf("failed to get %v: %v", sol.ToString(), err)
+	}
+	labels, err := data.ItemLabels(item.UID)
+	if err != nil {
+		return SolutionData{}, fmt.Errorf("failed to get labels of %v: %v", sol.ToString(), err)
+	}
+	media, err := data.MediaJSON(item.UID)
+	if err != nil {
+		return SolutionData{}, fmt.Errorf("failed to get media of %v: %v", sol.ToString(), err)
+	}
+	return SolutionData{
+		ID:          fmt.Sprintf("%x", item.UID),
+		Description: item.Description,
+		Link:        item.Link,
+		Media:       media,
+		Labels:      LabelNames(labels),
+		Children:    children,
+		Look:        []uint{uint(item.Color), uint(item.Border), uint(item.Shape)},
+		Pos:         []int{item.X, item.Y},
+		Size:        []int{item.Width, item.Height},
+		ReqIF:       data.ReqIFIdentifier(item.UID),
+	}, nil
 }
 
 func (sol Solution) MarshalJSON() ([]byte, error) {
-	x, y := sol.Pos()
-	w, h := sol.Size()
-	jsonData, err := json.Marshal(SolutionData{
-		ID:				fmt.Sprintf("%x", sol.UID()),
-		Description:	sol.Description(),
-		Media:			[]string{},
-		Children:		sol.Children(),
-		Look: 			[]uint{0, 0, 0},
-		Pos:			[]int{x, y},
-		Size: 			[]int{w, h},
-	})
-	return jsonData, err
-}
\ No newline at end of file
+	children := make([]json.Marshaler, 0)
+	for _, child := range sol.Children() {
+		children = append(children, child)
+	}
+	data, err := sol.JSONData(currentProject.Data(), children)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(data)
+}
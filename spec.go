@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SpecItem is a problem or solution in a specification document, with all text as plain text
+type SpecItem struct {
+	Item                                 Item
+	UID                                  int64
+	Description, Rationale, FitCriterion string
+}
+
+// Title gets the first line of the description, shortened, or the type and uid if it's empty
+func (item SpecItem) Title() string {
+	line := strings.SplitN(item.Description, "\n", 2)[0]
+	if line == "" {
+		return fmt.Sprintf("%v %v", GetItemName(item.Item), FormatUID(item.UID))
+	}
+	return Truncate(line, 60)
+}
+
+// SpecSection is a numbered section for a problem, with the solutions to it
+type SpecSection struct {
+	// Number of the section, like 1.2.3, where the level is the number of parts
+	Number string
+	Level  int
+	// Problem of the section, nil if the section is for a solution without a parent
+	Problem *SpecItem
+	// Solution the problem was derived from, if any
+	From *SpecItem
+	// Children of the problem that are solutions, and solutions to those solutions
+	Solutions []SpecItem
+}
+
+// Title gets the title of the problem, or of the solution if there is no problem
+func (section *SpecSection) Title() string {
+	if section.Problem != nil {
+		return section.Problem.Title()
+	}
+	return section.Solutions[0].Title()
+}
+
+// Specification is a document with a section for each problem, depth first from each root
+type Specification struct {
+	Title    string
+	Sections []SpecSection
+}
+
+// Specification gets the specification document of the project
+func (data *DataContext) Specification() (*Specification, error) {
+	items, err := data.LoadItems()
+	if err != nil {
+		return nil, err
+	}
+	graph := NewGraphFromItems(items)
+	specItems := make(map[Item]SpecItem)
+	for _, item := range items {
+		specItems[item.Item] = SpecItem{
+			item.Item, item.UID,
+			PlainText(item.Description), PlainText(item.Rationale), PlainText(item.FitCriterion),
+		}
+	}
+	spec := &Specification{Title: data.ProjectName()}
+	// Items are only visited once, even if they're in a loop or have several parents
+	visited := make(map[Item]bool)
+	var visitSection func(item Item, number string, level int, from *SpecItem)
+	var visitSolution func(solution Item, section *SpecSection, sections *[]func(number string))
+	visitSection = func(item Item, number string, level int, from *SpecItem) {
+		visited[item] = true
+		index := len(spec.Sections)
+		spec.Sections = append(spec.Sections, SpecSection{Number: number, Level: level, From: from})
+		section := &spec.Sections[index]
+		// Subsections are numbered in the order they're found, and added after all solutions
+		subsections := make([]func(number string), 0)
+		if GetItemType(item) == TypeSolution {
+			visitSolution(item, section, &subsections)
+		} else {
+			problem := specItems[item]
+			section.Problem = &problem
+			for _, child := range graph.Children(item) {
+				if visited[child] {
+					continue
+				}
+				if GetItemType(child) == TypeSolution {
+					visitSolution(child, section, &subsections)
+					continue
+				}
+				visited[child] = true
+				child := child
+				subsections = append(subsections, func(number string) {
+					visitSection(child, number, level+1, nil)
+				})
+			}
+		}
+		for i, subsection := range subsections {
+			subsection(fmt.Sprintf("%v.%v", number, i+1))
+		}
+	}
+	visitSolution = func(solution Item, section *SpecSection, subsections *[]func(number string)) {
+		visited[solution] = true
+		from := specItems[solution]
+		section.Solutions = append(section.Solutions, from)
+		for _, child := range graph.Children(solution) {
+			if visited[child] {
+				continue
+			}
+			if GetItemType(child) == TypeSolution {
+				visitSolution(child, section, subsections)
+				continue
+			}
+			visited[child] = true
+			child, level := child, section.Level
+			*subsections = append(*subsections, func(number string) {
+				visitSection(child, number, level+1, &from)
+			})
+		}
+	}
+	// Items only found in loops are added after all roots
+	roots := graph.Roots()
+	roots = append(roots, graph.Items()...)
+	count := 0
+	for _, root := range roots {
+		if !visited[root] {
+			count++
+			visitSection(root, fmt.Sprint(count), 1, nil)
+		}
+	}
+	return spec, nil
+}
+
+// SpecFormats are all formats the specification can be exported as, by file extension
+var SpecFormats = []string{".md", ".html", ".pdf"}
+
+// IsSpecFormat checks if format is one of SpecFormats
+func IsSpecFormat(format string) bool {
+	for _, specFormat := range SpecFormats {
+		if specFormat == format {
+			return true
+		}
+	}
+	return false
+}
+
+// specFormatError is returned for formats not in SpecFormats
+func specFormatError(format string) error {
+	return fmt.Errorf("unknown specification format \"%v\" (expected %v)", format, strings.Join(SpecFormats, ", "))
+}
+
+// Write writes the specification in the specified format, one of SpecFormats
+func (spec *Specification) Write(writer io.Writer, format string) error {
+	switch format {
+	case ".md":
+		return spec.WriteMarkdown(writer)
+	case ".html":
+		return spec.WriteHTML(writer)
+	case ".pdf":
+		return spec.WritePDF(writer)
+	}
+	return specFormatError(format)
+}
+
+// Export writes the specification to a file, in the format of its extension
+func (spec *Specification) Export(path string) error {
+	format := strings.ToLower(filepath.Ext(path))
+	if !IsSpecFormat(format) {
+		return specFormatError(format)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := spec.Write(file, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// markdownEscaper escapes characters that would otherwise format the text
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`)
+
+// markdownText escapes text, keeping line breaks within the same paragraph
+func markdownText(text string) string {
+	return strings.ReplaceAll(markdownEscaper.Replace(text), "\n", "  \n")
+}
+
+// WriteMarkdown writes the specification as markdown, with a heading for each section
+func (spec *Specification) WriteMarkdown(writer io.Writer) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %v\n", markdownText(spec.Title))
+	for _, section := range spec.Sections {
+		level := section.Level + 1
+		if level > 6 {
+			level = 6
+		}
+		fmt.Fprintf(&builder, "\n%v %v %v\n\n", strings.Repeat("#", level), section.Number,
+			markdownText(section.Title()))
+		if section.From != nil {
+			fmt.Fprintf(&builder, "*Derived from solution: %v*\n\n", markdownText(section.From.Title()))
+		}
+		if problem := section.Problem; problem != nil {
+			fmt.Fprintf(&builder, "%v\n\n", markdownText(problem.Description))
+			if problem.Rationale != "" {
+				fmt.Fprintf(&builder, "**Rationale:** %v\n\n", markdownText(problem.Rationale))
+			}
+			if problem.FitCriterion != "" {
+				fmt.Fprintf(&builder, "**Fit criterion:** %v\n\n", markdownText(problem.FitCriterion))
+			}
+		}
+		if len(section.Solutions) > 0 {
+			builder.WriteString("**Solutions:**\n\n")
+			for _, solution := range section.Solutions {
+				// Continued lines are indented to stay in the list item
+				text := strings.ReplaceAll(markdownText(solution.Description), "\n", "\n  ")
+				fmt.Fprintf(&builder, "- %v (`%v`)\n", text, FormatUID(solution.UID))
+			}
+			builder.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(writer, strings.TrimRight(builder.String(), "\n")+"\n")
+	return err
+}
+
+// specStyle is included in html exports, to keep them self-contained
+const specStyle = `body { font-family: sans-serif; margin: 2em auto; max-width: 50em; line-height: 1.4; }
+h2, h3, h4, h5, h6 { margin-top: 1.5em; }
+.from { color: #757575; font-style: italic; }
+.uid { color: #757575; font-family: monospace; }
+.solutions { border-left: 4px solid #2196f3; padding-left: 1em; }`
+
+// htmlText escapes text, keeping line breaks
+func htmlText(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// WriteHTML writes the specification as a single html file
+func (spec *Specification) WriteHTML(writer io.Writer) error {
+	var builder strings.Builder
+	builder.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&builder, "<title>%v</title>\n<style>\n%v\n</style>\n</head>\n<body>\n",
+		html.EscapeString(spec.Title), specStyle)
+	fmt.Fprintf(&builder, "<h1>%v</h1>\n", html.EscapeString(spec.Title))
+	for _, section := range spec.Sections {
+		level := section.Level + 1
+		if level > 6 {
+			level = 6
+		}
+		fmt.Fprintf(&builder, "<h%v id=\"section-%v\">%v %v</h%v>\n", level, section.Number, section.Number,
+			html.EscapeString(section.Title()), level)
+		if section.From != nil {
+			fmt.Fprintf(&builder, "<p class=\"from\">Derived from solution: %v</p>\n",
+				html.EscapeString(section.From.Title()))
+		}
+		if problem := section.Problem; problem != nil {
+			fmt.Fprintf(&builder, "<p>%v</p>\n", htmlText(problem.Description))
+			if problem.Rationale != "" {
+				fmt.Fprintf(&builder, "<p><strong>Rationale:</strong> %v</p>\n", htmlText(problem.Rationale))
+			}
+			if problem.FitCriterion != "" {
+				fmt.Fprintf(&builder, "<p><strong>Fit criterion:</strong> %v</p>\n", htmlText(problem.FitCriterion))
+			}
+		}
+		if len(section.Solutions) > 0 {
+			builder.WriteString("<div class=\"solutions\">\n<p><strong>Solutions:</strong></p>\n<ul>\n")
+			for _, solution := range section.Solutions {
+				fmt.Fprintf(&builder, "<li>%v <span class=\"uid\">%v</span></li>\n",
+					htmlText(solution.Description), FormatUID(solution.UID))
+			}
+			builder.WriteString("</ul>\n</div>\n")
+		}
+	}
+	builder.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// WritePDF writes the specification as a pdf document
+func (spec *Specification) WritePDF(writer io.Writer) error {
+	pdf := NewPDFDocument(spec.Title)
+	pdf.Heading(spec.Title, 0)
+	for _, section := range spec.Sections {
+		pdf.Heading(fmt.Sprintf("%v %v", section.Number, section.Title()), section.Level)
+		if section.From != nil {
+			pdf.Paragraph("", "Derived from solution: "+section.From.Title(), 0)
+		}
+		if problem := section.Problem; problem != nil {
+			pdf.Paragraph("", problem.Description, 0)
+			if problem.Rationale != "" {
+				pdf.Paragraph("Rationale:", problem.Rationale, 0)
+			}
+			if problem.FitCriterion != "" {
+				pdf.Paragraph("Fit criterion:", problem.FitCriterion, 0)
+			}
+		}
+		if len(section.Solutions) > 0 {
+			pdf.Paragraph("Solutions:", "", 0)
+			for _, solution := range section.Solutions {
+				pdf.Paragraph("•", fmt.Sprintf("%v (%v)", solution.Description, FormatUID(solution.UID)), 12)
+			}
+		}
+	}
+	return pdf.Write(writer)
+}
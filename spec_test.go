@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSpecification(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	db := project.Data()
+	db.SetProjectName("Ferry")
+	// Root problem, solved by a solution, which has a new problem, and a separate root
+	root, _ := db.AddRequirement("<p>Cars *must* board</p><p>quickly</p>", "Short stops", "Under 10 min", db.ItemUID())
+	solution, _ := db.AddSolution("Bow ramp", db.ItemUID())
+	child, _ := db.AddRequirement("Ramp must not leak", "", "", db.ItemUID())
+	_, _ = db.AddRequirement("Passengers need seats", "", "", db.ItemUID())
+	_ = db.AddItemChild(NewRequirement(root), NewSolution(solution))
+	_ = db.AddItemChild(NewSolution(solution), NewRequirement(child))
+	spec, err := db.Specification()
+	if err != nil {
+		t.Error("failed to get specification:", err)
+		return
+	}
+	sections := make([]string, len(spec.Sections))
+	for i, section := range spec.Sections {
+		sections[i] = fmt.Sprintf("%v %v (%v)", section.Number, section.Title(), len(section.Solutions))
+	}
+	if result := strings.Join(sections, ", "); result !=
+		"1 Cars *must* board (1), 1.1 Ramp must not leak (0), 2 Passengers need seats (0)" {
+		t.Error("unexpected sections, got", result)
+	}
+	if from := spec.Sections[1].From; from == nil || from.Description != "Bow ramp" {
+		t.Error("expected child problem to be derived from solution, got", from)
+	}
+	// Markdown is escaped, with solutions nested under the problem
+	var buffer bytes.Buffer
+	if err := spec.WriteMarkdown(&buffer); err != nil {
+		t.Error("failed to write markdown:", err)
+	}
+	for _, expected := range []string{
+		"# Ferry\n", "## 1 Cars \\*must\\* board\n", "Cars \\*must\\* board  \nquickly\n",
+		"**Fit criterion:** Under 10 min\n", "- Bow ramp (`", "### 1.1 Ramp must not leak\n",
+	} {
+		if !strings.Contains(buffer.String(), expected) {
+			t.Errorf("expected \"%v\" in markdown, but got %v", expected, buffer.String())
+		}
+	}
+	buffer.Reset()
+	if err := spec.WriteHTML(&buffer); err != nil || !strings.Contains(buffer.String(), "<h3 id=\"section-1.1\">") {
+		t.Error("unexpected html, got", buffer.String(), err)
+	}
+	// Pdf has a valid cross-reference table, and a page for the text
+	buffer.Reset()
+	if err := spec.WritePDF(&buffer); err != nil {
+		t.Error("failed to write pdf:", err)
+	}
+	pdf := buffer.String()
+	if !strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") || !strings.Contains(pdf, "(Bow ramp \\(") {
+		t.Error("unexpected pdf, got", pdf)
+	}
+	if offset := strings.Index(pdf, "3 0 obj"); !strings.Contains(pdf, fmt.Sprintf("%010d 00000 n", offset)) {
+		t.Error("expected offset of font in cross-reference table, but got", pdf)
+	}
+	if err := spec.Export(fmt.Sprintf("%v/spec.docx", tempDir)); err == nil {
+		t.Error("expected unknown format to fail")
+	}
+	// Long text is wrapped, and special characters are escaped
+	if lines := pdfWrap(strings.Repeat("word ", 100), pdfFontSize, 200, false); len(lines) < 10 {
+		t.Error("expected text to be wrapped, but got", lines)
+	}
+	if text := pdfString("(a) \\ “b” ✓"); text != "(\\(a\\) \\\\ \\223b\\224 ?)" {
+		t.Error("unexpected pdf string, got", text)
+	}
+}
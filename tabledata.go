@@ -53,6 +53,18 @@ var tableData = map[string][]string{
 		"item integer",
 		"itemV integer default 1",
 		"type integer",
+		"parent integer",
+		"description text",
+		"rationale text",
+		"fitCriterion text",
+		"link text",
+		"color integer",
+		"border integer",
+		"shape integer",
+		"x integer",
+		"y integer",
+		"width integer",
+		"height integer",
 		"foreign key (version) references Projects(id)",
 	},
 	"LabelItems": {
@@ -62,10 +74,16 @@ var tableData = map[string][]string{
 		"foreign key (label) references Labels(id)",
 	},
 	"Media": {
-		"parent int not null",
-		"format text default 'webp'",
+		"hash text",
+		"format text",
 		"data blob",
-		"foreign key(parent) references Solutions(id)",
+	},
+	"MediaItems": {
+		"media integer",
+		"item integer",
+		"type integer",
+		"name text",
+		"foreign key (media) references Media(id)",
 	},
 	"Labels": {
 		"tag text",
@@ -74,6 +92,11 @@ var tableData = map[string][]string{
 	"ValidationRules": {
 		"tag text",
 		"enabled integer default 1",
+		"severity text",
 		"data text",
 	},
+	"ReqIFIdentifiers": {
+		"identifier text",
+		"item integer",
+	},
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlHeadRegex      = regexp.MustCompile(`(?is)<head.*?</head>`)
+	htmlBreakRegex     = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</li>|</h[1-6]>`)
+	htmlTagRegex       = regexp.MustCompile(`(?s)<[^>]*>`)
+	multipleSpaceRegex = regexp.MustCompile(`[ \t]+`)
+	multipleLineRegex  = regexp.MustCompile(`\n{2,}`)
+)
+
+// PlainText converts html, as saved by the edit window, to plain text
+func PlainText(text string) string {
+	// Remove head, including styles, and keep paragraphs as new lines
+	text = htmlHeadRegex.ReplaceAllString(text, "")
+	text = htmlBreakRegex.ReplaceAllString(text, "\n")
+	// Remove all other tags and decode entities
+	text = html.UnescapeString(htmlTagRegex.ReplaceAllString(text, ""))
+	// Clean up whitespace
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(multipleSpaceRegex.ReplaceAllString(line, " "))
+	}
+	return strings.TrimSpace(multipleLineRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n"))
+}
+
+// TextToHTML converts plain text to html that can be shown in the edit window
+func TextToHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br />")
+}
+
+// Truncate shortens text to at most length characters, adding "..." if needed
+func Truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return strings.TrimSpace(string(runes[0:length-3])) + "..."
+}
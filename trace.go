@@ -0,0 +1,378 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Marks in the traceability matrix, from the problem in the row to the solution in the column
+const (
+	// TraceParent is when the problem is the parent of the solution
+	TraceParent = "P"
+	// TraceChild is when the problem is a child of the solution
+	TraceChild = "C"
+)
+
+// TraceItem is an item in the flat trace table
+type TraceItem struct {
+	Item Item
+	UID  int64
+	// Description as plain text
+	Description string
+	// Depth from the root, where roots are 1
+	Depth  int
+	Parent Item
+}
+
+// Traceability is what problems and solutions are linked to each other
+type Traceability struct {
+	// All items, depth first from each root
+	Items []TraceItem
+	// Problems and solutions, sorted by id
+	Problems, Solutions []TraceItem
+	// Parent of each item, as saved in the project
+	links map[Item]Item
+}
+
+// Traceability gets how all items in the project are linked
+func (data *DataContext) Traceability() (*Traceability, error) {
+	links, err := data.Links()
+	if err != nil {
+		return nil, err
+	}
+	items, err := data.LoadItems()
+	if err != nil {
+		return nil, err
+	}
+	// Children of each item, and all roots
+	trace := &Traceability{links: links}
+	byItem := make(map[Item]ItemData)
+	children := make(map[Item][]Item)
+	roots := make([]Item, 0, len(items))
+	for _, item := range items {
+		byItem[item.Item] = item
+	}
+	parents := make(map[Item]Item)
+	for _, item := range items {
+		// Links to items that don't exist are ignored
+		if parent, found := links[item.Item]; found {
+			if _, parentFound := byItem[parent]; parentFound {
+				parents[item.Item] = parent
+				children[parent] = append(children[parent], item.Item)
+				continue
+			}
+		}
+		roots = append(roots, item.Item)
+	}
+	// Depth first, where items only found in loops are added after everything else
+	visited := make(map[Item]bool)
+	var visit func(item Item, depth int)
+	visit = func(item Item, depth int) {
+		if visited[item] {
+			return
+		}
+		visited[item] = true
+		itemData := byItem[item]
+		trace.Items = append(trace.Items, TraceItem{item, itemData.UID, PlainText(itemData.Description), depth, parents[item]})
+		for _, child := range SortItems(children[item]) {
+			visit(child, depth+1)
+		}
+	}
+	for _, root := range SortItems(roots) {
+		visit(root, 1)
+	}
+	all := make([]Item, 0, len(items))
+	for _, item := range items {
+		all = append(all, item.Item)
+	}
+	for _, item := range SortItems(all) {
+		visit(item, 1)
+	}
+	traceItems := make(map[Item]TraceItem)
+	for _, traceItem := range trace.Items {
+		traceItems[traceItem.Item] = traceItem
+	}
+	for _, item := range all {
+		if GetItemType(item) == TypeRequirement {
+			trace.Problems = append(trace.Problems, traceItems[item])
+		} else {
+			trace.Solutions = append(trace.Solutions, traceItems[item])
+		}
+	}
+	return trace, nil
+}
+
+// Mark gets how a problem and a solution are linked, or an empty string if they're not
+func (trace *Traceability) Mark(problem, solution Item) string {
+	if trace.links[solution] == problem {
+		return TraceParent
+	}
+	if trace.links[problem] == solution {
+		return TraceChild
+	}
+	return ""
+}
+
+// TraceTable is a table in a traceability export, where the first row is the header
+type TraceTable struct {
+	Name string
+	Rows [][]string
+}
+
+// traceName gets the uid and start of the description of an item, for row and column headers
+func traceName(item TraceItem) string {
+	return fmt.Sprintf("%v %v", FormatUID(item.UID), Truncate(strings.ReplaceAll(item.Description, "\n", " "), 40))
+}
+
+// Tables gets the matrix, with problems as rows and solutions as columns, and the flat trace table
+func (trace *Traceability) Tables() []TraceTable {
+	matrix := TraceTable{Name: "Matrix"}
+	header := []string{"Problem \\ Solution"}
+	for _, solution := range trace.Solutions {
+		header = append(header, traceName(solution))
+	}
+	matrix.Rows = append(matrix.Rows, header)
+	for _, problem := range trace.Problems {
+		row := []string{traceName(problem)}
+		for _, solution := range trace.Solutions {
+			row = append(row, trace.Mark(problem.Item, solution.Item))
+		}
+		matrix.Rows = append(matrix.Rows, row)
+	}
+	table := TraceTable{Name: "Trace"}
+	table.Rows = append(table.Rows, []string{"UID", "Type", "Description", "Depth", "Parent UID", "Parent Type"})
+	uids := make(map[Item]int64)
+	for _, item := range trace.Items {
+		uids[item.Item] = item.UID
+	}
+	for _, item := range trace.Items {
+		parentUID, parentType := "", ""
+		if item.Parent != nil {
+			parentUID, parentType = FormatUID(uids[item.Parent]), GetItemName(item.Parent)
+		}
+		table.Rows = append(table.Rows, []string{
+			FormatUID(item.UID), GetItemName(item.Item), item.Description,
+			strconv.Itoa(item.Depth), parentUID, parentType,
+		})
+	}
+	return []TraceTable{matrix, table}
+}
+
+// TraceFormats are all formats the traceability can be exported as, by file extension
+var TraceFormats = []string{".csv", ".html", ".xlsx"}
+
+// IsTraceFormat checks if format is one of TraceFormats
+func IsTraceFormat(format string) bool {
+	for _, traceFormat := range TraceFormats {
+		if traceFormat == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes the matrix and trace table in the specified format, one of TraceFormats
+func (trace *Traceability) Write(writer io.Writer, format, title string) error {
+	switch format {
+	case ".csv":
+		return trace.WriteCSV(writer)
+	case ".html":
+		return trace.WriteHTML(writer, title)
+	case ".xlsx":
+		return trace.WriteXLSX(writer)
+	}
+	return traceFormatError(format)
+}
+
+// traceFormatError is returned for formats not in TraceFormats
+func traceFormatError(format string) error {
+	return fmt.Errorf("unknown traceability format \"%v\" (expected %v)", format, strings.Join(TraceFormats, ", "))
+}
+
+// Export writes the traceability to a file, in the format of its extension
+func (trace *Traceability) Export(path, title string) error {
+	format := strings.ToLower(filepath.Ext(path))
+	if !IsTraceFormat(format) {
+		return traceFormatError(format)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := trace.Write(file, format, title); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteCSV writes the matrix, an empty line, and the trace table
+func (trace *Traceability) WriteCSV(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	for i, table := range trace.Tables() {
+		if i > 0 {
+			if err := csvWriter.Write([]string{}); err != nil {
+				return err
+			}
+		}
+		if err := csvWriter.WriteAll(table.Rows); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// traceStyle is included in html exports, to keep them self-contained
+const traceStyle = `body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #bdbdbd; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eeeeee; }
+td.mark { text-align: center; font-weight: bold; }
+td.P { background: #e1f5fe; color: #2196f3; }
+td.C { background: #f3e5f5; color: #9c27b0; }`
+
+// WriteHTML writes the matrix and trace table as a single html file
+func (trace *Traceability) WriteHTML(writer io.Writer, title string) error {
+	var builder strings.Builder
+	builder.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&builder, "<title>%v</title>\n<style>\n%v\n</style>\n</head>\n<body>\n",
+		html.EscapeString(title), traceStyle)
+	fmt.Fprintf(&builder, "<h1>%v</h1>\n", html.EscapeString(title))
+	for i, table := range trace.Tables() {
+		fmt.Fprintf(&builder, "<h2>%v</h2>\n", table.Name)
+		if i == 0 {
+			fmt.Fprintf(&builder, "<p>%v: the problem is the parent of the solution, "+
+				"%v: the problem is a child of the solution</p>\n", TraceParent, TraceChild)
+		}
+		builder.WriteString("<table>\n")
+		for j, row := range table.Rows {
+			builder.WriteString("<tr>")
+			for k, cell := range row {
+				switch {
+				case j == 0 || (i == 0 && k == 0):
+					fmt.Fprintf(&builder, "<th>%v</th>", html.EscapeString(cell))
+				case i == 0:
+					fmt.Fprintf(&builder, "<td class=\"mark %v\">%v</td>", cell, html.EscapeString(cell))
+				default:
+					fmt.Fprintf(&builder, "<td>%v</td>", html.EscapeString(cell))
+				}
+			}
+			builder.WriteString("</tr>\n")
+		}
+		builder.WriteString("</table>\n")
+	}
+	builder.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// xlsxColumn gets the name of a column in a spreadsheet, like A, B or AA
+func xlsxColumn(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxEscape escapes text for use in xml
+func xlsxEscape(text string) string {
+	var builder strings.Builder
+	for _, r := range text {
+		switch r {
+		case '<':
+			builder.WriteString("&lt;")
+		case '>':
+			builder.WriteString("&gt;")
+		case '&':
+			builder.WriteString("&amp;")
+		case '"':
+			builder.WriteString("&quot;")
+		default:
+			// Control characters, except tabs and new lines, are not allowed
+			if r >= 0x20 || r == '\t' || r == '\n' {
+				builder.WriteRune(r)
+			}
+		}
+	}
+	return builder.String()
+}
+
+// WriteXLSX writes the matrix and trace table as sheets in a spreadsheet,
+// with the minimum files needed for an Office Open XML workbook
+func (trace *Traceability) WriteXLSX(writer io.Writer) error {
+	tables := trace.Tables()
+	const header = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+	sheetFiles := make([][2]string, 0, len(tables))
+	var sheets, sheetTypes, sheetRels strings.Builder
+	for i, table := range tables {
+		fmt.Fprintf(&sheets, `<sheet name="%v" sheetId="%v" r:id="rId%v"/>`, xlsxEscape(table.Name), i+1, i+1)
+		fmt.Fprintf(&sheetTypes, `<Override PartName="/xl/worksheets/sheet%v.xml" `+
+			`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&sheetRels, `<Relationship Id="rId%v" `+
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" `+
+			`Target="worksheets/sheet%v.xml"/>`, i+1, i+1)
+		// Text is written inline, so no shared strings are needed
+		var sheet strings.Builder
+		sheet.WriteString(header)
+		sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+		sheet.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane xSplit="1" ySplit="1" ` +
+			`topLeftCell="B2" activePane="bottomRight" state="frozen"/></sheetView></sheetViews><sheetData>`)
+		for j, row := range table.Rows {
+			fmt.Fprintf(&sheet, `<row r="%v">`, j+1)
+			for k, cell := range row {
+				ref := fmt.Sprintf("%v%v", xlsxColumn(k), j+1)
+				if number, err := strconv.Atoi(cell); err == nil && j > 0 && table.Rows[0][k] == "Depth" {
+					fmt.Fprintf(&sheet, `<c r="%v"><v>%v</v></c>`, ref, number)
+					continue
+				}
+				fmt.Fprintf(&sheet, `<c r="%v" t="inlineStr"><is><t xml:space="preserve">%v</t></is></c>`,
+					ref, xlsxEscape(cell))
+			}
+			sheet.WriteString("</row>")
+		}
+		sheet.WriteString("</sheetData></worksheet>")
+		sheetFiles = append(sheetFiles, [2]string{fmt.Sprintf("xl/worksheets/sheet%v.xml", i+1), sheet.String()})
+	}
+	// Content types first, as some readers expect
+	files := append([][2]string{
+		{"[Content_Types].xml", header +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ` +
+			`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			sheetTypes.String() + `</Types>`},
+		{"_rels/.rels", header +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" ` +
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" ` +
+			`Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", header +
+			`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", header +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			sheetRels.String() + `</Relationships>`},
+	}, sheetFiles...)
+	archive := zip.NewWriter(writer)
+	for _, file := range files {
+		fileWriter, err := archive.Create(file[0])
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fileWriter, file[1]); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
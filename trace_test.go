@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTraceability(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	db := project.Data()
+	// Root problem, solved by a solution, which has a new problem
+	root, _ := db.AddRequirement("<p>Root &amp; problem</p>", "", "", db.ItemUID())
+	solution, _ := db.AddSolution("Solution", db.ItemUID())
+	child, _ := db.AddRequirement("Child problem", "", "", db.ItemUID())
+	unlinked, _ := db.AddSolution("Unlinked", db.ItemUID())
+	_ = db.AddItemChild(NewRequirement(root), NewSolution(solution))
+	_ = db.AddItemChild(NewSolution(solution), NewRequirement(child))
+	trace, err := db.Traceability()
+	if err != nil {
+		t.Error("failed to get traceability:", err)
+		return
+	}
+	if len(trace.Problems) != 2 || len(trace.Solutions) != 2 {
+		t.Error("expected 2 problems and 2 solutions, but got", trace.Problems, trace.Solutions)
+		return
+	}
+	if mark := trace.Mark(NewRequirement(root), NewSolution(solution)); mark != TraceParent {
+		t.Error("expected root to be parent of solution, but got", mark)
+	}
+	if mark := trace.Mark(NewRequirement(child), NewSolution(solution)); mark != TraceChild {
+		t.Error("expected problem to be child of solution, but got", mark)
+	}
+	if mark := trace.Mark(NewRequirement(root), NewSolution(unlinked)); mark != "" {
+		t.Error("expected no link, but got", mark)
+	}
+	// Trace is depth first, from each root
+	depths := make([]string, len(trace.Items))
+	for i, item := range trace.Items {
+		depths[i] = fmt.Sprintf("%v:%v", item.Description, item.Depth)
+	}
+	if result := strings.Join(depths, ", "); result != "Root & problem:1, Solution:2, Child problem:3, Unlinked:1" {
+		t.Error("unexpected trace, got", result)
+	}
+	// Csv has the matrix, and then the trace table after an empty line
+	var buffer bytes.Buffer
+	if err := trace.WriteCSV(&buffer); err != nil {
+		t.Error("failed to write csv:", err)
+	}
+	reader := csv.NewReader(&buffer)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil || len(rows) != 3+5 || rows[1][1] != TraceParent || rows[3][0] != "UID" {
+		t.Error("unexpected csv, got", rows, err)
+	}
+	// Html is escaped
+	buffer.Reset()
+	if err := trace.WriteHTML(&buffer, "<Project>"); err != nil ||
+		!strings.Contains(buffer.String(), "Root &amp; problem") || strings.Contains(buffer.String(), "<Project>") {
+		t.Error("unexpected html, got", buffer.String(), err)
+	}
+	// Xlsx is a zip archive with a sheet for each table
+	xlsxPath := fmt.Sprintf("%v/trace.xlsx", tempDir)
+	if err := trace.Export(xlsxPath, "Project"); err != nil {
+		t.Error("failed to export xlsx:", err)
+		return
+	}
+	archive, err := zip.OpenReader(xlsxPath)
+	if err != nil {
+		t.Error("failed to open xlsx:", err)
+		return
+	}
+	defer archive.Close()
+	files := make([]string, 0)
+	for _, file := range archive.File {
+		files = append(files, file.Name)
+	}
+	if len(files) != 6 || files[0] != "[Content_Types].xml" || files[5] != "xl/worksheets/sheet2.xml" {
+		t.Error("unexpected files in xlsx, got", files)
+	}
+	if err := trace.Export(fmt.Sprintf("%v/trace.pdf", tempDir), "Project"); err == nil {
+		t.Error("expected unknown format to fail")
+	}
+	if xlsxColumn(0) != "A" || xlsxColumn(25) != "Z" || xlsxColumn(26) != "AA" || xlsxColumn(701) != "ZZ" {
+		t.Error("unexpected column names")
+	}
+}
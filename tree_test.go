@@ -21,15 +21,19 @@ func TestTree(t *testing.T) {
 		return
 	}
 	// Create a temporary project there
-	project := NewProject(projectPath)
-	if project == nil {
-		t.Error("failed to create new project")
+	project, err := NewProject(projectPath)
+	if err != nil {
+		t.Error("failed to create new project:", err)
 		return
 	}
 	// Create database connection
 	db := project.Data()
 	// Check so that we have no items
-	if count := len(Roots()); count != 0 {
+	graph, err := LoadGraph(db)
+	if err != nil {
+		t.Error("failed to load graph:", err)
+	}
+	if count := len(graph.Roots()); count != 0 {
 		t.Error("unexpected item count, expected 0, but got", count)
 	}
 	// Add a new requirement
@@ -71,8 +75,90 @@ func TestTree(t *testing.T) {
 	if sol1.Parent() != req1 {
 		t.Error("unexpected solution 1 parent, expected requirement 1, but got", sol1.Parent().ToString())
 	}
-	// Close database
-	if err = db.Close(); err != nil {
+	// Make sure the graph has the same tree
+	if graph, err = LoadGraph(db); err != nil {
+		t.Error("failed to load graph:", err)
+	}
+	if roots := graph.Roots(); len(roots) != 1 || roots[0] != req1 {
+		t.Error("unexpected roots, expected only requirement 1, but got", len(roots))
+	}
+	if children := graph.Children(req1); len(children) != 1 || children[0] != sol1 {
+		t.Error("unexpected requirement 1 children in graph, expected only solution 1, but got", len(children))
+	}
+	// Close project
+	if err = project.Close(); err != nil {
 		t.Error("failed to close database connection:", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestLoadItems(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create new project:", err)
+		return
+	}
+	defer project.Close()
+	db := project.Data()
+	// Add a requirement with a solution in a single transaction
+	var req Requirement
+	var sol Solution
+	err = db.Transaction(func() error {
+		reqID, err := db.AddRequirement("req", "rationale", "fit", db.ItemUID())
+		if err != nil {
+			return err
+		}
+		solID, err := db.AddSolution("sol", db.ItemUID())
+		if err != nil {
+			return err
+		}
+		req, sol = NewRequirement(reqID), NewSolution(solID)
+		req.SetPos(32, 64)
+		return db.AddItemChild(req, sol)
+	})
+	if err != nil {
+		t.Error("failed to add items:", err)
+	}
+	// A failed transaction should not save anything, including transactions started from it
+	if err = db.Transaction(func() error {
+		sol.SetDescription("changed")
+		if err := db.Transaction(func() error {
+			req.SetDescription("changed")
+			return nil
+		}); err != nil {
+			return err
+		}
+		return fmt.Errorf("test failure")
+	}); err == nil {
+		t.Error("expected failed transaction to return an error")
+	}
+	// Load everything back
+	items, err := db.LoadItems()
+	if err != nil {
+		t.Error("failed to load items:", err)
+	}
+	if len(items) != 2 {
+		t.Error("unexpected item count, expected 2, but got", len(items))
+		return
+	}
+	for _, item := range items {
+		switch item.Item {
+		case req:
+			if item.Description != "req" || item.Rationale != "rationale" || item.FitCriterion != "fit" ||
+				item.X != 32 || item.Y != 64 || item.Width != 128 || item.Parent != nil {
+				t.Errorf("unexpected requirement data: %+v", item)
+			}
+		case sol:
+			if item.Description != "sol" || item.Parent != req {
+				t.Errorf("unexpected solution data: %+v", item)
+			}
+		default:
+			t.Error("unexpected item:", item.Item.ToString())
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// TreeResolution is what to do with an imported item that has the same uid as an existing item
+type TreeResolution int
+
+const (
+	// TreeSkip keeps the existing item, where imported children are added to it instead
+	TreeSkip TreeResolution = iota
+	// TreeOverwrite replaces the existing item with the imported one
+	TreeOverwrite
+	// TreeRekey adds the imported item as a new item with a new uid
+	TreeRekey
+)
+
+// TreeResolutions are the names of all resolutions, as used in the command line
+var TreeResolutions = map[string]TreeResolution{
+	"skip":      TreeSkip,
+	"overwrite": TreeOverwrite,
+	"rekey":     TreeRekey,
+}
+
+func (resolution TreeResolution) String() string {
+	switch resolution {
+	case TreeOverwrite:
+		return "overwrite"
+	case TreeRekey:
+		return "rekey"
+	}
+	return "skip"
+}
+
+// TreePlacement is where the roots of an imported tree are placed,
+// either as children of an existing item, or at a position on the canvas
+type TreePlacement struct {
+	HasParent bool
+	ParentUID int64
+	X, Y      int
+}
+
+// TreeImport is a preview of importing items from another project into the current one.
+// Nothing is changed until the command is done.
+type TreeImport struct {
+	// All imported items, moved to where they are placed, with roots linked to the chosen parent
+	Items []JSONImportItem
+	// Uids of imported items that already exist, skipped until resolved otherwise
+	Conflicts   []int64
+	resolutions map[int64]TreeResolution
+	// Roots of the imported items, which stay linked to the chosen parent even if it's also imported
+	roots map[int64]bool
+}
+
+// ReadProjectTree reads all items in a json project, or in an .orq project without changing it
+func ReadProjectTree(path string) ([]JSONImportItem, error) {
+	switch filepath.Ext(path) {
+	case ".json":
+		jsonImport, err := ReadJSONFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return jsonImport.Items, nil
+	case ".orq":
+		// Projects from older versions are upgraded in a copy, leaving the file as it is
+		db, err := OpenDataContextCopy(path)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		return db.TreeItems()
+	}
+	return nil, fmt.Errorf("unknown project format \"%v\", expected .json or .orq", filepath.Ext(path))
+}
+
+// TreeItems gets all items, with their labels and attached files, as they would be imported from json
+func (data *DataContext) TreeItems() ([]JSONImportItem, error) {
+	snapshots, err := data.CurrentItems()
+	if err != nil {
+		return nil, err
+	}
+	labels, err := data.AllItemLabels()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]JSONImportItem, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		media, err := data.MediaJSON(snapshot.UID)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, JSONImportItem{
+			Path:     FormatUID(snapshot.UID),
+			Snapshot: snapshot,
+			Labels:   LabelNames(labels[snapshot.UID]),
+			Media:    media,
+		})
+	}
+	return items, nil
+}
+
+// PlanTreeImport creates a preview of importing items into the project,
+// keeping the layout of the imported items, but moving them to where they are placed
+func (data *DataContext) PlanTreeImport(items []JSONImportItem, placement TreePlacement) (*TreeImport, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("nothing to import")
+	}
+	// Imported items are placed below the parent, with some space between them
+	x, y := placement.X, placement.Y
+	if placement.HasParent {
+		parent, err := data.ItemByUID(placement.ParentUID)
+		if err != nil {
+			return nil, fmt.Errorf("no item with uid %v", FormatUID(placement.ParentUID))
+		}
+		parentData, err := data.LoadItem(parent)
+		if err != nil {
+			return nil, err
+		}
+		x, y = parentData.X, parentData.Y+parentData.Height+layoutRowHeight/2
+	}
+	left, top := items[0].Snapshot.X, items[0].Snapshot.Y
+	for _, item := range items {
+		if item.Snapshot.X < left {
+			left = item.Snapshot.X
+		}
+		if item.Snapshot.Y < top {
+			top = item.Snapshot.Y
+		}
+	}
+	treeImport := &TreeImport{
+		Items:       make([]JSONImportItem, len(items)),
+		Conflicts:   make([]int64, 0),
+		resolutions: make(map[int64]TreeResolution),
+		roots:       make(map[int64]bool),
+	}
+	for i, item := range items {
+		snapshot := &item.Snapshot
+		snapshot.X, snapshot.Y = snapshot.X-left+x, snapshot.Y-top+y
+		snapshot.ChildUIDs = nil
+		if !snapshot.HasParent {
+			treeImport.roots[snapshot.UID] = true
+			snapshot.HasParent, snapshot.ParentUID = placement.HasParent, placement.ParentUID
+		}
+		if data.UIDExists(snapshot.UID) {
+			treeImport.Conflicts = append(treeImport.Conflicts, snapshot.UID)
+		}
+		treeImport.Items[i] = item
+	}
+	return treeImport, nil
+}
+
+// Resolve sets what to do with an imported item that already exists
+func (treeImport *TreeImport) Resolve(uid int64, resolution TreeResolution) {
+	treeImport.resolutions[uid] = resolution
+}
+
+// ResolveAll sets what to do with all imported items that already exist
+func (treeImport *TreeImport) ResolveAll(resolution TreeResolution) {
+	for _, uid := range treeImport.Conflicts {
+		treeImport.resolutions[uid] = resolution
+	}
+}
+
+// Resolution gets what will be done with an imported item that already exists
+func (treeImport *TreeImport) Resolution(uid int64) TreeResolution {
+	return treeImport.resolutions[uid]
+}
+
+// Count gets the number of items that will be added, including rekeyed items, or overwritten
+func (treeImport *TreeImport) Count() (added, overwritten int) {
+	existing := make(map[int64]bool)
+	for _, uid := range treeImport.Conflicts {
+		existing[uid] = true
+	}
+	for _, item := range treeImport.Items {
+		if !existing[item.Snapshot.UID] || treeImport.resolutions[item.Snapshot.UID] == TreeRekey {
+			added++
+		} else if treeImport.resolutions[item.Snapshot.UID] == TreeOverwrite {
+			overwritten++
+		}
+	}
+	return added, overwritten
+}
+
+// Command gets the command adding, or overwriting, all items with their labels and attached files,
+// where labels that don't exist yet are created by the command
+func (treeImport *TreeImport) Command(db *DataContext) (Command, error) {
+	existing := make(map[int64]bool)
+	for _, uid := range treeImport.Conflicts {
+		existing[uid] = true
+	}
+	// Rekeyed items get uids that are neither in the project nor imported
+	uids := make(map[int64]int64)
+	used := make(map[int64]bool)
+	for _, item := range treeImport.Items {
+		used[item.Snapshot.UID] = true
+	}
+	for _, item := range treeImport.Items {
+		uid := item.Snapshot.UID
+		if existing[uid] && treeImport.resolutions[uid] == TreeRekey {
+			uids[uid] = db.ItemUID()
+			for used[uids[uid]] {
+				uids[uid] = db.ItemUID()
+			}
+			used[uids[uid]] = true
+		} else {
+			uids[uid] = uid
+		}
+	}
+	// Parents after importing, including added items, to check that overwriting doesn't link items in a loop
+	current, err := db.CurrentItems()
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[int64]int64)
+	before := make(map[int64]ItemSnapshot)
+	for _, item := range current {
+		before[item.UID] = item
+		if item.HasParent {
+			parents[item.UID] = item.ParentUID
+		}
+	}
+	edits := make([]Command, 0)
+	unlinks := make([]Command, 0)
+	adds := make([]Command, 0)
+	links := make([]Command, 0)
+	details := make([]Command, 0)
+	overwritten := make([]int64, 0)
+	// Labels that don't exist yet are created as part of the command, once for all items
+	created := make([]string, 0)
+	isCreated := make(map[string]bool)
+	for _, item := range treeImport.Items {
+		snapshot := item.Snapshot
+		resolution := treeImport.resolutions[snapshot.UID]
+		if existing[snapshot.UID] && resolution == TreeSkip {
+			continue
+		}
+		snapshot.UID = uids[snapshot.UID]
+		if parent, found := uids[snapshot.ParentUID]; snapshot.HasParent && found && !treeImport.roots[item.Snapshot.UID] {
+			snapshot.ParentUID = parent
+		}
+		if existing[item.Snapshot.UID] && resolution == TreeOverwrite {
+			// Links are changed separately, so changing the type keeps the links of the existing item
+			old := before[snapshot.UID]
+			edited := snapshot
+			edited.HasParent, edited.ParentUID, edited.ChildUIDs = old.HasParent, old.ParentUID, old.ChildUIDs
+			edits = append(edits, NewEditItemCommand(old, edited))
+			if old.HasParent && (!snapshot.HasParent || old.ParentUID != snapshot.ParentUID) {
+				unlinks = append(unlinks, NewRemoveLinkCommand(old.ParentUID, snapshot.UID))
+			}
+			if snapshot.HasParent && (!old.HasParent || old.ParentUID != snapshot.ParentUID) {
+				links = append(links, NewAddLinkCommand(snapshot.ParentUID, snapshot.UID))
+			}
+			delete(parents, snapshot.UID)
+			if snapshot.HasParent {
+				parents[snapshot.UID] = snapshot.ParentUID
+			}
+			overwritten = append(overwritten, snapshot.UID)
+		} else {
+			// Items are added without links, as items in loops may be added before their parent
+			added := snapshot
+			added.HasParent, added.ParentUID = false, 0
+			adds = append(adds, NewAddItemCommand(added))
+			if snapshot.HasParent {
+				links = append(links, NewAddLinkCommand(snapshot.ParentUID, snapshot.UID))
+				parents[snapshot.UID] = snapshot.ParentUID
+			}
+		}
+		// Labels replace the labels of overwritten items, while files are only added
+		labelIDs := make([]int64, 0, len(item.Labels))
+		names := make([]string, 0)
+		for _, name := range item.Labels {
+			if label, err := db.LabelByName(name); err == nil {
+				labelIDs = append(labelIDs, label.ID)
+				continue
+			}
+			if !isCreated[name] {
+				isCreated[name] = true
+				created = append(created, name)
+			}
+			names = append(names, name)
+		}
+		var oldLabels []int64
+		attached := make(map[string]bool)
+		if existing[snapshot.UID] {
+			labels, err := db.ItemLabels(snapshot.UID)
+			if err != nil {
+				return nil, err
+			}
+			oldLabels = LabelIDs(labels)
+			media, err := db.MediaJSON(snapshot.UID)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range media {
+				attached[MediaHash(file.Data)] = true
+			}
+		}
+		if len(names) > 0 || !SameLabels(oldLabels, labelIDs) {
+			details = append(details,
+				NewLabelItemNamesCommand(snapshot.UID, snapshot.Type, oldLabels, labelIDs, names))
+		}
+		for _, file := range item.Media {
+			if !attached[MediaHash(file.Data)] {
+				attached[MediaHash(file.Data)] = true
+				details = append(details, NewAttachMediaCommand(snapshot.UID, snapshot.Type, file.Name, file.Data))
+			}
+		}
+	}
+	for _, uid := range overwritten {
+		// Loops the item isn't part of may already exist above it
+		visited := make(map[int64]bool)
+		for parent, found := parents[uid]; found && !visited[parent]; parent, found = parents[parent] {
+			if parent == uid {
+				return nil, fmt.Errorf("overwriting %v would link items in a loop", FormatUID(uid))
+			}
+			visited[parent] = true
+		}
+	}
+	commands := append(append(append(append(edits, unlinks...), adds...), links...), details...)
+	if len(created) > 0 {
+		commands = append([]Command{NewCreateLabelsCommand(created)}, commands...)
+	}
+	if len(commands) == 0 {
+		return nil, fmt.Errorf("nothing to import, all items are skipped")
+	}
+	added, _ := treeImport.Count()
+	return NewCommandGroup(fmt.Sprintf("Import %v Items", added+len(overwritten)), commands...), nil
+}
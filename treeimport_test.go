@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTreeImport(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("failed to get temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	project, err := NewProject(fmt.Sprintf("%v/openrq_test.orq", tempDir))
+	if err != nil {
+		t.Error("failed to create project:", err)
+		return
+	}
+	defer project.Close()
+	db := project.Data()
+	history := NewHistory()
+	// Ferry with a ramp, where the supplier sends the ramp with a new parent and a new child
+	ferry, _ := db.AddRequirement("Ferry must be safe", "", "", 0x10)
+	ramp, _ := db.AddSolution("Bow ramp", 0x20)
+	_ = db.AddItemChild(NewRequirement(ferry), NewSolution(ramp))
+	_ = db.SetItemValues(ferry, "Requirements", map[string]interface{}{"x": 0, "y": 0, "height": 64})
+	_ = db.SetItemLabelNames(0x20, TypeSolution, []string{"Ours"})
+	jsonImport, err := ReadJSONProject([]byte(`{"ProjectName": "Supplier", "Tree": [
+		{"ID": "30", "Description": "Cars must board", "Rationale": "", "FitCriterion": "", "Pos": [100, 50],
+			"Labels": ["Supplier"], "Children": [
+			{"ID": "20", "Description": "Hydraulic bow ramp", "Pos": [100, 200], "Labels": ["Supplier"], "Children": [
+				{"ID": "40", "Description": "Ramp must not leak", "Rationale": "", "FitCriterion": "", "Pos": [150, 350],
+					"Media": [{"Name": "seal.txt", "Data": "c2VhbA=="}]}
+			]}
+		]}
+	]}`))
+	if err != nil {
+		t.Error("failed to read items:", err)
+		return
+	}
+	parents := func() map[int64]string {
+		items, _ := db.CurrentItems()
+		result := make(map[int64]string)
+		for _, item := range items {
+			result[item.UID] = fmt.Sprintf("%v %v", PlainText(item.Description), formatParent(item))
+		}
+		return result
+	}
+	importTree := func(placement TreePlacement, resolution TreeResolution) (*TreeImport, error) {
+		treeImport, err := db.PlanTreeImport(jsonImport.Items, placement)
+		if err != nil {
+			return nil, err
+		}
+		treeImport.ResolveAll(resolution)
+		command, err := treeImport.Command(db)
+		if err == nil {
+			err = history.Do(db, command)
+		}
+		return treeImport, err
+	}
+	// Skipping keeps the existing item, with the new child added to it
+	treeImport, err := importTree(TreePlacement{HasParent: true, ParentUID: 0x10}, TreeSkip)
+	if err != nil {
+		t.Error("failed to import items:", err)
+		return
+	}
+	if len(treeImport.Conflicts) != 1 || treeImport.Conflicts[0] != 0x20 {
+		t.Error("expected ramp to conflict, but got", treeImport.Conflicts)
+	}
+	if added, overwritten := treeImport.Count(); added != 2 || overwritten != 0 {
+		t.Errorf("expected 2 added items, but got %v added and %v overwritten", added, overwritten)
+	}
+	result := parents()
+	if result[0x20] != "Bow ramp 10" || result[0x30] != "Cars must board 10" || result[0x40] != "Ramp must not leak 20" {
+		t.Error("unexpected items after skipping, got", result)
+	}
+	// Imported items keep their layout, below the parent
+	if item, _ := db.ItemByUID(0x40); item != nil {
+		x, y := item.Pos()
+		if x != 50 || y != 64+layoutRowHeight/2+300 {
+			t.Errorf("expected imported item at 50, %v, but got %v, %v", 64+layoutRowHeight/2+300, x, y)
+		}
+	}
+	if media, _ := db.MediaJSON(0x40); len(media) != 1 || string(media[0].Data) != "seal" {
+		t.Error("expected attached file, but got", media)
+	}
+	_ = history.Undo(db)
+	if result := parents(); len(result) != 2 {
+		t.Error("expected import to be undone, but got", result)
+	}
+	if _, err := db.LabelByName("Supplier"); err == nil {
+		t.Error("expected created label to be removed when undone")
+	}
+	// Overwriting replaces the item, its parent and its labels
+	if _, err := importTree(TreePlacement{X: 0, Y: 500}, TreeOverwrite); err != nil {
+		t.Error("failed to import items:", err)
+		return
+	}
+	result = parents()
+	if result[0x20] != "Hydraulic bow ramp 30" || result[0x30] != "Cars must board " {
+		t.Error("unexpected items after overwriting, got", result)
+	}
+	if labels, _ := db.ItemLabels(0x20); len(labels) != 1 || labels[0].Name != "Supplier" {
+		t.Error("expected labels to be replaced, but got", labels)
+	}
+	_ = history.Undo(db)
+	if result := parents(); result[0x20] != "Bow ramp 10" {
+		t.Error("expected overwrite to be undone, but got", result)
+	}
+	if labels, _ := db.ItemLabels(0x20); len(labels) != 1 || labels[0].Name != "Ours" {
+		t.Error("expected labels to be restored, but got", labels)
+	}
+	// Rekeying adds a copy, with children linked to the copy
+	if _, err := importTree(TreePlacement{}, TreeRekey); err != nil {
+		t.Error("failed to import items:", err)
+		return
+	}
+	result = parents()
+	leak, _ := db.ItemByUID(0x40)
+	leakSnapshot, _ := db.Snapshot(leak)
+	if len(result) != 5 || result[0x20] != "Bow ramp 10" || result[leakSnapshot.ParentUID] != "Hydraulic bow ramp 30" {
+		t.Error("unexpected items after rekeying, got", result)
+	}
+	_ = history.Undo(db)
+	// Roots can only be placed under items that exist
+	if _, err := db.PlanTreeImport(jsonImport.Items, TreePlacement{HasParent: true, ParentUID: 0x99}); err == nil {
+		t.Error("expected unknown parent to fail")
+	}
+	// Overwriting can't link items in a loop, here the ferry under its own child
+	loop, _ := ReadJSONProject([]byte(`{"ProjectName": "Loop", "Tree": [
+		{"ID": "10", "Description": "Ferry", "Rationale": "", "FitCriterion": "", "Pos": [0, 0]}
+	]}`))
+	treeImport, _ = db.PlanTreeImport(loop.Items, TreePlacement{HasParent: true, ParentUID: 0x20})
+	treeImport.ResolveAll(TreeOverwrite)
+	if _, err := treeImport.Command(db); err == nil || !strings.Contains(err.Error(), "loop") {
+		t.Error("expected overwriting to fail when linking items in a loop, but got", err)
+	}
+	// Or through an added item, here the ferry under a new item placed under the ferry
+	loop, _ = ReadJSONProject([]byte(`{"ProjectName": "Loop", "Tree": [
+		{"ID": "30", "Description": "Cars must board", "Rationale": "", "FitCriterion": "", "Pos": [0, 0], "Children": [
+			{"ID": "10", "Description": "Ferry", "Rationale": "", "FitCriterion": "", "Pos": [0, 100]}
+		]}
+	]}`))
+	treeImport, _ = db.PlanTreeImport(loop.Items, TreePlacement{HasParent: true, ParentUID: 0x10})
+	treeImport.ResolveAll(TreeOverwrite)
+	if _, err := treeImport.Command(db); err == nil || !strings.Contains(err.Error(), "loop") {
+		t.Error("expected overwriting to fail when linking items in a loop through an added item, but got", err)
+	}
+	// Projects can also be imported from .orq, without changing them
+	items, err := ReadProjectTree(project.path)
+	labels := make(map[int64][]string)
+	for _, item := range items {
+		labels[item.Snapshot.UID] = item.Labels
+	}
+	if err != nil || len(items) != 2 || len(labels[0x20]) != 1 || labels[0x20][0] != "Ours" {
+		t.Error("expected items from project, but got", items, err)
+	}
+	// Projects from older versions are read without upgrading them
+	oldMigrations := migrations
+	defer func() {
+		migrations = oldMigrations
+	}()
+	migrations = append(append([]Migration{}, migrations...), Migration{
+		Info: "add test column",
+		Run: func(tx *sql.Tx) error {
+			_, err := tx.Exec("alter table Info add column test text")
+			return err
+		},
+	})
+	before, _ := ioutil.ReadFile(project.path)
+	if items, err := ReadProjectTree(project.path); err != nil || len(items) != 2 {
+		t.Error("expected items from older project, but got", items, err)
+	}
+	if after, _ := ioutil.ReadFile(project.path); !bytes.Equal(before, after) {
+		t.Error("expected older project to be unchanged")
+	}
+	if _, err := os.Stat(MigrationBackupPath(project.path, len(oldMigrations)+1)); !os.IsNotExist(err) {
+		t.Error("expected no backup of older project")
+	}
+	if _, err := ReadProjectTree(fmt.Sprintf("%v/missing.orq", tempDir)); err == nil {
+		t.Error("expected missing project to fail")
+	}
+	if _, err := os.Stat(fmt.Sprintf("%v/missing.orq", tempDir)); !os.IsNotExist(err) {
+		t.Error("expected no project to be created")
+	}
+}
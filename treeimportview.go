@@ -0,0 +1,153 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/widgets"
+)
+
+// treeResolutionNames are the names of resolutions as shown in the conflict list, in the order of TreeResolution
+var treeResolutionNames = []string{"Keep Existing", "Overwrite", "Add with New UID"}
+
+// ImportTree asks for a json or .orq project, and adds all items in it to the current project,
+// under a chosen item or at a position, asking what to do with items that already exist
+func ImportTree(window *widgets.QMainWindow) {
+	if currentProject == nil || currentProject.ReadOnly() {
+		widgets.QMessageBox_Information(window, "No Project Loaded",
+			"No project is currently loaded to import into", widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	fileName := widgets.QFileDialog_GetOpenFileName(window, "Import into Project",
+		filepath.Dir(currentProject.FilePath()), "OpenRQ Project(*.orq *.json)", "", 0)
+	if len(fileName) <= 0 {
+		return
+	}
+	db := currentProject.Data()
+	importItems, err := ReadProjectTree(fileName)
+	var items []ItemData
+	var treeImport *TreeImport
+	if err == nil {
+		items, err = db.LoadItems()
+	}
+	if err == nil {
+		// Conflicts only depend on the uids, so the placement is set when importing
+		treeImport, err = db.PlanTreeImport(importItems, TreePlacement{})
+	}
+	if err != nil {
+		widgets.QMessageBox_Critical(window, "Failed to Import Project", err.Error(),
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	existing := make(map[int64]ItemData)
+	for _, item := range items {
+		existing[item.UID] = item
+	}
+	dialog := widgets.NewQDialog(window, 0)
+	dialog.SetWindowTitle(fmt.Sprintf("Import %v", filepath.Base(fileName)))
+	dialog.Resize2(720, 480)
+	layout := widgets.NewQVBoxLayout()
+	// Where to place the imported roots
+	form := widgets.NewQFormLayout(nil)
+	parent := widgets.NewQComboBox(nil)
+	parent.AddItem("(None, place at position)", core.NewQVariant1(-1))
+	for i, item := range items {
+		parent.AddItem(fmt.Sprintf("%v: %v (%v)", GetItemName(item.Item),
+			Truncate(PlainText(item.Description), 40), FormatUID(item.UID)), core.NewQVariant1(i))
+	}
+	form.AddRow3("Add under", parent)
+	position := widgets.NewQHBoxLayout()
+	x := widgets.NewQSpinBox(nil)
+	y := widgets.NewQSpinBox(nil)
+	for _, spinBox := range []*widgets.QSpinBox{x, y} {
+		spinBox.SetRange(-100000, 100000)
+		position.AddWidget(spinBox, 1, 0)
+	}
+	form.AddRow4("Position", position)
+	parent.ConnectCurrentIndexChanged(func(index int) {
+		x.SetEnabled(index == 0)
+		y.SetEnabled(index == 0)
+	})
+	layout.AddLayout(form, 0)
+	// What to do with each item that already exists
+	added, _ := treeImport.Count()
+	summary := fmt.Sprintf("%v new items will be added", added)
+	if len(treeImport.Conflicts) > 0 {
+		summary = fmt.Sprintf("%v new items will be added, and %v items already exist in the project",
+			added, len(treeImport.Conflicts))
+	}
+	layout.AddWidget(widgets.NewQLabel2(summary, nil, 0), 0, 0)
+	resolutions := make([]*widgets.QComboBox, len(treeImport.Conflicts))
+	if len(treeImport.Conflicts) > 0 {
+		imported := make(map[int64]ItemSnapshot)
+		for _, item := range treeImport.Items {
+			imported[item.Snapshot.UID] = item.Snapshot
+		}
+		tree := widgets.NewQTreeWidget(nil)
+		tree.SetRootIsDecorated(false)
+		tree.SetHeaderLabels([]string{"Item", "Existing", "Imported", "Action"})
+		for i, uid := range treeImport.Conflicts {
+			before, after := PlainText(existing[uid].Description), PlainText(imported[uid].Description)
+			row := widgets.NewQTreeWidgetItem2([]string{
+				FormatUID(uid), Truncate(before, 40), Truncate(after, 40),
+			}, 0)
+			row.SetToolTip(1, before)
+			row.SetToolTip(2, after)
+			tree.AddTopLevelItem(row)
+			resolutions[i] = widgets.NewQComboBox(nil)
+			resolutions[i].AddItems(treeResolutionNames)
+			tree.SetItemWidget(row, 3, resolutions[i])
+		}
+		layout.AddWidget(tree, 1, 0)
+		// Buttons for picking the same action for all
+		allButtons := widgets.NewQHBoxLayout()
+		for i, name := range []string{"Keep All Existing", "Overwrite All", "Add All with New UIDs"} {
+			resolution := i
+			button := widgets.NewQPushButton2(name, nil)
+			button.ConnectReleased(func() {
+				for _, combo := range resolutions {
+					combo.SetCurrentIndex(resolution)
+				}
+			})
+			allButtons.AddWidget(button, 0, 0)
+		}
+		allButtons.AddStretch(1)
+		layout.AddLayout(allButtons, 0)
+	} else {
+		layout.AddStretch(1)
+	}
+	buttons := widgets.NewQDialogButtonBox3(widgets.QDialogButtonBox__Ok|widgets.QDialogButtonBox__Cancel, nil)
+	buttons.Button(widgets.QDialogButtonBox__Ok).SetText("Import")
+	buttons.ConnectAccepted(dialog.Accept)
+	buttons.ConnectRejected(dialog.Reject)
+	layout.AddWidget(buttons, 0, 0)
+	dialog.SetLayout(layout)
+	if dialog.Exec() != int(widgets.QDialog__Accepted) {
+		return
+	}
+	placement := TreePlacement{X: x.Value(), Y: y.Value()}
+	if index := parent.CurrentData(int(core.Qt__UserRole)).ToInt(nil); index >= 0 {
+		placement.HasParent, placement.ParentUID = true, items[index].UID
+	}
+	// Importing can be undone like any other change
+	treeImport, err = db.PlanTreeImport(importItems, placement)
+	if err == nil {
+		for i, uid := range treeImport.Conflicts {
+			treeImport.Resolve(uid, TreeResolution(resolutions[i].CurrentIndex()))
+		}
+		var command Command
+		if command, err = treeImport.Command(db); err == nil {
+			err = currentHistory.Do(db, command)
+		}
+	}
+	if err != nil {
+		widgets.QMessageBox_Critical(window, "Failed to Import Project", err.Error(),
+			widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+		return
+	}
+	ReloadProject(window)
+}
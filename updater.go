@@ -7,6 +7,9 @@ import (
 	"net/http"
 )
 
+// Variables set from linker flags
+const versionTagName = "v1.0"
+
 // IsLatestVersion checks for updates and returns true if no update is available
 func IsLatestVersion() (bool, error) {
 	// Check the latest release on GitHub
@@ -1,132 +1,143 @@
 package main
 
 import (
-	"fmt"
-	"io/ioutil"
 	"testing"
 )
 
 func TestValidateLinks(t *testing.T) {
-	links = make(map[Item][]*Link)
+	graph := NewGraph()
 	// Create two items of different types
 	req1 := NewRequirement(1)
 	sol1 := NewSolution(1)
+	graph.AddItem(req1, "req1")
+	graph.AddItem(sol1, "sol1")
 	// Create a link between the items
-	link := Link{
-		parent: req1,
-		child:  sol1,
-	}
-	links[req1] = append(links[req1], &link)
-	links[sol1] = append(links[sol1], &link)
+	graph.AddLink(req1, sol1)
 	// This is valid, so link validation should be successful
-	if links := len(ValidateLinks()); links != 0 {
+	if links := len(ValidateLinks(graph)); links != 0 {
 		t.Error("unexpected validation result, expected 0 errors, but got", links)
 	}
 	// Try adding a new solution to the current solution
 	sol2 := NewSolution(2)
-	link2 := Link{
-		parent: sol1,
-		child:  sol2,
-	}
-	links[sol1] = append(links[sol1], &link2)
-	links[sol2] = append(links[sol2], &link2)
+	graph.AddItem(sol2, "sol2")
+	graph.AddLink(sol1, sol2)
 	// Validation should fail now
-	if links := len(ValidateLinks()); links != 1 {
+	if links := len(ValidateLinks(graph)); links != 1 {
 		t.Error("unexpected validation result, expected 1 error, but got", links)
 	}
 }
 
 func TestValidateLoops(t *testing.T) {
-	links = make(map[Item][]*Link)
+	graph := NewGraph()
 	// Create two items of different types
 	req1 := NewRequirement(1)
 	sol1 := NewSolution(1)
+	graph.AddItem(req1, "req1")
+	graph.AddItem(sol1, "sol1")
 	// Create a link between the items
-	link := Link{
-		parent: req1,
-		child:  sol1,
-	}
-	links[req1] = append(links[req1], &link)
-	links[sol1] = append(links[sol1], &link)
+	graph.AddLink(req1, sol1)
 	// This is valid, so link validation should be successful
-	if links := len(ValidateLoops()); links != 0 {
+	if links := len(ValidateLoops(graph)); links != 0 {
 		t.Error("unexpected validation result, expected 0 errors, but got", links)
 	}
 	// Try linking them again, but in the other direction
-	link2 := Link{
-		parent: sol1,
-		child:  req1,
-	}
-	links[sol1] = append(links[sol1], &link2)
-	links[req1] = append(links[req1], &link2)
+	graph.AddLink(sol1, req1)
 	// Validation should fail now
-	if links := len(ValidateLoops()); links != 2 {
-		t.Error("unexpected validation result, expected 1 error, but got", links)
+	if links := len(ValidateLoops(graph)); links != 2 {
+		t.Error("unexpected validation result, expected 2 errors, but got", links)
 	}
 }
 
 func TestValidateRoots(t *testing.T) {
-	// ValidateRoots depend on a view being available
-	_, window := NewMainWindow()
-	CreateLayout(window)
-	// Reset project if any was loaded
-	tmpDir, err := ioutil.TempDir("", "")
-	if err != nil {
-		t.Error(err)
-	}
-	NewProject(fmt.Sprintf("%v/openrq_test.orq", tmpDir))
-	ReloadProject(window)
+	graph := NewGraph()
 	// Create two items of different types
-	req1 := NewGraphicsItem("req1", 0, 0, 0, 0, NewRequirement(1))
-	sol1 := NewGraphicsItem("sol1", 0, 0, 0, 0, NewSolution(1))
-	// Add to scene
-	scene.AddItem(req1)
-	scene.AddItem(sol1)
+	req1 := NewRequirement(1)
+	sol1 := NewSolution(1)
+	graph.AddItem(req1, "req1")
+	graph.AddItem(sol1, "sol1")
 	// Create a link between the items
-	CreateLink(req1, sol1)
+	graph.AddLink(req1, sol1)
 	// Validation should be successful
-	if links := len(ValidateRoots()); links != 0 {
+	if links := len(ValidateRoots(graph)); links != 0 {
 		t.Error("unexpected validation result, expected 0 errors, but got", links)
 	}
 	// Add another child to parent
-	sol2 := NewGraphicsItem("sol2", 0, 0, 0, 0, NewSolution(2))
-	scene.AddItem(sol2)
-	CreateLink(req1, sol2)
+	sol2 := NewSolution(2)
+	graph.AddItem(sol2, "sol2")
+	graph.AddLink(req1, sol2)
 	// Validation should now fail
-	if links := len(ValidateRoots()); links != 1 {
+	if links := len(ValidateRoots(graph)); links != 1 {
 		t.Error("unexpected validation result, expected 1 error, but got", links)
 	}
 }
 
 func TestValidateLinkErrors(t *testing.T) {
-	// ValidateRoots depend on a view being available
-	_, window := NewMainWindow()
-	CreateLayout(window)
-	// Reset project if any was loaded
-	tmpDir, err := ioutil.TempDir("", "")
-	if err != nil {
-		t.Error(err)
-	}
-	NewProject(fmt.Sprintf("%v/openrq_test.orq", tmpDir))
-	ReloadProject(window)
+	graph := NewGraph()
 	// Create two items of different types
-	req1 := NewGraphicsItem("req1", 0, 0, 0, 0, NewRequirement(1))
-	sol1 := NewGraphicsItem("sol1", 0, 0, 0, 0, NewSolution(1))
-	// Add to scene
-	scene.AddItem(req1)
-	scene.AddItem(sol1)
+	req1 := NewRequirement(1)
+	sol1 := NewSolution(1)
+	graph.AddItem(req1, "req1")
+	graph.AddItem(sol1, "sol1")
 	// Create a link between the items
-	CreateLink(req1, sol1)
+	graph.AddLink(req1, sol1)
 	// Validation should be successful
-	if links := len(ValidateLinkErrors()); links != 0 {
+	if links := len(ValidateLinkErrors(graph)); links != 0 {
 		t.Error("unexpected validation result, expected 0 errors, but got", links)
 	}
 	// Add another parent
-	req2 := NewGraphicsItem("req2", 0, 0, 0, 0, NewRequirement(2))
-	scene.AddItem(req2)
-	CreateLink(req2, sol1)
+	req2 := NewRequirement(2)
+	graph.AddItem(req2, "req2")
+	graph.AddLink(req2, sol1)
 	// Validation should now fail
-	if links := len(ValidateLinkErrors()); links != 1 {
+	if links := len(ValidateLinkErrors(graph)); links != 1 {
 		t.Error("unexpected validation result, expected 1 error, but got", links)
 	}
-}
\ No newline at end of file
+}
+
+func TestValidateCycles(t *testing.T) {
+	graph := NewGraph()
+	// A chain of problems, and a solution linking to itself
+	for i := int64(1); i <= 5; i++ {
+		graph.AddItem(NewRequirement(i), "req")
+		if i > 1 {
+			graph.AddLink(NewRequirement(i-1), NewRequirement(i))
+		}
+	}
+	graph.AddItem(NewSolution(1), "sol1")
+	graph.AddLink(NewRequirement(5), NewSolution(1))
+	if cycles := graph.Cycles(); len(cycles) != 0 {
+		t.Error("unexpected cycles, expected none, but got", cycles)
+	}
+	// Link back from 4 to 2, which two-item loops didn't find
+	graph.AddLink(NewRequirement(4), NewRequirement(2))
+	graph.AddLink(NewSolution(1), NewSolution(1))
+	if items := ValidateLoops(graph); len(items) != 4 {
+		t.Error("unexpected validation result, expected 4 errors, but got", items)
+	}
+	cycles := graph.Cycles()
+	expected := [][]Item{
+		{NewRequirement(2), NewRequirement(3), NewRequirement(4)},
+		{NewSolution(1)},
+	}
+	if len(cycles) != len(expected) {
+		t.Error("unexpected cycles, expected", expected, "but got", cycles)
+		return
+	}
+	for i, cycle := range cycles {
+		if len(cycle) != len(expected[i]) {
+			t.Error("unexpected cycle, expected", expected[i], "but got", cycle)
+			continue
+		}
+		for j, item := range cycle {
+			if item != expected[i][j] {
+				t.Error("unexpected cycle, expected", expected[i], "but got", cycle)
+				break
+			}
+		}
+	}
+	// A second loop in the same component, through 3 and 5
+	graph.AddLink(NewRequirement(5), NewRequirement(3))
+	if cycles := graph.Cycles(); len(cycles) != 3 || len(cycles[1]) != 3 || cycles[1][0] != NewRequirement(5) {
+		t.Error("expected another loop to include item 5, but got", cycles)
+	}
+}
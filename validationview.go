@@ -0,0 +1,344 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// Rules used by the current project, and the list showing their results
+var validationRules []ValidationRule
+var validationResults *widgets.QListWidget
+
+// Findings from the last validation, in the same order as the affected items
+var validationFindings []ValidationFinding
+
+// Boxes and lines shown on top of the scene for the selected affected item
+var validationOverlay []*widgets.QGraphicsItem
+
+// ClearValidationHighlight removes the highlight of the last selected affected item
+func ClearValidationHighlight() {
+	for _, overlay := range validationOverlay {
+		scene.RemoveItem(overlay)
+	}
+	validationOverlay = nil
+}
+
+// findSceneItem finds the group showing an item in the scene
+func findSceneItem(item Item) *widgets.QGraphicsItem {
+	for _, sceneItem := range scene.Items(core.Qt__AscendingOrder) {
+		if sceneItem.Type() == 10 && sceneItem.Data(0).ToLongLong(nil) == item.ID() &&
+			ItemType(sceneItem.Data(1).ToInt(nil)) == GetItemType(item) {
+			return sceneItem
+		}
+	}
+	return nil
+}
+
+// HighlightItems shows items of a finding in the scene, and the links between them if they form a loop
+func HighlightItems(items []Item, loop bool) {
+	ClearValidationHighlight()
+	pen := gui.NewQPen3(gui.NewQColor3(244, 67, 54, 255))
+	pen.SetWidth(3)
+	centers := make([]*core.QPointF, 0, len(items))
+	for _, item := range items {
+		sceneItem := findSceneItem(item)
+		if sceneItem == nil {
+			continue
+		}
+		rect := sceneItem.SceneBoundingRect()
+		box := widgets.NewQGraphicsRectItem3(rect.X()-4, rect.Y()-4, rect.Width()+8, rect.Height()+8, nil)
+		box.SetPen(pen)
+		box.SetZValue(5)
+		scene.AddItem(box)
+		validationOverlay = append(validationOverlay, box.QGraphicsItem_PTR())
+		centers = append(centers, rect.Center())
+	}
+	if len(centers) == 0 {
+		return
+	}
+	// Path through all items, back to the first one in loops
+	if loop && len(centers) > 1 {
+		centers = append(centers, centers[0])
+	}
+	pathPen := gui.NewQPen3(gui.NewQColor3(244, 67, 54, 255))
+	pathPen.SetWidth(3)
+	pathPen.SetStyle(core.Qt__DashLine)
+	for i := 1; i < len(centers); i++ {
+		line := widgets.NewQGraphicsLineItem3(centers[i-1].X(), centers[i-1].Y(), centers[i].X(), centers[i].Y(), nil)
+		line.SetPen(pathPen)
+		line.SetZValue(6)
+		scene.AddItem(line)
+		validationOverlay = append(validationOverlay, line.QGraphicsItem_PTR())
+	}
+	view.CenterOn(centers[0])
+}
+
+// FindingText gets the text shown for a finding in the list of affected items
+func FindingText(finding ValidationFinding) string {
+	// Invalid rules fail without any items
+	if len(finding.Items) == 0 {
+		return fmt.Sprintf("%v\n(%v, %v)", finding.Rule.Info, strings.ToLower(finding.Rule.Name), finding.Rule.Severity)
+	}
+	names := make([]string, len(finding.Items))
+	for i, item := range finding.Items {
+		names[i] = fmt.Sprintf("%v %v", GetItemName(item), item.ID())
+	}
+	// Loops end where they started
+	if finding.Rule.Tag == "link-loop" {
+		names = append(names, names[0])
+	}
+	return fmt.Sprintf("%v\n(%v, %v)", strings.Join(names, " → "),
+		strings.ToLower(finding.Rule.Name), finding.Rule.Severity)
+}
+
+func CreateValidationResult(rule ValidationRule, result ValidationResult) *widgets.QListWidgetItem {
+	item := widgets.NewQListWidgetItem3(GetIcon(string(result)), rule.Name, nil, 0)
+	item.SetToolTip(fmt.Sprintf("%v (%v)", rule.Info, rule.Severity))
+	return item
+}
+
+// LoadValidationRules shows the rules saved in the current project
+func LoadValidationRules() {
+	if validationResults == nil || currentProject == nil {
+		return
+	}
+	rules, err := currentProject.Data().ValidationRules()
+	if err != nil {
+		fmt.Println("error: failed to get validation rules:", err)
+	}
+	validationRules = rules
+	validationResults.Clear()
+	for _, rule := range validationRules {
+		validationResults.AddItem2(CreateValidationResult(rule, GetDefaultValidationResult(rule.Enabled)))
+	}
+}
+
+// SaveValidationRule saves the settings of a rule to the current project
+func SaveValidationRule(i int) {
+	if err := currentProject.Data().SaveValidationRule(validationRules[i]); err != nil {
+		fmt.Println("error:", err)
+	}
+}
+
+// Shown in the rule editor, as a short reference of the rule language
+const ruleEditorHelp = `A rule is an expression that should be true for every item, like:
+type == "Problem" implies children(type == "Solution") >= 1
+
+Properties: type, uid, description, rationale, fitCriterion, link, children, parents, depth
+Functions: children(condition), label(name), contains(text, part), len(text)
+Operators: == != < <= > >= not and or implies ( )`
+
+// EditCustomRule shows an editor for a custom rule, or a new one if rule is nil,
+// and saves it to the current project
+func EditCustomRule(rule *ValidationRule) {
+	if currentProject == nil || currentProject.ReadOnly() {
+		return
+	}
+	dialog := widgets.NewQDialog(nil, 0)
+	dialog.SetWindowTitle("Validation Rule")
+	dialog.Resize2(480, 320)
+	layout := widgets.NewQFormLayout(nil)
+	name := widgets.NewQLineEdit(nil)
+	layout.AddRow3("Name", name)
+	expression := widgets.NewQPlainTextEdit(nil)
+	layout.AddRow3("Expression", expression)
+	severity := widgets.NewQComboBox(nil)
+	for _, s := range Severities() {
+		severity.AddItem(strings.Title(string(s)), core.NewQVariant1(string(s)))
+	}
+	layout.AddRow3("Severity", severity)
+	help := widgets.NewQLabel2(ruleEditorHelp, nil, 0)
+	help.SetWordWrap(true)
+	help.SetTextInteractionFlags(core.Qt__TextSelectableByMouse)
+	layout.AddRow5(help)
+	// Fill in the current rule, if editing one
+	previous := ""
+	if rule != nil {
+		previous = rule.Tag
+		name.SetText(rule.Tag)
+		expression.SetPlainText(rule.Expression)
+		severity.SetCurrentIndex(severity.FindData(core.NewQVariant1(string(rule.Severity)),
+			int(core.Qt__UserRole), core.Qt__MatchExactly))
+	}
+	// Only close when the rule could be saved
+	buttons := widgets.NewQDialogButtonBox3(widgets.QDialogButtonBox__Save|widgets.QDialogButtonBox__Cancel, nil)
+	buttons.ConnectAccepted(func() {
+		edited, err := NewCustomRule(name.Text(), expression.ToPlainText(), Severity(severity.CurrentData(int(core.Qt__UserRole)).ToString()))
+		if err == nil {
+			if rule != nil {
+				edited.Enabled = rule.Enabled
+			}
+			err = currentProject.Data().SaveCustomRule(previous, edited)
+		}
+		if err != nil {
+			widgets.QMessageBox_Warning(dialog, "Invalid Rule", err.Error(),
+				widgets.QMessageBox__Ok, widgets.QMessageBox__NoButton)
+			return
+		}
+		dialog.Accept()
+	})
+	buttons.ConnectRejected(dialog.Reject)
+	layout.AddRow5(buttons)
+	dialog.SetLayout(layout)
+	if dialog.Exec() == int(widgets.QDialog__Accepted) {
+		LoadValidationRules()
+	}
+}
+
+// CreateValidationRuleMenu creates the menu for changing the settings of a rule
+func CreateValidationRuleMenu(i int, item *widgets.QListWidgetItem) *widgets.QMenu {
+	rule := &validationRules[i]
+	menu := widgets.NewQMenu(nil)
+	action := menu.AddAction("Enabled")
+	action.SetCheckable(true)
+	action.SetChecked(rule.Enabled)
+	action.ConnectTriggered(func(checked bool) {
+		rule.Enabled = action.IsChecked()
+		item.SetIcon(GetIcon(string(GetDefaultValidationResult(rule.Enabled))))
+		SaveValidationRule(i)
+	})
+	// Severity, only one can be checked
+	severityMenu := menu.AddMenu2("Severity")
+	for _, severity := range Severities() {
+		s := severity
+		action := severityMenu.AddAction(strings.Title(string(s)))
+		action.SetCheckable(true)
+		action.SetChecked(rule.Severity == s)
+		action.ConnectTriggered(func(checked bool) {
+			rule.Severity = s
+			item.SetToolTip(fmt.Sprintf("%v (%v)", rule.Info, rule.Severity))
+			SaveValidationRule(i)
+		})
+	}
+	// Parameters, if any
+	for _, name := range rule.ParamNames() {
+		n := name
+		menu.AddAction(fmt.Sprintf("%v: %v...", n, rule.Params[n])).ConnectTriggered(func(checked bool) {
+			ok := false
+			value := widgets.QInputDialog_GetInt(nil, rule.Name, n, rule.Params[n], 0, 1000, 1, &ok, 0)
+			if ok {
+				rule.Params[n] = value
+				SaveValidationRule(i)
+			}
+		})
+	}
+	// Custom rules can also be changed or removed
+	if rule.IsCustom() {
+		menu.AddSeparator()
+		menu.AddAction("Edit...").ConnectTriggered(func(checked bool) {
+			EditCustomRule(rule)
+		})
+		menu.AddAction("Delete").ConnectTriggered(func(checked bool) {
+			if widgets.QMessageBox_Question(nil, "Delete Rule", fmt.Sprintf("Delete rule \"%v\"?", rule.Name),
+				widgets.QMessageBox__Yes|widgets.QMessageBox__No, widgets.QMessageBox__No) != widgets.QMessageBox__Yes {
+				return
+			}
+			if err := currentProject.Data().RemoveCustomRule(rule.Tag); err != nil {
+				fmt.Println("error:", err)
+			}
+			LoadValidationRules()
+		})
+	}
+	return menu
+}
+
+func CreateValidationEngineLayout() *widgets.QWidget {
+	// Main vertical box
+	layout := widgets.NewQVBoxLayout()
+	// List of validation results
+	results := widgets.NewQListWidget(nil)
+	validationResults = results
+	// List of affected items
+	items := widgets.NewQListWidget(nil)
+	// Main container for validation results
+	title := CreateGroupBox("Last validation: never", results)
+	// Option to validate
+	runBtn := widgets.NewQPushButton2("Run now", nil)
+	runBtn.ConnectReleased(func() {
+		// Nothing to validate without a project
+		if currentGraph == nil {
+			return
+		}
+		// Update text and disable all options
+		runBtn.SetText("Running...")
+		runBtn.SetEnabled(false)
+		results.SetEnabled(false)
+		items.SetEnabled(false)
+		// Empty list of affected items
+		items.Clear()
+		ClearValidationHighlight()
+		// Start validation timer
+		start := time.Now()
+		// Run all enabled rules, with the settings in the project
+		context, err := NewValidationContext(currentProject.Data(), currentGraph)
+		if err != nil {
+			fmt.Println("error: failed to load items to validate:", err)
+		}
+		findings := Validate(context, validationRules)
+		for _, finding := range findings {
+			items.AddItem(FindingText(finding))
+		}
+		validationFindings = findings
+		failed := FailedRules(findings)
+		for i, rule := range validationRules {
+			if !rule.Enabled {
+				continue
+			}
+			result := ValidateOK
+			if failed[rule.Tag] {
+				result = ValidateFail
+			}
+			results.Item(i).SetIcon(GetIcon(string(result)))
+		}
+		// Enable them again
+		runBtn.SetText("Run now")
+		runBtn.SetEnabled(true)
+		results.SetEnabled(true)
+		items.SetEnabled(true)
+		// Set last validation time
+		title.SetTitle(fmt.Sprintf("Last validation: %v (%v ms)", time.Now().Format("15:04"), time.Now().Sub(start).Milliseconds()))
+	})
+	layout.AddWidget(runBtn, 0, 0)
+
+	// Create initial results
+	LoadValidationRules()
+	layout.AddWidget(title, 0, 0)
+	// Option to add custom rules
+	addBtn := widgets.NewQPushButton2("Add Rule...", nil)
+	addBtn.SetToolTip("Add a rule that every item should match")
+	addBtn.ConnectReleased(func() {
+		EditCustomRule(nil)
+	})
+	layout.AddWidget(addBtn, 0, 0)
+	// Show menu when clicking on result item
+	results.ConnectItemPressed(func(item *widgets.QListWidgetItem) {
+		CreateValidationRuleMenu(results.Row(item), item).Popup(gui.QCursor_Pos(), nil)
+	})
+	// Show affected items in the scene when selected
+	items.ConnectCurrentRowChanged(func(row int) {
+		if row < 0 || row >= len(validationFindings) {
+			ClearValidationHighlight()
+			return
+		}
+		finding := validationFindings[row]
+		HighlightItems(finding.Items, finding.Rule.Tag == "link-loop")
+	})
+	// Create list to show affected items
+	itemGroup := CreateGroupBox("Affected Items", items)
+	layout.AddWidget(itemGroup, 1, 0)
+
+	// Convert layout to widget and return it
+	widget := widgets.NewQWidget(nil, core.Qt__Widget)
+	widget.SetLayout(layout)
+	widget.SetMaximumWidth(250)
+	widget.SetMinimumWidth(175)
+	return widget
+}